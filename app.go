@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/pinpox/opencrow/backend"
 )
@@ -40,21 +42,43 @@ func extractSendFiles(text string) (string, []string) {
 // App orchestrates the business logic: command handling, inbox enqueueing,
 // and file extraction. It delegates transport concerns to a Backend.
 type App struct {
-	backend backend.Backend
-	worker  *Worker
-	inbox   *InboxStore
-	outbox  *outboxStore
+	backend  backend.Backend
+	worker   *Worker
+	inbox    *InboxStore
+	outbox   *outboxStore
+	history  *historyStore
+	settings *settingsStore
+	reply    ReplyConfig
+	group    GroupConfig
+	cron     *cronScheduler // nil when no OPENCROW_CRON_FILE is configured
+
+	commands *commandRegistry
+	groupCtx *groupBuffer
+	scripts  sync.WaitGroup // running script commands
 }
 
 // NewApp creates a new App. The db connection is shared with the inbox
 // and owned by the caller.
 func NewApp(b backend.Backend, worker *Worker, inbox *InboxStore, db *sql.DB) *App {
-	return &App{
-		backend: b,
-		worker:  worker,
-		inbox:   inbox,
-		outbox:  newOutboxStore(db),
+	verbosity := Verbosity{ToolCalls: worker.piCfg.ShowToolCalls, DebugTiming: worker.piCfg.DebugTiming}
+
+	a := &App{
+		backend:  b,
+		worker:   worker,
+		inbox:    inbox,
+		outbox:   newOutboxStore(db),
+		history:  newHistoryStore(db),
+		settings: newSettingsStore(db, verbosity),
+		reply:    ReplyConfig{Depth: defaultReplyChainDepth, Tokens: defaultReplyChainTokens},
+		group:    GroupConfig{Context: defaultGroupContext},
+
+		commands: newCommandRegistry(nil),
+		groupCtx: newGroupBuffer(),
 	}
+
+	a.registerBuiltinCommands()
+
+	return a
 }
 
 // HandleMessage is the backend.MessageHandler callback. It dispatches
@@ -63,39 +87,47 @@ func (a *App) HandleMessage(ctx context.Context, msg backend.Message) {
 	// Record the incoming message so future reply-to references can quote it.
 	a.outbox.Put(ctx, msg.ConversationID, msg.MessageID, msg.Text)
 
-	switch strings.TrimSpace(msg.Text) {
-	case "!help":
-		a.handleHelp(ctx, msg)
-	case "!restart":
-		a.handleRestart(ctx, msg)
-	case "!stop":
-		a.handleStop(ctx, msg)
-	case "!compact":
-		a.handleCompact(ctx, msg)
-	case "!skills":
-		a.handleSkills(ctx, msg)
-	default:
+	if a.handleReminderReply(ctx, msg) {
+		return
+	}
+
+	cmd, args, ok := a.commands.match(msg.Text)
+	if !ok {
 		a.handlePrompt(ctx, msg)
+
+		return
 	}
+
+	if args == "" && cmd.argsRequired() {
+		a.backend.SendMessage(ctx, msg.ConversationID, "Usage: "+a.commands.synopsis(cmd), "")
+
+		return
+	}
+
+	cmd.run(ctx, msg, args)
 }
 
-func (a *App) handleHelp(ctx context.Context, msg backend.Message) {
-	help := "Available commands:\n" +
-		"  !help    — Show this help message\n" +
-		"  !restart — Kill the current session and start fresh\n" +
-		"  !stop    — Abort the currently running agent turn\n" +
-		"  !compact — Compact conversation context to reduce token usage\n" +
-		"  !skills  — List loaded skills"
+func (a *App) handleHelp(ctx context.Context, msg backend.Message, args string) {
+	help := a.commands.summary()
+
+	if args != "" {
+		if cmd := a.commands.lookup(args); cmd != nil {
+			help = a.commands.describe(cmd)
+		} else {
+			help = fmt.Sprintf("Unknown command %q.\n\n%s", args, help)
+		}
+	}
+
 	a.backend.SendMessage(ctx, msg.ConversationID, help, "")
 }
 
-func (a *App) handleRestart(ctx context.Context, msg backend.Message) {
+func (a *App) handleRestart(ctx context.Context, msg backend.Message, _ string) {
 	a.backend.ResetConversation(ctx, msg.ConversationID)
 	a.worker.Restart()
 	a.backend.SendMessage(ctx, msg.ConversationID, "Session restarted. Next message starts a fresh session (previous context discarded).", "")
 }
 
-func (a *App) handleStop(ctx context.Context, msg backend.Message) {
+func (a *App) handleStop(ctx context.Context, msg backend.Message, _ string) {
 	if !a.worker.IsActive() {
 		a.backend.SendMessage(ctx, msg.ConversationID, "No active session.", "")
 
@@ -109,7 +141,7 @@ func (a *App) handleStop(ctx context.Context, msg backend.Message) {
 	}
 }
 
-func (a *App) handleCompact(ctx context.Context, msg backend.Message) {
+func (a *App) handleCompact(ctx context.Context, msg backend.Message, _ string) {
 	if !a.worker.IsActive() {
 		a.backend.SendMessage(ctx, msg.ConversationID, "No active session to compact.", "")
 
@@ -128,16 +160,88 @@ func (a *App) handleCompact(ctx context.Context, msg backend.Message) {
 	a.backend.SendMessage(ctx, msg.ConversationID, reply, "")
 }
 
-func (a *App) handleSkills(ctx context.Context, msg backend.Message) {
+func (a *App) handleSkills(ctx context.Context, msg backend.Message, _ string) {
 	a.backend.SendMessage(ctx, msg.ConversationID, a.worker.SkillsSummary(), "")
 }
 
+func (a *App) handleStatus(ctx context.Context, msg backend.Message, _ string) {
+	session := "not running"
+	if a.worker.IsActive() {
+		session = "active"
+	}
+
+	queued, err := a.inbox.Count(ctx)
+	if err != nil {
+		slog.Warn("status: failed to count inbox", "error", err)
+	}
+
+	status := fmt.Sprintf("Session: %s\nQueued: %d\n%s", session, queued, a.cron.summary())
+	a.backend.SendMessage(ctx, msg.ConversationID, status, "")
+}
+
+func (a *App) handleSearch(ctx context.Context, msg backend.Message, query string) {
+	hits, err := a.history.Search(ctx, msg.ConversationID, query, searchResultLimit)
+	if err != nil {
+		slog.Error("search failed", "conversation", msg.ConversationID, "error", err)
+		a.backend.SendMessage(ctx, msg.ConversationID, fmt.Sprintf("Search failed: %v", err), "")
+
+		return
+	}
+
+	a.backend.SendMessage(ctx, msg.ConversationID, formatSearchResults(query, hits, a.worker.promptCfg.location()), "")
+}
+
 func (a *App) handlePrompt(ctx context.Context, msg backend.Message) {
+	a.recordInbound(ctx, msg)
+
+	if msg.Group && a.group.MentionOnly {
+		text, addressed := a.addressesBot(ctx, msg)
+		if !addressed {
+			a.bufferGroupMessage(msg)
+
+			return
+		}
+
+		msg.Text = text
+	}
+
+	a.enqueuePrompt(ctx, msg)
+}
+
+// recordInbound logs a user message in the history.
+func (a *App) recordInbound(ctx context.Context, msg backend.Message) {
+	a.history.Record(ctx, historyEntry{
+		ConversationID: msg.ConversationID,
+		Direction:      historyIn,
+		Sender:         msg.SenderID,
+		MessageID:      msg.MessageID,
+		ReplyTo:        msg.ReplyToID,
+		Source:         sourceUser,
+		Text:           msg.Text,
+		Attachments:    inboundAttachments(msg.Text),
+	})
+}
+
+// enqueuePrompt queues msg for the agent, with the reply chain it
+// answers and, in mention-only groups, the messages buffered since the
+// bot was last addressed.
+func (a *App) enqueuePrompt(ctx context.Context, msg backend.Message) {
 	a.worker.SetRoomID(msg.ConversationID)
 
 	promptText := a.buildPromptText(ctx, msg)
 
-	if err := a.inbox.Enqueue(ctx, PriorityUser, sourceUser, promptText, msg.ReplyToID); err != nil {
+	if msg.Group && a.group.MentionOnly {
+		if groupCtx := a.groupCtx.take(msg.ConversationID); len(groupCtx) > 0 {
+			promptText = formatGroupContext(groupCtx) + promptText
+		}
+	}
+
+	if err := a.inbox.EnqueueUser(ctx, Inbox{
+		Content:          promptText,
+		ReplyTo:          msg.ReplyToID,
+		Sender:           cmp.Or(msg.SenderName, msg.SenderID),
+		ConversationName: msg.ConversationName,
+	}); err != nil {
 		slog.Error("failed to enqueue user message", "error", err)
 		a.backend.SendMessage(ctx, msg.ConversationID, fmt.Sprintf("Error: %v", err), "")
 
@@ -147,30 +251,34 @@ func (a *App) handlePrompt(ctx context.Context, msg backend.Message) {
 	a.worker.Notify(PriorityUser)
 }
 
-// buildPromptText prepends reply-quote context to the message text.
+// buildPromptText prepends the reply chain the message answers, if any.
 func (a *App) buildPromptText(ctx context.Context, msg backend.Message) string {
-	promptText := msg.Text
+	if msg.ReplyToID == "" {
+		return msg.Text
+	}
 
-	if msg.ReplyToID != "" {
-		if quoted := a.outbox.Get(ctx, msg.ConversationID, msg.ReplyToID); quoted != "" {
-			promptText = fmt.Sprintf("[user replied to message: %q]\n%s", quoted, promptText)
-		} else {
-			promptText = "[user replied to a message whose content is unavailable — ask for clarification if their message is unclear]\n" + promptText
-		}
+	chain, truncated := a.replyChain(ctx, msg)
+	if len(chain) == 0 {
+		return "[user replied to a message whose content is unavailable — ask for clarification if their message is unclear]\n" + msg.Text
 	}
 
-	return promptText
+	return formatReplyChain(chain, truncated) + msg.Text
 }
 
 // sendReplyWithFiles extracts <sendfile> tags, uploads each file, and
-// sends the final text reply.
-func (a *App) sendReplyWithFiles(ctx context.Context, conversationID, reply, replyToID string) {
+// sends the final text reply. source is the inbox source of the item the
+// reply answers, for the history. Returns the backend ID of the text
+// message, or "" if none was sent.
+func (a *App) sendReplyWithFiles(ctx context.Context, conversationID, reply, replyToID, source string, footer replyFooter) string {
 	slog.Info("sending reply", "conversation", conversationID, "len", len(reply))
 	slog.Debug("outgoing reply content", "conversation", conversationID, "content", reply)
 
 	cleanReply, filePaths := extractSendFiles(reply)
 
-	var fileSendErrors strings.Builder
+	var (
+		fileSendErrors strings.Builder
+		sent           []string
+	)
 
 	for _, fp := range filePaths {
 		slog.Info("sending file", "conversation", conversationID, "path", fp)
@@ -178,67 +286,64 @@ func (a *App) sendReplyWithFiles(ctx context.Context, conversationID, reply, rep
 		if err := a.backend.SendFile(ctx, conversationID, fp); err != nil {
 			slog.Error("failed to send file", "conversation", conversationID, "path", fp, "error", err)
 			fileSendErrors.WriteString(fmt.Sprintf("\n\n(failed to send file %s: %v)", filepath.Base(fp), err))
+		} else {
+			sent = append(sent, fp)
 		}
 	}
 
 	cleanReply += fileSendErrors.String()
 
+	var sentID string
 	if cleanReply != "" {
-		sentID := a.backend.SendMessage(ctx, conversationID, cleanReply, replyToID)
+		sentID = a.sendWithFooter(ctx, conversationID, cleanReply, replyToID, footer)
 		a.outbox.Put(ctx, conversationID, sentID, cleanReply)
 	}
-}
 
-// formatToolCall produces a short human-readable summary of a tool invocation.
-// The Markdown flavor controls whether commands and paths are wrapped in
-// fenced code blocks / inline backticks (and whether fences carry a language
-// hint) so backends that do not render Markdown do not leak raw syntax.
-func formatToolCall(evt ToolCallEvent, flavor backend.MarkdownFlavor) string {
-	switch evt.ToolName {
-	case "bash":
-		return formatBashCall(evt, flavor)
-	case "read":
-		return formatPathCall(evt, flavor, "📄 reading", "file")
-	case "edit":
-		return formatPathCall(evt, flavor, "✏️ editing", "file")
-	case "write":
-		return formatPathCall(evt, flavor, "📝 writing", "file")
-	default:
-		return "🔧 " + evt.ToolName
-	}
+	a.history.Record(ctx, historyEntry{
+		ConversationID: conversationID,
+		Direction:      historyOut,
+		MessageID:      sentID,
+		ReplyTo:        replyToID,
+		Source:         source,
+		Text:           cleanReply,
+		Attachments:    sent,
+	})
+
+	return sentID
 }
 
-func formatBashCall(evt ToolCallEvent, flavor backend.MarkdownFlavor) string {
-	cmd, ok := evt.Args["command"].(string)
-	if !ok {
-		return "🔧 bash"
-	}
+// sendVerbatim posts text and files exactly as given, without <sendfile>
+// extraction. Used for scheduled messages and script commands, which
+// bypass the agent; source labels them in the history. File failures
+// are appended to the text like in sendReplyWithFiles.
+func (a *App) sendVerbatim(ctx context.Context, conversationID, text string, filePaths []string, source string) {
+	var sent []string
 
-	switch flavor {
-	case backend.MarkdownFull:
-		return fmt.Sprintf("🔧\n```sh\n%s\n```", cmd)
-	case backend.MarkdownBasic:
-		// No language hint: some clients (e.g. Nostr/0xchat)
-		// render the hint literally instead of hiding it.
-		return fmt.Sprintf("🔧\n```\n%s\n```", cmd)
-	case backend.MarkdownNone:
-		return "🔧 " + cmd
-	default:
-		return "🔧 " + cmd
+	for _, fp := range filePaths {
+		if err := a.backend.SendFile(ctx, conversationID, fp); err != nil {
+			slog.Error("failed to send file", "conversation", conversationID, "path", fp, "error", err)
+			text += fmt.Sprintf("\n\n(failed to send file %s: %v)", filepath.Base(fp), err)
+		} else {
+			sent = append(sent, fp)
+		}
 	}
-}
 
-func formatPathCall(evt ToolCallEvent, flavor backend.MarkdownFlavor, prefix, fallback string) string {
-	p, ok := evt.Args["path"].(string)
-	if !ok {
-		return prefix + " " + fallback
-	}
+	text = strings.TrimSpace(text)
 
-	if flavor == backend.MarkdownNone {
-		return prefix + " " + p
+	var sentID string
+	if text != "" {
+		sentID = a.backend.SendMessage(ctx, conversationID, text, "")
+		a.outbox.Put(ctx, conversationID, sentID, text)
 	}
 
-	return prefix + " `" + p + "`"
+	a.history.Record(ctx, historyEntry{
+		ConversationID: conversationID,
+		Direction:      historyOut,
+		MessageID:      sentID,
+		Source:         source,
+		Text:           text,
+		Attachments:    sent,
+	})
 }
 
 // systemPrompt returns the full system prompt including backend-specific extras.
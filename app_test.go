@@ -134,6 +134,11 @@ func TestApp_Commands(t *testing.T) {
 		{"help", "!help", []string{"!help", "!restart", "!stop", "!compact", "!skills"}, false},
 		{"restart", "!restart", []string{"Session restarted"}, true},
 		{"skills", "!skills", []string{"No skills loaded"}, false},
+		{"status", "!status", []string{"Session: not running", "Queued: 0", "Scheduled jobs: none"}, false},
+		{"help command", "!help search", []string{"!search <query> — Search past messages"}, false},
+		{"help unknown", "!help nope", []string{`Unknown command "nope"`, "!restart"}, false},
+		{"search usage", "!search", []string{"Usage: !search <query>"}, false},
+		{"search no hits", "!search nothing here", []string{`No messages found for "nothing here"`}, false},
 	}
 
 	for _, tc := range cases {
@@ -233,7 +238,8 @@ func TestApp_BuildPromptText_ReplyToUserMessage(t *testing.T) {
 	}
 
 	got := app.buildPromptText(ctx, replyMsg)
-	want := `[user replied to message: "original question"]
+	want := `[user replied to the last message of this thread, oldest first]
+> earlier message: original question
 follow-up`
 
 	if got != want {
@@ -265,24 +271,3 @@ func TestApp_SystemPrompt(t *testing.T) {
 		})
 	}
 }
-
-func TestFormatToolCall(t *testing.T) {
-	t.Parallel()
-
-	bash := ToolCallEvent{ToolName: "bash", Args: map[string]any{"command": "ls -la"}}
-	read := ToolCallEvent{ToolName: "read", Args: map[string]any{"path": "/etc/hosts"}}
-
-	check := func(evt ToolCallEvent, flavor backend.MarkdownFlavor, want string) {
-		t.Helper()
-
-		if got := formatToolCall(evt, flavor); got != want {
-			t.Errorf("formatToolCall(%s, %d) = %q, want %q", evt.ToolName, flavor, got, want)
-		}
-	}
-
-	check(bash, backend.MarkdownFull, "🔧\n```sh\nls -la\n```")
-	check(bash, backend.MarkdownBasic, "🔧\n```\nls -la\n```")
-	check(bash, backend.MarkdownNone, "🔧 ls -la")
-	check(read, backend.MarkdownBasic, "📄 reading `/etc/hosts`")
-	check(read, backend.MarkdownNone, "📄 reading /etc/hosts")
-}
@@ -54,11 +54,25 @@ const (
 
 // Message represents a transport-agnostic inbound message.
 type Message struct {
-	ConversationID string // room ID, DM pubkey, channel ID — opaque to the core
-	SenderID       string // user ID / pubkey
-	Text           string // message text (or synthesized "[User sent file: ...]")
-	MessageID      string // backend-specific ID of this message (used to resolve future reply-to references)
-	ReplyToID      string // backend-specific ID of the message being replied to (empty if not a reply)
+	ConversationID   string // room ID, DM pubkey, channel ID — opaque to the core
+	ConversationName string // room or group name, "" if unknown or a DM
+	SenderID         string // user ID / pubkey
+	SenderName       string // display name, "" if unknown
+	Text             string // message text (or synthesized "[User sent file: ...]")
+	MessageID        string // backend-specific ID of this message (used to resolve future reply-to references)
+	ReplyToID        string // backend-specific ID of the message being replied to (empty if not a reply)
+	Quote            *Quote // replied-to message as carried by the transport (Signal quotes), or nil
+	Group            bool   // conversation has members besides the sender and the bot
+	MentionsBot      bool   // message @-mentions the bot account
+}
+
+// Quote is a message referenced by a reply, as far as the backend knows it.
+// The core uses it for reply targets missing from its own message store.
+type Quote struct {
+	SenderID  string
+	Text      string
+	FromBot   bool   // sent by the bot account itself
+	ReplyToID string // the quoted message's own reply target, if known
 }
 
 // Streamer is an optional interface backends can implement to support
@@ -68,6 +82,45 @@ type Streamer interface {
 	// SendDelta sends an incremental text fragment for an in-progress message.
 	// messageID identifies the message being built (same across all deltas).
 	SendDelta(ctx context.Context, conversationID string, messageID string, delta string)
+	// SendThinkingDelta sends an incremental fragment of the model's
+	// thinking, for conversations that opted in. Clients should show it
+	// apart from the reply, e.g. collapsed.
+	SendThinkingDelta(ctx context.Context, conversationID string, messageID string, delta string)
+}
+
+// MessageFetcher is an optional interface backends can implement to look
+// up a message the core never saw (sent before opencrow started or from
+// another client), so reply chains can be followed past its own store.
+type MessageFetcher interface {
+	FetchMessage(ctx context.Context, conversationID, messageID string) (Quote, error)
+}
+
+// MessageEditor is an optional interface backends can implement to replace
+// the text of a message the bot sent earlier, so notifications can be
+// updated in place. Backends that don't implement this get follow-up
+// messages instead.
+type MessageEditor interface {
+	// EditMessage replaces the text of messageID, an ID returned by
+	// SendMessage.
+	EditMessage(ctx context.Context, conversationID, messageID, text string) error
+}
+
+// MessageRedactor is an optional interface backends can implement to
+// remove a message the bot sent earlier, so a notification can be taken
+// back when the turn it belongs to ends without a reply.
+type MessageRedactor interface {
+	// RedactMessage removes messageID, an ID returned by SendMessage.
+	RedactMessage(ctx context.Context, conversationID, messageID string) error
+}
+
+// DetailsSender is an optional interface for backends whose clients can
+// collapse part of a message (HTML <details> on Matrix). Backends that
+// don't implement this get the summary appended to the text.
+type DetailsSender interface {
+	// SendMessageWithDetails sends text like SendMessage, followed by a
+	// collapsed section that shows summary and expands to details. text
+	// may be empty. All three are Markdown.
+	SendMessageWithDetails(ctx context.Context, conversationID, text, summary, details, replyToID string) string
 }
 
 // MessageHandler is a callback invoked by the backend for each inbound user message.
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCalendarLead = 15 * time.Minute
+	// calendarFiredRetention is how long fired instances are remembered;
+	// longer than any lead time, so an instance never fires twice.
+	calendarFiredRetention = 7 * 24 * time.Hour
+)
+
+// CalendarConfig enables pre-event triggers from local .ics files.
+type CalendarConfig struct {
+	Paths []string      // OPENCROW_CALENDAR_PATHS (files or directories), default none
+	Lead  time.Duration // OPENCROW_CALENDAR_LEAD, default 15m
+}
+
+// calendarFile is a parsed .ics file, re-read when its size or
+// modification time changes.
+type calendarFile struct {
+	modTime time.Time
+	size    int64
+	events  []icsEvent
+}
+
+// calendarWatcher enqueues a trigger Lead before each event instance in
+// the configured calendars. Fired instances are recorded in
+// calendar_fired so restarts and file rewrites don't fire them again.
+type calendarWatcher struct {
+	w     *Worker
+	cfg   CalendarConfig
+	files map[string]*calendarFile // only touched from the tick goroutine
+}
+
+func newCalendarWatcher(w *Worker, cfg CalendarConfig) *calendarWatcher {
+	return &calendarWatcher{w: w, cfg: cfg, files: make(map[string]*calendarFile)}
+}
+
+// startCalendar runs the calendar watcher on reminderTick until ctx is done.
+func startCalendar(ctx context.Context, c *calendarWatcher) {
+	slog.Info("calendar watcher started", "paths", c.cfg.Paths, "lead", c.cfg.Lead)
+
+	go func() {
+		ticker := time.NewTicker(reminderTick)
+		defer ticker.Stop()
+
+		c.tick(ctx, time.Now())
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				c.tick(ctx, now)
+			}
+		}
+	}()
+}
+
+// tick reloads changed calendar files and enqueues one trigger for the
+// instances starting within the lead time that haven't fired yet.
+// All-day events are skipped: "15 minutes before midnight" helps no one.
+func (c *calendarWatcher) tick(ctx context.Context, now time.Time) {
+	c.reload()
+
+	var events []icsEvent
+	for _, f := range c.files {
+		events = append(events, f.events...)
+	}
+
+	var due []eventInstance
+
+	for _, in := range expandEvents(events, now, now.Add(c.cfg.Lead)) {
+		if in.Event.AllDay {
+			continue
+		}
+
+		fired, err := c.w.inbox.queries.CalendarEventFired(ctx, in.Key())
+		if err != nil {
+			slog.Warn("calendar: failed to check fired events", "error", err)
+
+			return
+		}
+
+		if fired == 0 {
+			due = append(due, in)
+		}
+	}
+
+	cutoff := now.UTC().Add(-calendarFiredRetention).Format(time.RFC3339)
+	if err := c.w.inbox.queries.PruneCalendarFired(ctx, cutoff); err != nil {
+		slog.Warn("calendar: failed to prune fired events", "error", err)
+	}
+
+	if len(due) == 0 {
+		return
+	}
+
+	item := Inbox{Priority: priorityUnset, Label: "calendar", Content: formatCalendarTrigger(due, now)}
+
+	if _, err := c.w.enqueueTrigger(ctx, item); err != nil {
+		slog.Error("calendar: failed to enqueue", "error", err)
+
+		return
+	}
+
+	firedAt := now.UTC().Format(time.RFC3339)
+
+	for _, in := range due {
+		if err := c.w.inbox.queries.RecordCalendarFired(ctx, RecordCalendarFiredParams{
+			EventKey: in.Key(),
+			FiredAt:  firedAt,
+		}); err != nil {
+			slog.Warn("calendar: failed to record fired event", "error", err)
+		}
+	}
+
+	slog.Info("calendar: upcoming events", "count", len(due))
+}
+
+// reload re-parses .ics files that are new or changed since the last tick
+// and forgets files that disappeared.
+func (c *calendarWatcher) reload() {
+	present := make(map[string]bool, len(c.files))
+
+	for _, root := range c.cfg.Paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".ics") {
+				return nil
+			}
+
+			present[path] = true
+			c.loadFile(path)
+
+			return nil
+		})
+		if err != nil {
+			slog.Warn("calendar: failed to scan", "path", root, "error", err)
+		}
+	}
+
+	for path := range c.files {
+		if !present[path] {
+			delete(c.files, path)
+		}
+	}
+}
+
+func (c *calendarWatcher) loadFile(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		slog.Warn("calendar: failed to stat", "path", path, "error", err)
+
+		return
+	}
+
+	if f, ok := c.files[path]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+		return
+	}
+
+	fh, err := os.Open(path)
+	if err != nil {
+		slog.Warn("calendar: failed to open", "path", path, "error", err)
+
+		return
+	}
+	defer fh.Close()
+
+	events, err := parseICS(fh)
+	if err != nil {
+		slog.Warn("calendar: failed to parse", "path", path, "error", err)
+
+		return
+	}
+
+	c.files[path] = &calendarFile{modTime: info.ModTime(), size: info.Size(), events: events}
+}
+
+func formatCalendarTrigger(due []eventInstance, now time.Time) string {
+	var sb strings.Builder
+
+	sb.WriteString("Upcoming calendar events — remind the user and help them prepare:")
+
+	for _, in := range due {
+		ev := in.Event
+
+		fmt.Fprintf(&sb, "\n\n%s\nStarts: %s (in %d min)",
+			cmp.Or(ev.Summary, "(untitled event)"), in.Start.Format("Mon 2006-01-02 15:04 MST"),
+			int(in.Start.Sub(now).Round(time.Minute).Minutes()))
+
+		if ev.Duration > 0 {
+			fmt.Fprintf(&sb, "\nEnds: %s", in.Start.Add(ev.Duration).Format("15:04"))
+		}
+
+		if ev.Location != "" {
+			sb.WriteString("\nLocation: " + ev.Location)
+		}
+
+		if len(ev.Attendees) > 0 {
+			sb.WriteString("\nAttendees: " + strings.Join(ev.Attendees, ", "))
+		}
+
+		if ev.Description != "" {
+			sb.WriteString("\nDescription:\n" + strings.TrimSpace(ev.Description))
+		}
+	}
+
+	return sb.String()
+}
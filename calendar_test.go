@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarWatcher_FiresOncePerInstance(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "work", "review.ics")
+	must(t, os.MkdirAll(filepath.Dir(path), 0o755))
+
+	writeEvent := func(start string) {
+		t.Helper()
+		must(t, os.WriteFile(path, []byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:review@example.com\r\n"+
+			"SUMMARY:Design review\r\nLOCATION:Jitsi\r\nATTENDEE;CN=Carol:mailto:carol@example.com\r\n"+
+			"DTSTART:"+start+"\r\nDTEND:20250616T110000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"), 0o600))
+	}
+
+	writeEvent("20250616T100000Z")
+
+	inbox := newTestInbox(ctx, t)
+	c := newCalendarWatcher(NewWorker(inbox, PiConfig{}, "", ""), CalendarConfig{Paths: []string{dir}, Lead: 15 * time.Minute})
+
+	// Too early, then within the lead time twice: one trigger.
+	c.tick(ctx, time.Date(2025, 6, 16, 9, 40, 0, 0, time.UTC))
+	c.tick(ctx, time.Date(2025, 6, 16, 9, 46, 0, 0, time.UTC))
+	c.tick(ctx, time.Date(2025, 6, 16, 9, 50, 0, 0, time.UTC))
+
+	if n, _ := inbox.Count(ctx); n != 1 {
+		t.Fatalf("inbox has %d items, want 1", n)
+	}
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	for _, want := range []string{"Design review", "(in 14 min)", "Location: Jitsi", "Attendees: Carol", "Ends: "} {
+		if !strings.Contains(item.Content, want) {
+			t.Errorf("content %q missing %q", item.Content, want)
+		}
+	}
+
+	// Moving the event re-arms it for the new time. Same size, so bump the
+	// modification time explicitly in case the filesystem is coarse.
+	writeEvent("20250616T103000Z")
+	must(t, os.Chtimes(path, time.Time{}, time.Now().Add(time.Minute)))
+	c.tick(ctx, time.Date(2025, 6, 16, 9, 55, 0, 0, time.UTC))
+
+	if n, _ := inbox.Count(ctx); n != 0 {
+		t.Fatalf("moved event fired early: inbox has %d items", n)
+	}
+
+	c.tick(ctx, time.Date(2025, 6, 16, 10, 20, 0, 0, time.UTC))
+
+	if n, _ := inbox.Count(ctx); n != 1 {
+		t.Fatalf("inbox has %d items after the moved event's lead time, want 1", n)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+const (
+	defaultScriptTimeout = 30 * time.Second
+	// maxScriptArgs bounds how many arguments a user can pass.
+	maxScriptArgs = 16
+	// maxScriptOutput bounds the reply; longer output keeps its tail,
+	// where logs and errors usually end up.
+	maxScriptOutput = 4000
+	// scriptWaitDelay is how long to wait for output pipes after the
+	// process group was killed.
+	scriptWaitDelay = 2 * time.Second
+	// scriptShutdownTimeout bounds how long shutdown waits for running
+	// script commands to reply.
+	scriptShutdownTimeout = 10 * time.Second
+)
+
+// scriptArgRe is what a user-supplied script argument may look like: no
+// shell metacharacters, whitespace or leading "-" that could be taken
+// for an option.
+var scriptArgRe = regexp.MustCompile(`^[A-Za-z0-9_.:/@=+,%][A-Za-z0-9_.:/@=+,%-]*$`)
+
+func (c *CustomCommand) validateExec() error {
+	if !filepath.IsAbs(c.Exec[0]) {
+		return fmt.Errorf("command %q: exec must start with an absolute program path, got %q", c.Name, c.Exec[0])
+	}
+
+	c.timeout = defaultScriptTimeout
+
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("command %q: timeout must be a positive duration, got %q", c.Name, c.Timeout)
+		}
+
+		c.timeout = d
+	}
+
+	return nil
+}
+
+// sanitizeScriptArgs splits the user's arguments and rejects anything
+// that is not a plain word, so the program only ever sees arguments it
+// could have been given on a command line without quoting.
+func sanitizeScriptArgs(args string) ([]string, error) {
+	fields := strings.Fields(args)
+	if len(fields) > maxScriptArgs {
+		return nil, fmt.Errorf("at most %d arguments are allowed", maxScriptArgs)
+	}
+
+	for _, f := range fields {
+		if !scriptArgRe.MatchString(f) || strings.Contains(f, "..") {
+			return nil, fmt.Errorf("argument %q is not allowed", f)
+		}
+	}
+
+	return fields, nil
+}
+
+// scriptCommand returns a handler that runs c's program in the working
+// directory and replies with its output, without involving the agent.
+// The program runs on its own goroutine so the handler returns at once:
+// backends like Signal deliver messages one at a time from their receive
+// loop. It isn't bound to the handler's context, which may end with the
+// message, but to the command's own timeout.
+func (a *App) scriptCommand(c CustomCommand) func(context.Context, backend.Message, string) {
+	return func(ctx context.Context, msg backend.Message, args string) {
+		userArgs, err := sanitizeScriptArgs(args)
+		if err != nil {
+			a.backend.SendMessage(ctx, msg.ConversationID, fmt.Sprintf("Command %s: %v", c.Name, err), "")
+
+			return
+		}
+
+		ctx = context.WithoutCancel(ctx)
+
+		a.scripts.Go(func() {
+			a.backend.SetTyping(ctx, msg.ConversationID, true)
+			defer a.backend.SetTyping(ctx, msg.ConversationID, false)
+
+			output, runErr := runScript(ctx, c, a.worker.piCfg.WorkingDir, userArgs)
+
+			text, files := extractSendFiles(output)
+			text = formatScriptOutput(text, runErr, a.backend.MarkdownFlavor())
+
+			a.sendVerbatim(ctx, msg.ConversationID, text, files, sourceCommand)
+		})
+	}
+}
+
+// waitScripts waits up to timeout for running script commands to send
+// their replies, so they don't race the backend and database closing.
+// Reports whether they all finished.
+func (a *App) waitScripts(timeout time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		a.scripts.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// runScript runs c with userArgs appended and returns its combined
+// stdout and stderr. The whole process group is killed on timeout.
+func runScript(ctx context.Context, c CustomCommand, dir string, userArgs []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	args := append(c.Exec[1:len(c.Exec):len(c.Exec)], userArgs...)
+
+	cmd := exec.CommandContext(ctx, c.Exec[0], args...) //nolint:gosec // program is from trusted config, args are sanitized
+	cmd.Dir = dir
+	configurePiSysProcAttr(cmd)
+	cmd.Cancel = func() error {
+		killProcessTree(cmd.Process.Pid, syscall.SIGKILL)
+
+		return nil
+	}
+	cmd.WaitDelay = scriptWaitDelay
+
+	slog.Info("running command", "command", c.Name, "program", c.Exec[0], "args", len(args))
+
+	out, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+
+	if err != nil {
+		slog.Warn("command failed", "command", c.Name, "error", err)
+	}
+
+	return string(out), err
+}
+
+// formatScriptOutput renders program output as a code block for the
+// backend's Markdown flavor, noting a failure below it.
+func formatScriptOutput(output string, runErr error, flavor backend.MarkdownFlavor) string {
+	output = strings.Trim(output, "\n")
+	if r := []rune(output); len(r) > maxScriptOutput {
+		output = "…" + string(r[len(r)-maxScriptOutput:])
+	}
+
+	var text string
+
+	switch {
+	case strings.TrimSpace(output) == "":
+		text = "(no output)"
+	case flavor == backend.MarkdownNone:
+		text = output
+	default:
+		// No language hint: the output is not any particular language,
+		// and some clients render the hint literally.
+		text = "```\n" + output + "\n```"
+	}
+
+	if runErr != nil {
+		text += fmt.Sprintf("\n(%v)", runErr)
+	}
+
+	return text
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+func TestSanitizeScriptArgs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		args    string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{" /var/log  nginx.service ", []string{"/var/log", "nginx.service"}, false},
+		{"user@host key=value 50%", []string{"user@host", "key=value", "50%"}, false},
+		{"--force", nil, true},
+		{"; rm -rf /", nil, true},
+		{"$(id)", nil, true},
+		{"../../etc/passwd", nil, true},
+		{strings.Repeat("a ", maxScriptArgs+1), nil, true},
+	}
+
+	for _, tc := range cases {
+		got, err := sanitizeScriptArgs(tc.args)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("sanitizeScriptArgs(%q) error = %v, wantErr %v", tc.args, err, tc.wantErr)
+
+			continue
+		}
+
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("sanitizeScriptArgs(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestApp_ScriptCommand(t *testing.T) {
+	t.Parallel()
+
+	script := writeTestFile(t, "report.sh", `#!/bin/sh
+echo "args: $*"
+echo "dir: $(pwd)"
+echo oops >&2
+echo "<sendfile>$0</sendfile>"
+exit 3
+`)
+	must(t, os.Chmod(script, 0o700))
+
+	app, mb := newTestAppWithBackend(t, &mockBackend{markdownFlavor: backend.MarkdownFull})
+	app.worker.piCfg.WorkingDir = t.TempDir()
+
+	cmd := CustomCommand{Name: "report", Args: "[unit]", Exec: []string{script, "fixed"}}
+	must(t, cmd.validate())
+	must(t, app.registerCustomCommands([]CustomCommand{cmd}))
+
+	sendCommand(app, "!report nginx")
+	app.scripts.Wait()
+	sendCommand(app, "!report `id`")
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if len(mb.sentMessages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(mb.sentMessages))
+	}
+
+	wantDir, err := filepath.EvalSymlinks(app.worker.piCfg.WorkingDir)
+	must(t, err)
+
+	want := "```\nargs: fixed nginx\ndir: " + wantDir + "\noops\n```\n(exit status 3)"
+	if got := mb.sentMessages[0].text; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+
+	if len(mb.sentFiles) != 1 || mb.sentFiles[0].filePath != script {
+		t.Errorf("sent files = %v, want [%s]", mb.sentFiles, script)
+	}
+
+	if got := mb.sentMessages[1].text; !strings.Contains(got, "not allowed") {
+		t.Errorf("reply to unsafe argument = %q, want a refusal", got)
+	}
+
+	if n, _ := app.inbox.Count(t.Context()); n != 0 {
+		t.Errorf("inbox has %d items, want 0: script commands bypass the agent", n)
+	}
+}
+
+// The handler must return while the program runs: backends deliver
+// messages one at a time.
+func TestApp_ScriptCommandDoesNotBlockHandler(t *testing.T) {
+	t.Parallel()
+
+	release := filepath.Join(t.TempDir(), "release")
+	script := writeTestFile(t, "wait.sh", `#!/bin/sh
+while [ ! -e "$1" ]; do sleep 0.01; done
+echo done
+`)
+	must(t, os.Chmod(script, 0o700))
+
+	app, mb := newTestAppWithBackend(t, &mockBackend{})
+	app.worker.piCfg.WorkingDir = t.TempDir()
+
+	cmd := CustomCommand{Name: "wait", Exec: []string{script, release}}
+	must(t, cmd.validate())
+	must(t, app.registerCustomCommands([]CustomCommand{cmd}))
+
+	sendCommand(app, "!wait")
+
+	mb.mu.Lock()
+	sent := len(mb.sentMessages)
+	mb.mu.Unlock()
+
+	if sent != 0 {
+		t.Fatalf("sent %d messages before the program finished, want 0", sent)
+	}
+
+	must(t, os.WriteFile(release, nil, 0o600))
+	app.scripts.Wait()
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if len(mb.sentMessages) != 1 || !strings.Contains(mb.sentMessages[0].text, "done") {
+		t.Errorf("sent %v, want the program's output", mb.sentMessages)
+	}
+}
+
+func TestApp_WaitScripts(t *testing.T) {
+	t.Parallel()
+
+	release := filepath.Join(t.TempDir(), "release")
+	script := writeTestFile(t, "wait.sh", `#!/bin/sh
+while [ ! -e "$1" ]; do sleep 0.01; done
+echo done
+`)
+	must(t, os.Chmod(script, 0o700))
+
+	app, mb := newTestAppWithBackend(t, &mockBackend{})
+	app.worker.piCfg.WorkingDir = t.TempDir()
+
+	cmd := CustomCommand{Name: "wait", Exec: []string{script, release}}
+	must(t, cmd.validate())
+	must(t, app.registerCustomCommands([]CustomCommand{cmd}))
+
+	sendCommand(app, "!wait")
+
+	if app.waitScripts(50 * time.Millisecond) {
+		t.Fatal("waitScripts returned true while the program was running")
+	}
+
+	must(t, os.WriteFile(release, nil, 0o600))
+
+	if !app.waitScripts(5 * time.Second) {
+		t.Fatal("waitScripts timed out after the program finished")
+	}
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if len(mb.sentMessages) != 1 {
+		t.Errorf("sent %v, want the reply sent before waitScripts returned", mb.sentMessages)
+	}
+}
+
+func TestRunScript_Timeout(t *testing.T) {
+	t.Parallel()
+
+	script := writeTestFile(t, "slow.sh", "#!/bin/sh\necho started\nsleep 30\n")
+	must(t, os.Chmod(script, 0o700))
+
+	cmd := CustomCommand{Name: "slow", Exec: []string{script}, Timeout: "200ms"}
+	must(t, cmd.validate())
+
+	out, err := runScript(t.Context(), cmd, t.TempDir(), nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("err = %v, want timeout", err)
+	}
+
+	if !strings.Contains(out, "started") {
+		t.Errorf("output = %q, want partial output", out)
+	}
+}
+
+func TestFormatScriptOutput(t *testing.T) {
+	t.Parallel()
+
+	if got := formatScriptOutput("  \n", nil, backend.MarkdownFull); got != "(no output)" {
+		t.Errorf("empty output = %q", got)
+	}
+
+	if got := formatScriptOutput("42G free\n", nil, backend.MarkdownNone); got != "42G free" {
+		t.Errorf("plain output = %q", got)
+	}
+
+	long := strings.Repeat("x", maxScriptOutput) + "END"
+	if got := formatScriptOutput(long, nil, backend.MarkdownNone); !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "END") {
+		t.Errorf("long output keeps %q…%q, want the tail", got[:10], got[len(got)-10:])
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// defaultCommandPrefix introduces a chat command unless
+// OPENCROW_COMMAND_PREFIXES says otherwise.
+const defaultCommandPrefix = "!"
+
+// commandNameRe restricts command names and aliases to what is easy to
+// type on a phone keyboard.
+var commandNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// CommandsConfig configures the chat command registry.
+type CommandsConfig struct {
+	Prefixes []string        // OPENCROW_COMMAND_PREFIXES, default "!"
+	Commands []CustomCommand // OPENCROW_COMMANDS_FILE (JSON list), default none
+}
+
+// CustomCommand is one entry of OPENCROW_COMMANDS_FILE: a chat command
+// that either expands to a prompt for the agent or runs a program and
+// replies with its output.
+type CustomCommand struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	// Args is the argument synopsis shown in help, e.g. "<url>". A
+	// synopsis with a "<placeholder>" makes arguments required.
+	Args string `json:"args"`
+	Help string `json:"help"`
+	// Prompt is a Go template; {{.Args}} is the text after the command.
+	Prompt string `json:"prompt"`
+	// Exec is an absolute program path and its fixed arguments. The
+	// user's arguments are appended after sanitizing.
+	Exec []string `json:"exec"`
+	// Timeout bounds an Exec run, default defaultScriptTimeout.
+	Timeout string `json:"timeout"`
+
+	tmpl    *template.Template
+	timeout time.Duration
+}
+
+// promptCommandData is what a CustomCommand prompt template can reference.
+type promptCommandData struct {
+	Args string
+}
+
+// loadCustomCommands reads and validates the JSON command list at path.
+// All errors are reported at once.
+func loadCustomCommands(path string) ([]CustomCommand, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OPENCROW_COMMANDS_FILE: %w", err)
+	}
+
+	var cmds []CustomCommand
+	if err := json.Unmarshal(data, &cmds); err != nil {
+		return nil, fmt.Errorf("parsing OPENCROW_COMMANDS_FILE: %w", err)
+	}
+
+	var errs error
+
+	for i := range cmds {
+		c := &cmds[i]
+
+		if !commandNameRe.MatchString(c.Name) {
+			errs = errors.Join(errs, fmt.Errorf("command #%d: name %q must be lowercase letters, digits, - or _", i+1, c.Name))
+
+			continue
+		}
+
+		for _, alias := range c.Aliases {
+			if !commandNameRe.MatchString(alias) {
+				errs = errors.Join(errs, fmt.Errorf("command %q: alias %q must be lowercase letters, digits, - or _", c.Name, alias))
+			}
+		}
+
+		errs = errors.Join(errs, c.validate())
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return cmds, nil
+}
+
+// validate checks that c has exactly one action and prepares it.
+func (c *CustomCommand) validate() error {
+	switch hasPrompt := strings.TrimSpace(c.Prompt) != ""; {
+	case hasPrompt && len(c.Exec) > 0:
+		return fmt.Errorf("command %q: set either prompt or exec, not both", c.Name)
+	case hasPrompt:
+		var err error
+		if c.tmpl, err = template.New(c.Name).Parse(c.Prompt); err != nil {
+			return fmt.Errorf("command %q: prompt: %w", c.Name, err)
+		}
+
+		return nil
+	case len(c.Exec) > 0:
+		return c.validateExec()
+	default:
+		return fmt.Errorf("command %q: prompt or exec is required", c.Name)
+	}
+}
+
+// chatCommand is one entry of the command registry.
+type chatCommand struct {
+	name    string
+	aliases []string
+	usage   string // argument synopsis such as "<query>"; "" takes no arguments
+	help    string
+	run     func(ctx context.Context, msg backend.Message, args string)
+}
+
+// argsRequired reports whether the usage has a "<placeholder>".
+func (c *chatCommand) argsRequired() bool {
+	return strings.Contains(c.usage, "<")
+}
+
+// commandRegistry maps command names and aliases to handlers. Commands
+// are listed in help in registration order.
+type commandRegistry struct {
+	prefixes []string
+	commands []*chatCommand
+	byName   map[string]*chatCommand
+}
+
+func newCommandRegistry(prefixes []string) *commandRegistry {
+	if len(prefixes) == 0 {
+		prefixes = []string{defaultCommandPrefix}
+	}
+
+	return &commandRegistry{prefixes: prefixes, byName: make(map[string]*chatCommand)}
+}
+
+// register adds c. Names and aliases must be unique across the registry.
+func (r *commandRegistry) register(c *chatCommand) error {
+	for _, name := range append([]string{c.name}, c.aliases...) {
+		if _, ok := r.byName[name]; ok {
+			return fmt.Errorf("command %q: name %q is already taken", c.name, name)
+		}
+	}
+
+	for _, name := range append([]string{c.name}, c.aliases...) {
+		r.byName[name] = c
+	}
+
+	r.commands = append(r.commands, c)
+
+	return nil
+}
+
+// lookup resolves a command name, with or without a prefix.
+func (r *commandRegistry) lookup(name string) *chatCommand {
+	for _, p := range r.prefixes {
+		if rest, ok := strings.CutPrefix(name, p); ok && rest != "" {
+			name = rest
+
+			break
+		}
+	}
+
+	return r.byName[strings.ToLower(name)]
+}
+
+// match parses text as a command invocation. Commands that take no
+// arguments only match when none are given, so "!stop the presses" still
+// reaches the agent.
+func (r *commandRegistry) match(text string) (*chatCommand, string, bool) {
+	text = strings.TrimSpace(text)
+
+	for _, p := range r.prefixes {
+		rest, ok := strings.CutPrefix(text, p)
+		if !ok {
+			continue
+		}
+
+		name, args, _ := strings.Cut(rest, " ")
+
+		c := r.byName[strings.ToLower(strings.TrimSpace(name))]
+		if c == nil {
+			continue
+		}
+
+		args = strings.TrimSpace(args)
+		if args != "" && c.usage == "" {
+			return nil, "", false
+		}
+
+		return c, args, true
+	}
+
+	return nil, "", false
+}
+
+// synopsis renders c as typed, e.g. "!search <query>".
+func (r *commandRegistry) synopsis(c *chatCommand) string {
+	return strings.TrimSpace(r.prefixes[0] + c.name + " " + c.usage)
+}
+
+// summary lists every command with its one-line help.
+func (r *commandRegistry) summary() string {
+	var sb strings.Builder
+
+	sb.WriteString("Available commands:")
+
+	for _, c := range r.commands {
+		fmt.Fprintf(&sb, "\n  %s — %s", r.synopsis(c), c.help)
+	}
+
+	if len(r.prefixes) > 1 {
+		fmt.Fprintf(&sb, "\n\nCommands can start with any of: %s", strings.Join(r.prefixes, " "))
+	}
+
+	return sb.String()
+}
+
+// describe is the help for a single command.
+func (r *commandRegistry) describe(c *chatCommand) string {
+	text := fmt.Sprintf("%s — %s", r.synopsis(c), c.help)
+
+	if len(c.aliases) > 0 {
+		aliases := slices.Clone(c.aliases)
+		for i, a := range aliases {
+			aliases[i] = r.prefixes[0] + a
+		}
+
+		text += "\nAliases: " + strings.Join(aliases, ", ")
+	}
+
+	return text
+}
+
+// registerBuiltinCommands adds the commands every instance has.
+func (a *App) registerBuiltinCommands() {
+	for _, c := range []*chatCommand{
+		{name: "help", usage: "[command]", help: "Show this help message, or details on one command", run: a.handleHelp},
+		{name: "restart", help: "Kill the current session and start fresh", run: a.handleRestart},
+		{name: "stop", help: "Abort the currently running agent turn", run: a.handleStop},
+		{name: "compact", help: "Compact conversation context to reduce token usage", run: a.handleCompact},
+		{name: "skills", help: "List loaded skills", run: a.handleSkills},
+		{name: "status", help: "Show session, queue and scheduled job status", run: a.handleStatus},
+		{name: "search", usage: "<query>", help: "Search past messages in this conversation", run: a.handleSearch},
+		{name: "verbose", help: "Show tool calls, thinking and timing in this conversation", run: a.handleVerbose},
+		{name: "quiet", help: "Show only replies in this conversation", run: a.handleQuiet},
+		{name: "tools", usage: "[on|off]", help: "Show or toggle tool call notifications in this conversation", run: a.handleTools},
+		{name: "thinking", usage: "[on|off]", help: "Show or toggle relaying the model's thinking in this conversation", run: a.handleThinking},
+	} {
+		if err := a.commands.register(c); err != nil {
+			panic(err) // built-in names are fixed and distinct
+		}
+	}
+}
+
+// registerCustomCommands adds the operator-defined commands.
+func (a *App) registerCustomCommands(cmds []CustomCommand) error {
+	var errs error
+
+	for _, c := range cmds {
+		run := a.promptCommand(c)
+		if len(c.Exec) > 0 {
+			run = a.scriptCommand(c)
+		}
+
+		errs = errors.Join(errs, a.commands.register(&chatCommand{
+			name:    c.Name,
+			aliases: c.Aliases,
+			usage:   c.Args,
+			help:    customCommandHelp(c),
+			run:     run,
+		}))
+	}
+
+	return errs
+}
+
+func customCommandHelp(c CustomCommand) string {
+	if c.Help != "" {
+		return c.Help
+	}
+
+	if len(c.Exec) > 0 {
+		return "Run " + filepath.Base(c.Exec[0])
+	}
+
+	first, _, _ := strings.Cut(strings.TrimSpace(c.Prompt), "\n")
+
+	return "Ask: " + truncateRunes(first, 60)
+}
+
+// promptCommand returns a handler that sends pc's expanded prompt to the
+// agent as if the user had typed it.
+func (a *App) promptCommand(pc CustomCommand) func(context.Context, backend.Message, string) {
+	return func(ctx context.Context, msg backend.Message, args string) {
+		var sb strings.Builder
+		if err := pc.tmpl.Execute(&sb, promptCommandData{Args: args}); err != nil {
+			slog.Error("prompt command failed", "command", pc.Name, "error", err)
+			a.backend.SendMessage(ctx, msg.ConversationID, fmt.Sprintf("Command failed: %v", err), "")
+
+			return
+		}
+
+		a.recordInbound(ctx, msg)
+
+		msg.Text = sb.String()
+		a.enqueuePrompt(ctx, msg)
+	}
+}
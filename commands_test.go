@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+func TestCommandRegistry_Match(t *testing.T) {
+	t.Parallel()
+
+	r := newCommandRegistry([]string{"!", "/"})
+	must(t, r.register(&chatCommand{name: "stop"}))
+	must(t, r.register(&chatCommand{name: "search", aliases: []string{"s"}, usage: "<query>"}))
+
+	cases := []struct {
+		text     string
+		wantName string
+		wantArgs string
+	}{
+		{"!stop", "stop", ""},
+		{"  /STOP \n", "stop", ""},
+		{"!s  backup logs ", "search", "backup logs"},
+		{"!search", "search", ""},
+		{"!stop the presses", "", ""}, // no arguments taken: goes to the agent
+		{"!unknown", "", ""},
+		{"stop", "", ""},
+		{"!", "", ""},
+	}
+
+	for _, tc := range cases {
+		cmd, args, ok := r.match(tc.text)
+
+		if tc.wantName == "" {
+			if ok {
+				t.Errorf("match(%q) = %q, want no match", tc.text, cmd.name)
+			}
+
+			continue
+		}
+
+		if !ok || cmd.name != tc.wantName || args != tc.wantArgs {
+			t.Errorf("match(%q) = %v %q %v, want %q %q", tc.text, cmd, args, ok, tc.wantName, tc.wantArgs)
+		}
+	}
+
+	if err := r.register(&chatCommand{name: "find", aliases: []string{"s"}}); err == nil {
+		t.Error("registering a taken alias succeeded")
+	}
+}
+
+func TestApp_HelpForCommand(t *testing.T) {
+	t.Parallel()
+
+	app, mb := newTestApp(t)
+	must(t, app.registerCustomCommands([]CustomCommand{{Name: "tldr", Aliases: []string{"tl"}, Args: "<url>", Help: "Summarize a page"}}))
+
+	sendCommand(app, "!help")
+	sendCommand(app, "!help !tl")
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if len(mb.sentMessages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(mb.sentMessages))
+	}
+
+	if want := "!tldr <url> — Summarize a page"; !strings.Contains(mb.sentMessages[0].text, want) {
+		t.Errorf("help %q missing %q", mb.sentMessages[0].text, want)
+	}
+
+	if want := "!tldr <url> — Summarize a page\nAliases: !tl"; mb.sentMessages[1].text != want {
+		t.Errorf("help tl = %q, want %q", mb.sentMessages[1].text, want)
+	}
+}
+
+func TestApp_CustomCommand(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestFile(t, "commands.json", `[{"name": "tldr", "args": "<url>", "prompt": "Summarize {{.Args}} in 5 bullets."}]`)
+	cmds, err := loadCustomCommands(path)
+	must(t, err)
+
+	ctx := context.Background()
+	app, mb := newTestApp(t)
+	must(t, app.registerCustomCommands(cmds))
+
+	sendCommand(app, "!tldr")
+	app.HandleMessage(ctx, backend.Message{ConversationID: testRoom, SenderID: "@user:example.com", Text: "!tldr https://example.com/post"})
+
+	mb.mu.Lock()
+	if len(mb.sentMessages) != 1 || mb.sentMessages[0].text != "Usage: !tldr <url>" {
+		t.Errorf("sent %v, want one usage reply", mb.sentMessages)
+	}
+	mb.mu.Unlock()
+
+	item, err := app.inbox.Dequeue(ctx)
+	must(t, err)
+
+	if want := "Summarize https://example.com/post in 5 bullets."; item.Content != want {
+		t.Errorf("Content = %q, want %q", item.Content, want)
+	}
+}
+
+func TestLoadCustomCommands_ReportsAllErrors(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestFile(t, "commands.json", `[
+		{"name": "Bad Name", "prompt": "x"},
+		{"name": "empty"},
+		{"name": "broken", "prompt": "{{.Args"},
+		{"name": "alias", "aliases": ["no way"], "prompt": "x"}
+	]`)
+
+	_, err := loadCustomCommands(path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{`"Bad Name"`, `"empty": prompt or exec is required`, `"broken"`, `"no way"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,17 @@ type Config struct {
 	Socket      SocketConfig
 	Pi          PiConfig
 	Heartbeat   HeartbeatConfig
+	Cron        []CronJob   // OPENCROW_CRON_FILE (JSON list), default none
+	Watch       []WatchRule // OPENCROW_WATCH_FILE (JSON list), default none
+	Feeds       FeedsConfig
+	Calendar    CalendarConfig
+	Webhook     WebhookConfig
+	Trigger     TriggerConfig
+	Reply       ReplyConfig
+	Prompt      PromptConfig
+	Group       GroupConfig
+	Commands    CommandsConfig
+	Tools       ToolsConfig
 }
 
 type SocketConfig struct {
@@ -37,6 +49,34 @@ type SocketConfig struct {
 type HeartbeatConfig struct {
 	Interval time.Duration // OPENCROW_HEARTBEAT_INTERVAL, default 0 (disabled)
 	Prompt   string        // OPENCROW_HEARTBEAT_PROMPT, default built-in
+	// ReminderRefire re-fires important reminders that were delivered but
+	// neither snoozed nor acknowledged within this duration —
+	// OPENCROW_REMINDER_REFIRE_INTERVAL, default 0 (disabled).
+	ReminderRefire     time.Duration
+	ReminderMaxRefires int // OPENCROW_REMINDER_MAX_REFIRES, default 3
+}
+
+// Trigger dedup policies for OPENCROW_TRIGGER_DEDUP.
+const (
+	triggerDedupKey   = "key"   // only triggers carrying the same dedup_key
+	triggerDedupExact = "exact" // also triggers with identical source and content
+	triggerDedupOff   = "off"   // never drop triggers
+)
+
+type TriggerConfig struct {
+	Merge bool // OPENCROW_TRIGGER_MERGE — fold queued triggers into one turn
+	// MergeWindow holds a trigger back until it is this old so a burst
+	// lands in one turn — OPENCROW_TRIGGER_MERGE_WINDOW, default 0.
+	MergeWindow time.Duration
+	Dedup       string // OPENCROW_TRIGGER_DEDUP, default triggerDedupKey
+	// DedupWindow also drops triggers matching one accepted this recently,
+	// even if it was already processed — OPENCROW_TRIGGER_DEDUP_WINDOW,
+	// default 0 (compare against queued triggers only).
+	DedupWindow time.Duration
+	Sources     []TriggerSource // OPENCROW_TRIGGER_SOURCES_FILE (JSON list), default none
+	// SyncTimeout bounds how long a trigger.sock caller waits for the
+	// reply — OPENCROW_TRIGGER_SYNC_TIMEOUT, default 30m.
+	SyncTimeout time.Duration
 }
 
 type MatrixConfig struct {
@@ -89,7 +129,9 @@ type PiConfig struct {
 	Skills       []string
 	// Extensions are omp extension paths (dirs or files) loaded via
 	// --extension — OPENCROW_PI_EXTENSIONS, comma-separated.
-	Extensions    []string
+	Extensions []string
+	// ShowToolCalls and DebugTiming are defaults; !verbose, !quiet and
+	// !tools override them per conversation.
 	ShowToolCalls bool // OPENCROW_SHOW_TOOL_CALLS — relay tool_execution_start events to chat
 	DebugTiming   bool // OPENCROW_DEBUG_TIMING — append timing info to each reply
 }
@@ -126,6 +168,81 @@ func loadConfig(getenv func(string) string) (*Config, error) {
 		return nil, err
 	}
 
+	reminderRefire, err := env.duration("OPENCROW_REMINDER_REFIRE_INTERVAL", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	reminderMaxRefires, err := env.int("OPENCROW_REMINDER_MAX_REFIRES", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	var cronJobs []CronJob
+	if path := env.str("OPENCROW_CRON_FILE"); path != "" {
+		if cronJobs, err = loadCronJobs(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var watchRules []WatchRule
+	if path := env.str("OPENCROW_WATCH_FILE"); path != "" {
+		if watchRules, err = loadWatchRules(path); err != nil {
+			return nil, err
+		}
+	}
+
+	calendar := CalendarConfig{Paths: env.list("OPENCROW_CALENDAR_PATHS")}
+	if calendar.Lead, err = env.duration("OPENCROW_CALENDAR_LEAD", defaultCalendarLead); err != nil {
+		return nil, err
+	}
+
+	feeds, err := loadFeedsConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	trigger, err := loadTriggerConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := loadWebhookConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := loadReplyConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := loadPromptConfig(env, backendType)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := loadGroupConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err := loadCommandsConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	tools, err := loadToolsConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range webhook.Routes {
+		if r.Source != "" && trigger.source(r.Source) == nil {
+			return nil, fmt.Errorf("webhook route %q: unknown trigger source %q", r.Name, r.Source)
+		}
+	}
+
 	cfg := &Config{
 		BackendType: backendType,
 		Matrix: MatrixConfig{
@@ -144,7 +261,7 @@ func loadConfig(getenv func(string) string) (*Config, error) {
 		},
 		Pi: PiConfig{
 			BinaryPath:    env.or("OPENCROW_PI_BINARY", "omp"),
-			SessionDir:    env.or("OPENCROW_PI_SESSION_DIR", "/var/lib/opencrow/sessions"),
+			SessionDir:    env.or("OPENCROW_PI_SESSION_DIR", defaultSessionDir),
 			Provider:      env.or("OPENCROW_PI_PROVIDER", "anthropic"),
 			Model:         env.or("OPENCROW_PI_MODEL", "claude-opus-4-6"),
 			WorkingDir:    workingDir,
@@ -156,9 +273,22 @@ func loadConfig(getenv func(string) string) (*Config, error) {
 			DebugTiming:   env.bool("OPENCROW_DEBUG_TIMING"),
 		},
 		Heartbeat: HeartbeatConfig{
-			Interval: heartbeatInterval,
-			Prompt:   env.or("OPENCROW_HEARTBEAT_PROMPT", defaultHeartbeatPrompt),
+			Interval:           heartbeatInterval,
+			Prompt:             env.or("OPENCROW_HEARTBEAT_PROMPT", defaultHeartbeatPrompt),
+			ReminderRefire:     reminderRefire,
+			ReminderMaxRefires: reminderMaxRefires,
 		},
+		Cron:     cronJobs,
+		Watch:    watchRules,
+		Feeds:    feeds,
+		Calendar: calendar,
+		Webhook:  webhook,
+		Trigger:  trigger,
+		Reply:    reply,
+		Prompt:   prompt,
+		Group:    group,
+		Commands: commands,
+		Tools:    tools,
 	}
 
 	if err := cfg.validateBackend(env); err != nil {
@@ -168,6 +298,207 @@ func loadConfig(getenv func(string) string) (*Config, error) {
 	return cfg, nil
 }
 
+func loadTriggerConfig(env envReader) (TriggerConfig, error) {
+	cfg := TriggerConfig{
+		Merge: env.bool("OPENCROW_TRIGGER_MERGE"),
+		Dedup: env.or("OPENCROW_TRIGGER_DEDUP", triggerDedupKey),
+	}
+
+	switch cfg.Dedup {
+	case triggerDedupKey, triggerDedupExact, triggerDedupOff:
+	default:
+		return cfg, fmt.Errorf("OPENCROW_TRIGGER_DEDUP=%q is not supported (valid: key, exact, off)", cfg.Dedup)
+	}
+
+	var err error
+
+	if cfg.MergeWindow, err = env.duration("OPENCROW_TRIGGER_MERGE_WINDOW", 0); err != nil {
+		return cfg, err
+	}
+
+	if cfg.DedupWindow, err = env.duration("OPENCROW_TRIGGER_DEDUP_WINDOW", 0); err != nil {
+		return cfg, err
+	}
+
+	if cfg.SyncTimeout, err = env.duration("OPENCROW_TRIGGER_SYNC_TIMEOUT", defaultSyncTriggerTimeout); err != nil {
+		return cfg, err
+	}
+
+	if cfg.SyncTimeout <= 0 {
+		return cfg, errors.New("OPENCROW_TRIGGER_SYNC_TIMEOUT must be positive")
+	}
+
+	if path := env.str("OPENCROW_TRIGGER_SOURCES_FILE"); path != "" {
+		if cfg.Sources, err = loadTriggerSources(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadFeedsConfig(env envReader) (FeedsConfig, error) {
+	var (
+		cfg FeedsConfig
+		err error
+	)
+
+	if cfg.Interval, err = env.duration("OPENCROW_FEED_INTERVAL", defaultFeedInterval); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Interval <= 0 {
+		return cfg, errors.New("OPENCROW_FEED_INTERVAL must be positive")
+	}
+
+	if path := env.str("OPENCROW_FEEDS_FILE"); path != "" {
+		if cfg.Feeds, err = loadFeeds(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadPromptConfig(env envReader, backendType string) (PromptConfig, error) {
+	cfg := PromptConfig{
+		Envelope: env.or("OPENCROW_PROMPT_ENVELOPE", defaultPromptEnvelope),
+		Backend:  backendType,
+	}
+
+	if tz := env.str("OPENCROW_TIMEZONE"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return cfg, fmt.Errorf("OPENCROW_TIMEZONE: %w", err)
+		}
+
+		cfg.Location = loc
+	}
+
+	return cfg, cfg.load()
+}
+
+func loadReplyConfig(env envReader) (ReplyConfig, error) {
+	var (
+		cfg ReplyConfig
+		err error
+	)
+
+	if cfg.Depth, err = env.int("OPENCROW_REPLY_CHAIN_DEPTH", defaultReplyChainDepth); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Tokens, err = env.int("OPENCROW_REPLY_CHAIN_TOKENS", defaultReplyChainTokens); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Depth < 1 || cfg.Tokens < 1 {
+		return cfg, errors.New("OPENCROW_REPLY_CHAIN_DEPTH and OPENCROW_REPLY_CHAIN_TOKENS must be at least 1")
+	}
+
+	return cfg, nil
+}
+
+func loadCommandsConfig(env envReader) (CommandsConfig, error) {
+	cfg := CommandsConfig{Prefixes: env.list("OPENCROW_COMMAND_PREFIXES")}
+	if len(cfg.Prefixes) == 0 {
+		cfg.Prefixes = []string{defaultCommandPrefix}
+	}
+
+	if path := env.str("OPENCROW_COMMANDS_FILE"); path != "" {
+		var err error
+		if cfg.Commands, err = loadCustomCommands(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadToolsConfig(env envReader) (ToolsConfig, error) {
+	cfg := ToolsConfig{
+		Results: env.or("OPENCROW_TOOL_RESULTS", toolResultsFailures),
+		Summary: env.bool("OPENCROW_TOOL_SUMMARY"),
+	}
+
+	switch cfg.Results {
+	case toolResultsOff, toolResultsFailures, toolResultsAll:
+	default:
+		return cfg, fmt.Errorf("OPENCROW_TOOL_RESULTS must be %s, %s or %s, got %q",
+			toolResultsOff, toolResultsFailures, toolResultsAll, cfg.Results)
+	}
+
+	var err error
+	if cfg.OutputTail, err = env.int("OPENCROW_TOOL_OUTPUT_TAIL", defaultToolOutputTail); err != nil {
+		return cfg, err
+	}
+
+	if cfg.OutputTail < 0 {
+		return cfg, errors.New("OPENCROW_TOOL_OUTPUT_TAIL must not be negative")
+	}
+
+	if cfg.DigestInterval, err = env.duration("OPENCROW_TOOL_DIGEST_INTERVAL", defaultToolDigestInterval); err != nil {
+		return cfg, err
+	}
+
+	if cfg.ProgressInterval, err = env.duration("OPENCROW_PROGRESS_INTERVAL", defaultProgressInterval); err != nil {
+		return cfg, err
+	}
+
+	if cfg.ProgressInterval < 0 {
+		return cfg, errors.New("OPENCROW_PROGRESS_INTERVAL must not be negative")
+	}
+
+	if path := env.str("OPENCROW_TOOL_TEMPLATES_FILE"); path != "" {
+		if cfg.Templates, err = loadToolTemplates(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadGroupConfig(env envReader) (GroupConfig, error) {
+	cfg := GroupConfig{
+		MentionOnly: env.bool("OPENCROW_GROUP_MENTION_ONLY"),
+		Prefix:      env.str("OPENCROW_GROUP_PREFIX"),
+	}
+
+	var err error
+	if cfg.Context, err = env.int("OPENCROW_GROUP_CONTEXT", defaultGroupContext); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Context < 0 {
+		return cfg, errors.New("OPENCROW_GROUP_CONTEXT must not be negative")
+	}
+
+	return cfg, nil
+}
+
+// loadWebhookConfig reads the optional webhook listener. Routes are only
+// required once a listen address is set.
+func loadWebhookConfig(env envReader) (WebhookConfig, error) {
+	cfg := WebhookConfig{Listen: env.str("OPENCROW_WEBHOOK_LISTEN")}
+	if cfg.Listen == "" {
+		return cfg, nil
+	}
+
+	path := env.str("OPENCROW_WEBHOOK_FILE")
+	if path == "" {
+		return cfg, errors.New("OPENCROW_WEBHOOK_FILE is required when OPENCROW_WEBHOOK_LISTEN is set")
+	}
+
+	routes, err := loadWebhookRoutes(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.Routes = routes
+
+	return cfg, nil
+}
+
 func loadMatrixPassword(env envReader) (string, error) {
 	if path := env.str("OPENCROW_MATRIX_PASSWORD_FILE"); path != "" {
 		data, err := os.ReadFile(path)
@@ -294,6 +625,21 @@ func (e envReader) duration(key string, def time.Duration) (time.Duration, error
 	return d, nil
 }
 
+// int parses a base-10 integer, returning def if unset.
+func (e envReader) int(key string, def int) (int, error) {
+	v := e.getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", key, err)
+	}
+
+	return n, nil
+}
+
 func parseSkills(env envReader) []string {
 	skills := env.list("OPENCROW_PI_SKILLS")
 
@@ -375,6 +721,8 @@ Edit the file to add/remove checks. Prefix with [paused] to skip without
 deleting. This is a stable checklist — for one-shot reminders, use the
 remind_at tool instead.`
 
+const defaultSessionDir = "/var/lib/opencrow/sessions"
+
 const defaultHeartbeatPrompt = `Run through the standing checks below.
 If nothing needs attention, reply with exactly: HEARTBEAT_OK`
 
@@ -121,6 +121,42 @@ func TestLoadConfig_Errors(t *testing.T) {
 				"OPENCROW_NOSTR_PRIVATE_KEY": "0000000000000000000000000000000000000000000000000000000000000001",
 			},
 		},
+		{
+			name: "unknown timezone",
+			env: func() map[string]string {
+				m := baseMatrixEnv()
+				m["OPENCROW_TIMEZONE"] = "Mars/Olympus_Mons"
+
+				return m
+			}(),
+		},
+		{
+			name: "bad prompt envelope",
+			env: func() map[string]string {
+				m := baseMatrixEnv()
+				m["OPENCROW_PROMPT_ENVELOPE"] = "{{.Content"
+
+				return m
+			}(),
+		},
+		{
+			name: "unknown tool results mode",
+			env: func() map[string]string {
+				m := baseMatrixEnv()
+				m["OPENCROW_TOOL_RESULTS"] = "some"
+
+				return m
+			}(),
+		},
+		{
+			name: "negative progress interval",
+			env: func() map[string]string {
+				m := baseMatrixEnv()
+				m["OPENCROW_PROGRESS_INTERVAL"] = "-1m"
+
+				return m
+			}(),
+		},
 	}
 
 	for _, tc := range cases {
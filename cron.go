@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// CronJob is one entry of OPENCROW_CRON_FILE: a named prompt enqueued as a
+// trigger whenever its five-field cron schedule matches, in the
+// OPENCROW_TIMEZONE zone (default server local time).
+type CronJob struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	// Prompt is a text/template rendered with cronPromptData at each run.
+	Prompt string `json:"prompt"`
+
+	spec cronSpec
+	tmpl *template.Template
+}
+
+// cronPromptData is what a job's prompt template can reference, e.g.
+// {{.LastRun.Format "2006-01-02"}}. LastRun is the zero time on the
+// first run.
+type cronPromptData struct {
+	Name    string
+	Now     time.Time
+	LastRun time.Time
+}
+
+// loadCronJobs reads and validates the JSON job list at path. All errors
+// are reported up front so a typo in a schedule fails startup instead of
+// silently never firing.
+func loadCronJobs(path string) ([]CronJob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OPENCROW_CRON_FILE: %w", err)
+	}
+
+	var jobs []CronJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("parsing OPENCROW_CRON_FILE: %w", err)
+	}
+
+	seen := make(map[string]bool, len(jobs))
+
+	var errs error
+
+	for i := range jobs {
+		j := &jobs[i]
+
+		if j.Name == "" {
+			errs = errors.Join(errs, fmt.Errorf("cron job #%d: name is required", i+1))
+
+			continue
+		}
+
+		if seen[j.Name] {
+			errs = errors.Join(errs, fmt.Errorf("cron job %q: duplicate name", j.Name))
+		}
+
+		seen[j.Name] = true
+
+		if strings.TrimSpace(j.Prompt) == "" {
+			errs = errors.Join(errs, fmt.Errorf("cron job %q: prompt is required", j.Name))
+		}
+
+		if j.spec, err = parseCronSpec(j.Schedule); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("cron job %q: %w", j.Name, err))
+		}
+
+		if j.tmpl, err = template.New(j.Name).Option("missingkey=error").Parse(j.Prompt); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("cron job %q: prompt template: %w", j.Name, err))
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return jobs, nil
+}
+
+// cronSpec is a parsed five-field cron expression. Each field is a bitset
+// of allowed values.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	// domAny/dowAny record a literal "*" so day matching can follow
+	// cron's rule: if both day fields are restricted, either may match.
+	domAny, dowAny bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCronSpec parses "minute hour day-of-month month day-of-week" with
+// *, lists, ranges and steps (e.g. "*/15 9-17 * * 1-5"), or one of the
+// @daily-style macros. Day-of-week 7 is Sunday, like 0.
+func parseCronSpec(expr string) (cronSpec, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := cronMacros[expr]; ok {
+		expr = m
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSpec{}, fmt.Errorf("schedule %q: want 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+
+	var (
+		spec cronSpec
+		err  error
+		errs error
+	)
+
+	spec.minute, err = parseCronField(fields[0], 0, 59)
+	errs = errors.Join(errs, err)
+	spec.hour, err = parseCronField(fields[1], 0, 23)
+	errs = errors.Join(errs, err)
+	spec.dom, err = parseCronField(fields[2], 1, 31)
+	errs = errors.Join(errs, err)
+	spec.month, err = parseCronField(fields[3], 1, 12)
+	errs = errors.Join(errs, err)
+	spec.dow, err = parseCronField(fields[4], 0, 7)
+	errs = errors.Join(errs, err)
+
+	if errs != nil {
+		return cronSpec{}, fmt.Errorf("schedule %q: %w", expr, errs)
+	}
+
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+
+	spec.domAny = fields[2] == "*"
+	spec.dowAny = fields[4] == "*"
+
+	return spec, nil
+}
+
+// parseCronField parses one comma-separated field into a bitset.
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+
+	for part := range strings.SplitSeq(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+
+			step = n
+		}
+
+		start, end := lo, hi
+
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+
+			var err error
+			if start, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+
+			end = start
+
+			switch {
+			case isRange:
+				if end, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			case hasStep:
+				end = hi
+			}
+		}
+
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+// cronSearchLimit bounds next() for schedules that can never match, such
+// as "0 0 31 2 *".
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// next returns the first matching minute strictly after t, in t's
+// location, or the zero time if none exists within cronSearchLimit.
+func (s cronSpec) next(t time.Time) time.Time {
+	limit := t.Add(cronSearchLimit)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s cronSpec) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// cronScheduler fires config-defined cron jobs as trigger items and keeps
+// their last/next run times for !status. Schedules are evaluated and shown
+// in loc.
+type cronScheduler struct {
+	w    *Worker
+	jobs []CronJob
+	loc  *time.Location
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+	nextRun map[string]time.Time
+}
+
+// newCronScheduler loads persisted last-run times and computes each job's
+// next run. A job whose scheduled time passed while opencrow was down is
+// due immediately, so at most one missed run is caught up.
+func newCronScheduler(ctx context.Context, w *Worker, jobs []CronJob, now time.Time) *cronScheduler {
+	s := &cronScheduler{
+		w:       w,
+		jobs:    jobs,
+		loc:     w.promptCfg.location(),
+		lastRun: make(map[string]time.Time),
+		nextRun: make(map[string]time.Time),
+	}
+
+	runs, err := w.inbox.queries.ListCronRuns(ctx)
+	if err != nil {
+		slog.Warn("cron: failed to load last runs", "error", err)
+	}
+
+	for _, r := range runs {
+		if t, err := time.Parse(time.RFC3339, r.LastRun); err == nil {
+			s.lastRun[r.Name] = t
+		}
+	}
+
+	now = now.In(s.loc)
+
+	for _, j := range jobs {
+		from := now
+		if last, ok := s.lastRun[j.Name]; ok {
+			from = last.In(s.loc)
+		}
+
+		s.nextRun[j.Name] = j.spec.next(from)
+	}
+
+	return s
+}
+
+// startCron runs the cron scheduler on reminderTick until ctx is done.
+func startCron(ctx context.Context, s *cronScheduler) {
+	slog.Info("cron scheduler started", "jobs", len(s.jobs))
+
+	go func() {
+		ticker := time.NewTicker(reminderTick)
+		defer ticker.Stop()
+
+		s.tick(ctx, time.Now())
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.tick(ctx, now)
+			}
+		}
+	}()
+}
+
+// tick enqueues every job whose next run is at or before now.
+func (s *cronScheduler) tick(ctx context.Context, now time.Time) {
+	now = now.In(s.loc)
+
+	for _, j := range s.jobs {
+		s.mu.Lock()
+		next, last := s.nextRun[j.Name], s.lastRun[j.Name]
+		s.mu.Unlock()
+
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+
+		if err := s.fire(ctx, j, now, last); err != nil {
+			slog.Error("cron: failed to enqueue job", "job", j.Name, "error", err)
+
+			continue
+		}
+
+		s.mu.Lock()
+		s.lastRun[j.Name] = now
+		s.nextRun[j.Name] = j.spec.next(now)
+		s.mu.Unlock()
+
+		if err := s.w.inbox.queries.UpsertCronRun(ctx, UpsertCronRunParams{
+			Name:    j.Name,
+			LastRun: now.UTC().Format(time.RFC3339),
+		}); err != nil {
+			slog.Warn("cron: failed to record run", "job", j.Name, "error", err)
+		}
+	}
+}
+
+// fire enqueues a run of j as a trigger labelled with the job name, so a
+// named trigger source of the same name applies to it.
+func (s *cronScheduler) fire(ctx context.Context, j CronJob, now, last time.Time) error {
+	if !last.IsZero() {
+		last = last.In(s.loc)
+	}
+
+	var prompt strings.Builder
+	if err := j.tmpl.Execute(&prompt, cronPromptData{Name: j.Name, Now: now, LastRun: last}); err != nil {
+		return fmt.Errorf("rendering prompt: %w", err)
+	}
+
+	slog.Info("cron: firing", "job", j.Name, "schedule", j.Schedule)
+
+	content := fmt.Sprintf("Scheduled job %q (%s):\n%s", j.Name, j.Schedule, prompt.String())
+
+	_, err := s.w.enqueueTrigger(ctx, Inbox{
+		Priority: priorityUnset,
+		Source:   sourceTrigger,
+		Label:    j.Name,
+		Content:  content,
+	})
+
+	return err
+}
+
+// summary lists each job with its last and next run for !status.
+func (s *cronScheduler) summary() string {
+	if s == nil || len(s.jobs) == 0 {
+		return "Scheduled jobs: none"
+	}
+
+	const layout = "Mon Jan 2 15:04"
+
+	var b strings.Builder
+
+	b.WriteString("Scheduled jobs:")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, j := range s.jobs {
+		last, next := "never", "never"
+		if t, ok := s.lastRun[j.Name]; ok {
+			last = t.In(s.loc).Format(layout)
+		}
+
+		if t := s.nextRun[j.Name]; !t.IsZero() {
+			next = t.In(s.loc).Format(layout)
+		}
+
+		fmt.Fprintf(&b, "\n  %s (%s) — last run: %s, next run: %s", j.Name, j.Schedule, last, next)
+	}
+
+	return b.String()
+}
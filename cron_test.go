@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestCronSpec_Next(t *testing.T) {
+	t.Parallel()
+
+	// Sunday 2025-06-15 14:07.
+	from := time.Date(2025, 6, 15, 14, 7, 30, 0, time.UTC)
+
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 6, 15, 14, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 6, 15, 14, 15, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2025, 6, 16, 9, 0, 0, 0, time.UTC)},
+		{"30 8-10 * * 1-5", time.Date(2025, 6, 16, 8, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2025, 6, 22, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either may match (the 20th is a Friday).
+		{"0 0 20 * 1", time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 20 * 5", time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+
+	for _, tc := range cases {
+		spec, err := parseCronSpec(tc.expr)
+		if err != nil {
+			t.Errorf("parseCronSpec(%q): %v", tc.expr, err)
+
+			continue
+		}
+
+		if got := spec.next(from); !got.Equal(tc.want) {
+			t.Errorf("next(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseCronSpec_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "x * * * *"} {
+		if _, err := parseCronSpec(expr); err == nil {
+			t.Errorf("parseCronSpec(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestLoadCronJobs_ReportsAllErrors(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cron.json")
+	must(t, os.WriteFile(path, []byte(`[
+		{"name": "ok", "schedule": "@daily", "prompt": "hi"},
+		{"name": "ok", "schedule": "@daily", "prompt": "dup"},
+		{"name": "bad-schedule", "schedule": "every monday", "prompt": "x"},
+		{"name": "bad-template", "schedule": "@daily", "prompt": "{{.Nope"}
+	]`), 0o600))
+
+	_, err := loadCronJobs(path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"duplicate name", "bad-schedule", "bad-template"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+}
+
+func TestCronScheduler_FiresAndRecordsRuns(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, _ := newTestApp(t)
+
+	path := filepath.Join(t.TempDir(), "cron.json")
+	must(t, os.WriteFile(path, []byte(`[{
+		"name": "weekly-commits",
+		"schedule": "0 9 * * 1",
+		"prompt": "Summarize commits since {{if .LastRun.IsZero}}last week{{else}}{{.LastRun.Format \"2006-01-02\"}}{{end}}."
+	}]`), 0o600))
+
+	jobs, err := loadCronJobs(path)
+	must(t, err)
+
+	app.worker.promptCfg.Location = time.UTC
+
+	// Sunday evening: nothing due yet.
+	start := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	s := newCronScheduler(ctx, app.worker, jobs, start)
+	s.tick(ctx, start)
+
+	if n, _ := app.inbox.Count(ctx); n != 0 {
+		t.Fatalf("inbox count = %d before the job is due, want 0", n)
+	}
+
+	monday := time.Date(2025, 6, 16, 9, 0, 0, 0, time.UTC)
+	s.tick(ctx, monday)
+
+	item, err := app.inbox.Dequeue(ctx)
+	must(t, err)
+
+	if item.Source != sourceTrigger || !strings.Contains(item.Content, `"weekly-commits"`) ||
+		!strings.Contains(item.Content, "since last week.") {
+		t.Errorf("enqueued item = %+v", item)
+	}
+
+	if sum := s.summary(); !strings.Contains(sum, "weekly-commits (0 9 * * 1)") || strings.Contains(sum, "last run: never") {
+		t.Errorf("summary = %q", sum)
+	}
+
+	// A restarted scheduler picks up the persisted last run: the next
+	// Monday was missed while "down", so it is caught up once.
+	later := time.Date(2025, 6, 25, 12, 0, 0, 0, time.UTC)
+	s = newCronScheduler(ctx, app.worker, jobs, later)
+	s.tick(ctx, later)
+	s.tick(ctx, later.Add(time.Minute))
+
+	item, err = app.inbox.Dequeue(ctx)
+	must(t, err)
+
+	if !strings.Contains(item.Content, "since 2025-06-16.") {
+		t.Errorf("caught-up item = %+v", item)
+	}
+
+	if n, _ := app.inbox.Count(ctx); n != 0 {
+		t.Errorf("inbox count = %d after catch-up, want 0 (one catch-up only)", n)
+	}
+}
+
+func TestCronScheduler_FiresLabelledTriggers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, _ := newTestApp(t)
+	app.worker.triggerCfg = TriggerConfig{Merge: true}
+
+	spec, err := parseCronSpec("@hourly")
+	must(t, err)
+
+	jobs := []CronJob{{Name: "backup-check", Schedule: "@hourly", Prompt: "Check backups.", spec: spec}}
+	jobs[0].tmpl, err = template.New("backup-check").Parse(jobs[0].Prompt)
+	must(t, err)
+
+	// An unlabeled pipe trigger queued first must not absorb the run.
+	_, err = app.worker.enqueueTrigger(ctx, Inbox{Priority: priorityUnset, Content: "disk full"})
+	must(t, err)
+
+	start := time.Date(2025, 6, 15, 9, 30, 0, 0, time.UTC)
+	s := newCronScheduler(ctx, app.worker, jobs, start)
+	s.tick(ctx, start.Add(30*time.Minute))
+
+	item, err := app.inbox.Dequeue(ctx)
+	must(t, err)
+
+	if merged := app.worker.mergeTriggerItems(ctx, item); strings.Contains(merged.Content, "Check backups.") {
+		t.Errorf("pipe trigger merged with the cron run: %q", merged.Content)
+	}
+
+	item, err = app.inbox.Dequeue(ctx)
+	must(t, err)
+
+	if item.Label != "backup-check" || !strings.Contains(item.Content, "Check backups.") {
+		t.Errorf("cron item = %+v, want the run labelled backup-check", item)
+	}
+}
+
+func TestCronScheduler_UsesConfiguredTimezone(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, _ := newTestApp(t)
+	app.worker.promptCfg.Location = time.FixedZone("UTC+2", 2*60*60)
+
+	spec, err := parseCronSpec("0 9 * * 1")
+	must(t, err)
+
+	jobs := []CronJob{{Name: "standup", Schedule: "0 9 * * 1", Prompt: "Standup.", spec: spec}}
+	jobs[0].tmpl, err = template.New("standup").Parse(jobs[0].Prompt)
+	must(t, err)
+
+	// 06:59 UTC on a Monday is 08:59 in the configured zone.
+	start := time.Date(2025, 6, 16, 6, 59, 0, 0, time.UTC)
+	s := newCronScheduler(ctx, app.worker, jobs, start)
+
+	if sum := s.summary(); !strings.Contains(sum, "next run: Mon Jun 16 09:00") {
+		t.Errorf("summary = %q, want the next run at 09:00 local", sum)
+	}
+
+	s.tick(ctx, start.Add(time.Minute))
+
+	if n, _ := app.inbox.Count(ctx); n != 1 {
+		t.Errorf("inbox count = %d at 09:00 local, want 1", n)
+	}
+}
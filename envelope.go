@@ -0,0 +1,126 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultPromptEnvelope gives the agent the local time and who is
+// speaking where. The age is only shown once a message has waited in the
+// queue long enough to matter.
+const defaultPromptEnvelope = `[{{.Now.Format "Mon 2006-01-02 15:04 MST"}}{{with .Timezone}} ({{.}}){{end}} · {{.Backend}}
+{{- with .Conversation}} · {{.}}{{end}}
+{{- with .Sender}} · from {{.}}{{end}}
+{{- if ge .Age.Minutes 1.0}} · sent {{duration .Age}} ago{{end}}]
+{{.Content}}`
+
+// promptEnvelopeOff as OPENCROW_PROMPT_ENVELOPE sends prompts unwrapped.
+const promptEnvelopeOff = "off"
+
+// PromptConfig wraps every user, trigger and heartbeat prompt in a
+// template before it is sent to pi.
+type PromptConfig struct {
+	Envelope string         // OPENCROW_PROMPT_ENVELOPE, default defaultPromptEnvelope; "off" disables it
+	Location *time.Location // OPENCROW_TIMEZONE, default the system zone
+	Backend  string         // OPENCROW_BACKEND, shown to the agent
+
+	tmpl *template.Template // nil leaves prompts unwrapped
+}
+
+// promptEnvelopeData is what the envelope template can reference.
+type promptEnvelopeData struct {
+	Content        string        // the prompt being wrapped
+	Source         string        // user, trigger or heartbeat
+	Label          string        // trigger label, "" otherwise
+	Now            time.Time     // in the configured timezone
+	Timezone       string        // IANA name, "" when using the system zone
+	Backend        string        // matrix, nostr, signal or socket
+	Sender         string        // display name, else user ID; "" for triggers
+	Conversation   string        // room or group name, else its ID
+	ConversationID string        // backend conversation ID
+	Sent           time.Time     // when the item was queued
+	Age            time.Duration // how long it waited
+}
+
+var envelopeFuncs = template.FuncMap{
+	// duration renders d compactly at minute precision: "5m", "2h5m".
+	"duration": func(d time.Duration) string {
+		s := strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+		if strings.HasSuffix(s, "h0m") {
+			s = strings.TrimSuffix(s, "0m")
+		}
+
+		return s
+	},
+}
+
+// load parses the envelope template, unless it is turned off.
+func (c *PromptConfig) load() error {
+	if c.Envelope == promptEnvelopeOff {
+		c.tmpl = nil
+
+		return nil
+	}
+
+	tmpl, err := template.New("envelope").Funcs(envelopeFuncs).Parse(c.Envelope)
+	if err != nil {
+		return fmt.Errorf("OPENCROW_PROMPT_ENVELOPE: %w", err)
+	}
+
+	c.tmpl = tmpl
+
+	return nil
+}
+
+func (c PromptConfig) now() time.Time {
+	return time.Now().In(c.location())
+}
+
+// location is the zone times are shown in to the agent.
+func (c PromptConfig) location() *time.Location {
+	return cmp.Or(c.Location, time.Local)
+}
+
+// wrapPrompt renders the envelope around prompt for item. Falls back to
+// the bare prompt if the template fails, so a bad template never loses
+// a message.
+func (w *Worker) wrapPrompt(item Inbox, prompt string) string {
+	cfg := w.promptCfg
+	if cfg.tmpl == nil {
+		return prompt
+	}
+
+	data := promptEnvelopeData{
+		Content:        prompt,
+		Source:         item.Source,
+		Label:          item.Label,
+		Now:            cfg.now(),
+		Backend:        cfg.Backend,
+		Sender:         item.Sender,
+		ConversationID: cmp.Or(item.ConversationID, w.resolveRoomID()),
+	}
+
+	data.Conversation = cmp.Or(item.ConversationName, data.ConversationID)
+
+	if loc := data.Now.Location(); loc != time.Local {
+		data.Timezone = loc.String()
+	}
+
+	if sent, err := time.Parse(inboxTimeLayout, item.CreatedAt); err == nil {
+		data.Sent = sent.In(data.Now.Location())
+		data.Age = max(data.Now.Sub(sent), 0)
+	}
+
+	var sb strings.Builder
+	if err := cfg.tmpl.Execute(&sb, data); err != nil {
+		slog.Warn("prompt envelope failed, sending bare prompt", "error", err)
+
+		return prompt
+	}
+
+	return sb.String()
+}
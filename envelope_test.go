@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPromptConfig(t *testing.T, envelope, tz string) PromptConfig {
+	t.Helper()
+
+	loc, err := time.LoadLocation(tz)
+	must(t, err)
+
+	cfg := PromptConfig{Envelope: envelope, Location: loc, Backend: "matrix"}
+	must(t, cfg.load())
+
+	return cfg
+}
+
+func TestWrapPrompt(t *testing.T) {
+	t.Parallel()
+
+	sent := time.Now().Add(-2*time.Hour - 5*time.Minute).UTC().Format(inboxTimeLayout)
+	item := Inbox{
+		Source:           sourceUser,
+		Sender:           "Alice",
+		ConversationID:   "!ops:example.org",
+		ConversationName: "Ops",
+		CreatedAt:        sent,
+	}
+
+	cases := []struct {
+		name     string
+		envelope string
+		item     Inbox
+		want     string
+	}{
+		{
+			"fields",
+			"{{.Source}}|{{.Backend}}|{{.Sender}}|{{.Conversation}}|{{.ConversationID}}|{{.Timezone}}|{{duration .Age}}|{{.Content}}",
+			item,
+			"user|matrix|Alice|Ops|!ops:example.org|Europe/Berlin|2h5m|hi",
+		},
+		{
+			"conversation falls back to ID",
+			"{{.Conversation}}",
+			Inbox{ConversationID: "+4911111"},
+			"+4911111",
+		},
+		{
+			"default hides fresh age",
+			defaultPromptEnvelope,
+			Inbox{Source: sourceUser, Sender: "Alice", CreatedAt: time.Now().UTC().Format(inboxTimeLayout)},
+			"(Europe/Berlin) · matrix · !room1 · from Alice]\nhi",
+		},
+		{
+			"default shows age",
+			defaultPromptEnvelope,
+			item,
+			" · matrix · Ops · from Alice · sent 2h5m ago]\nhi",
+		},
+		{
+			"execution error keeps bare prompt",
+			"{{.Missing}}",
+			item,
+			"hi",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := NewWorker(nil, PiConfig{SessionDir: t.TempDir()}, "", "")
+			w.roomID.Store(testRoom)
+			w.promptCfg = newTestPromptConfig(t, tc.envelope, "Europe/Berlin")
+
+			if got := w.wrapPrompt(tc.item, "hi"); !strings.HasSuffix(got, tc.want) {
+				t.Errorf("wrapPrompt = %q, want suffix %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWrapPrompt_Unconfigured(t *testing.T) {
+	t.Parallel()
+
+	w := NewWorker(nil, PiConfig{SessionDir: t.TempDir()}, "", "")
+
+	if got := w.wrapPrompt(Inbox{Sender: "Alice"}, "hi"); got != "hi" {
+		t.Errorf("wrapPrompt = %q, want bare prompt", got)
+	}
+}
+
+func TestWrapPrompt_EnvelopeOff(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := loadPromptConfig(envReader{testEnv(map[string]string{"OPENCROW_PROMPT_ENVELOPE": "off"})}, backendMatrix)
+	must(t, err)
+
+	w := NewWorker(nil, PiConfig{SessionDir: t.TempDir()}, "", "")
+	w.promptCfg = cfg
+
+	if got := w.wrapPrompt(Inbox{Sender: "Alice"}, "hi"); got != "hi" {
+		t.Errorf("wrapPrompt = %q, want bare prompt", got)
+	}
+}
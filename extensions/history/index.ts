@@ -0,0 +1,138 @@
+/**
+ * History Extension — full-text search over opencrow's chat log
+ *
+ * opencrow records every message it receives and sends in the `messages`
+ * table of opencrow.db, indexed by the `messages_fts` FTS5 table. This
+ * extension lets the LLM search it, so conversations that compaction has
+ * dropped from the context can still be looked up.
+ *
+ * Tools:
+ *   history_search(query, limit?) → rows — best-matching past messages
+ *
+ * Only the conversation of the running turn is searched: one pi process
+ * serves every room, so opencrow writes the turn's conversation ID to
+ * .turn_conversation in the session dir before each prompt and removes it
+ * afterwards. Without that file the search is refused.
+ *
+ * The extension only reads from SQLite; recording is owned by the
+ * opencrow process.
+ */
+
+import { readFileSync } from "node:fs";
+import type { ExtensionAPI } from "@oh-my-pi/pi-coding-agent";
+
+// Nix build substitutes the store path here. If the placeholder survives
+// (non-Nix install), fall back to PATH lookup.
+const SQLITE_BIN_RAW = "@@SQLITE_BIN@@";
+const SQLITE_BIN = SQLITE_BIN_RAW.startsWith("@@") ? "sqlite3" : SQLITE_BIN_RAW;
+
+const DB_PATH =
+  process.env.OPENCROW_SESSION_DIR
+    ? `${process.env.OPENCROW_SESSION_DIR}/opencrow.db`
+    : undefined;
+
+const TURN_CONVERSATION_PATH =
+  process.env.OPENCROW_SESSION_DIR
+    ? `${process.env.OPENCROW_SESSION_DIR}/.turn_conversation`
+    : undefined;
+
+const DEFAULT_LIMIT = 10;
+const MAX_LIMIT = 50;
+
+// SQLite single-quote escaping: double the quote. Input is always wrapped
+// in single quotes, so this is sufficient to prevent injection.
+function q(s: string): string {
+  return `'${s.replace(/'/g, "''")}'`;
+}
+
+// The conversation of the running turn, "" if opencrow hasn't written
+// one. Read on every call: the file is rewritten for every turn.
+function currentConversation(): string {
+  try {
+    return readFileSync(TURN_CONVERSATION_PATH!, "utf8").trim();
+  } catch {
+    return "";
+  }
+}
+
+// Same rules as ftsQuery in history.go: every word must match, each word
+// is quoted so punctuation is not parsed as FTS5 syntax, and a trailing *
+// is kept as a prefix match.
+function ftsQuery(query: string): string {
+  return query
+    .split(/\s+/)
+    .map((word) => {
+      const prefix = word.endsWith("*");
+      word = word.replace(/\*+$/, "");
+      if (!word) return "";
+      return `"${word.replace(/"/g, '""')}"` + (prefix ? "*" : "");
+    })
+    .filter(Boolean)
+    .join(" ");
+}
+
+export default function historyExtension(pi: ExtensionAPI) {
+  if (!DB_PATH) {
+    // OPENCROW_SESSION_DIR is exported by opencrow's StartPi; if it is
+    // missing we are running outside opencrow — silently skip.
+    return;
+  }
+
+  const { Type } = pi.typebox;
+
+  async function sqlite(sql: string, signal?: AbortSignal): Promise<string> {
+    const result = await pi.exec(
+      SQLITE_BIN,
+      // .timeout mirrors the Go side's busy_timeout(5000), see the
+      // reminders extension.
+      ["-batch", "-noheader", "-cmd", ".timeout 5000", DB_PATH, sql],
+      { signal, timeout: 5000 },
+    );
+    if (result.code !== 0) {
+      throw new Error(`sqlite3 failed: ${result.stderr || result.stdout}`);
+    }
+    return result.stdout.trim();
+  }
+
+  pi.registerTool({
+    name: "history_search",
+    label: "Search chat history",
+    description:
+      "Full-text search over all past messages of this chat, including ones no " +
+      "longer in your context. Every word must match; end a word with * " +
+      "for a prefix match. Returns time, direction (in = from the user, " +
+      "out = from you), sender and text, best match first.",
+    parameters: Type.Object({
+      query: Type.String({ description: "Words to search for, e.g. 'dentist appoint*'" }),
+      limit: Type.Optional(
+        Type.Integer({
+          description: `Maximum number of results (default ${DEFAULT_LIMIT}, max ${MAX_LIMIT}).`,
+        }),
+      ),
+    }),
+    async execute(_id, params, signal) {
+      const match = ftsQuery(params.query);
+      if (!match) {
+        throw new Error("query is empty");
+      }
+      const conversation = currentConversation();
+      if (!conversation) {
+        throw new Error("no conversation known for this turn; refusing to search");
+      }
+      const limit = Math.min(Math.max(params.limit ?? DEFAULT_LIMIT, 1), MAX_LIMIT);
+      const out = await sqlite(
+        `SELECT m.created_at || '  ' || m.direction || '  ' || ` +
+          `iif(m.sender = '', '', m.sender || ': ') || replace(m.text, char(10), ' ') || ` +
+          `iif(m.attachments = '', '', '  [files: ' || replace(m.attachments, char(10), ', ') || ']') ` +
+          `FROM messages_fts JOIN messages m ON m.id = messages_fts.rowid ` +
+          `WHERE messages_fts MATCH ${q(match)} AND m.conversation_id = ${q(conversation)} ` +
+          `ORDER BY rank LIMIT ${limit};`,
+        signal,
+      );
+      return {
+        content: [{ type: "text", text: out || `No messages found for '${params.query}'.` }],
+        details: {},
+      };
+    },
+  });
+}
@@ -1,17 +1,22 @@
 /**
  * Reminders Extension — one-shot scheduled prompts for opencrow
  *
- * Gives the LLM structured tools to manage rows in the `reminders` table
- * of opencrow.db. The Go-side scheduler polls that table every minute and
- * delivers due reminders as trigger messages, deleting them atomically.
+ * Gives the LLM structured tools to manage rows in the `reminders` and
+ * `scheduled_messages` tables of opencrow.db. The Go-side scheduler polls
+ * both every minute: due reminders are delivered back to the agent as
+ * trigger messages, due scheduled messages are posted to the chat as-is.
+ * Fired rows are deleted atomically.
  *
  * Tools:
- *   remind_at(when, prompt) → id   — schedule a one-shot reminder
+ *   remind_at(when, prompt, important?) → id — schedule a one-shot reminder
  *   remind_list()           → rows — list pending reminders
  *   remind_cancel(id)              — delete a reminder
+ *   schedule_message(when, text, files?) → id — post a message verbatim later
+ *   scheduled_list()                      → rows — list pending messages
+ *   scheduled_cancel(id)                          — delete a scheduled message
  *
- * The extension only writes to SQLite; all scheduling, delivery and
- * cleanup is owned by the opencrow process.
+ * The extension only writes to SQLite; all scheduling, delivery, snoozing
+ * and cleanup is owned by the opencrow process.
  */
 
 import type { ExtensionAPI } from "@oh-my-pi/pi-coding-agent";
@@ -109,7 +114,9 @@ export default function remindersExtension(pi: ExtensionAPI) {
     label: "Set reminder",
     description:
       "Schedule a one-shot reminder. The prompt is delivered back as a " +
-      "trigger message at the given time (±1 min), then auto-deleted.",
+      "trigger message at the given time (±1 min), then auto-deleted. " +
+      "The user can reply to the delivered message with \"done\" or " +
+      "\"snooze 1h\" / \"tomorrow 9\"; opencrow handles those itself.",
     parameters: Type.Object({
       when: Type.String({
         description:
@@ -119,12 +126,21 @@ export default function remindersExtension(pi: ExtensionAPI) {
       prompt: Type.String({
         description: "Message to deliver when the reminder fires.",
       }),
+      important: Type.Optional(
+        Type.Boolean({
+          description:
+            "Re-fire the reminder until the user acknowledges it " +
+            "(only if the operator enabled re-firing).",
+        }),
+      ),
     }),
     async execute(_id, params, signal) {
       const at = normalizeWhen(params.when);
       const delta = Date.parse(at) - Date.now();
+      const important = params.important ? 1 : 0;
       const out = await sqlite(
-        `INSERT INTO reminders (fire_at, prompt) VALUES (${q(at)}, ${q(params.prompt)}); ` +
+        `INSERT INTO reminders (fire_at, prompt, important) ` +
+          `VALUES (${q(at)}, ${q(params.prompt)}, ${important}); ` +
           `SELECT last_insert_rowid();`,
         signal,
       );
@@ -135,7 +151,7 @@ export default function remindersExtension(pi: ExtensionAPI) {
             text: `Reminder #${out} set for ${at} — in ${humanizeDelta(delta)}`,
           },
         ],
-        details: { id: Number(out), fire_at: at },
+        details: { id: Number(out), fire_at: at, important: important === 1 },
       };
     },
   });
@@ -143,11 +159,12 @@ export default function remindersExtension(pi: ExtensionAPI) {
   pi.registerTool({
     name: "remind_list",
     label: "List reminders",
-    description: "List pending reminders (id, fire_at, prompt).",
+    description: "List pending reminders (id, fire_at, prompt; ! marks important).",
     parameters: Type.Object({}),
     async execute(_id, _params, signal) {
       const out = await sqlite(
-        `SELECT id || '  ' || fire_at || '  ' || prompt FROM reminders ORDER BY fire_at;`,
+        `SELECT id || '  ' || fire_at || '  ' || iif(important, '! ', '') || prompt ` +
+          `FROM reminders ORDER BY fire_at;`,
         signal,
       );
       return {
@@ -181,4 +198,99 @@ export default function remindersExtension(pi: ExtensionAPI) {
       };
     },
   });
+
+  pi.registerTool({
+    name: "schedule_message",
+    label: "Schedule message",
+    description:
+      "Post a fixed message (and optionally files) to the chat at the given " +
+      "time (±1 min). Unlike remind_at, the text is sent verbatim and you " +
+      "are not consulted again — use it for stand-up pings or drafted messages.",
+    parameters: Type.Object({
+      when: Type.String({
+        description:
+          "Future ISO 8601 timestamp with explicit timezone, " +
+          "e.g. 2025-06-15T09:00:00+02:00",
+      }),
+      text: Type.String({ description: "Exact message text to post." }),
+      files: Type.Optional(
+        Type.Array(Type.String(), {
+          description: "Absolute paths of files to attach.",
+        }),
+      ),
+    }),
+    async execute(_id, params, signal) {
+      const at = normalizeWhen(params.when);
+      const delta = Date.parse(at) - Date.now();
+      const files = (params.files ?? []).map((f) => f.trim()).filter(Boolean);
+      if (files.some((f) => !f.startsWith("/") || f.includes("\n"))) {
+        throw new Error("files must be absolute paths");
+      }
+      if (!params.text.trim() && files.length === 0) {
+        throw new Error("nothing to send — give text or files");
+      }
+      const out = await sqlite(
+        `INSERT INTO scheduled_messages (send_at, text, files) ` +
+          `VALUES (${q(at)}, ${q(params.text)}, ${q(files.join("\n"))}); ` +
+          `SELECT last_insert_rowid();`,
+        signal,
+      );
+      return {
+        content: [
+          {
+            type: "text",
+            text: `Message #${out} scheduled for ${at} — in ${humanizeDelta(delta)}`,
+          },
+        ],
+        details: { id: Number(out), send_at: at },
+      };
+    },
+  });
+
+  pi.registerTool({
+    name: "scheduled_list",
+    label: "List scheduled messages",
+    description: "List pending scheduled messages (id, send_at, text).",
+    parameters: Type.Object({}),
+    async execute(_id, _params, signal) {
+      const out = await sqlite(
+        `SELECT id || '  ' || send_at || '  ' || text || ` +
+          `iif(files = '', '', '  [+' || (length(files) - length(replace(files, char(10), '')) + 1) || ' file(s)]') ` +
+          `FROM scheduled_messages ORDER BY send_at;`,
+        signal,
+      );
+      return {
+        content: [{ type: "text", text: out || "No messages scheduled." }],
+        details: {},
+      };
+    },
+  });
+
+  pi.registerTool({
+    name: "scheduled_cancel",
+    label: "Cancel scheduled message",
+    description: "Delete a pending scheduled message by id.",
+    parameters: Type.Object({
+      id: Type.Integer({ description: "Scheduled message id to cancel" }),
+    }),
+    async execute(_id, params, signal) {
+      const out = await sqlite(
+        `DELETE FROM scheduled_messages WHERE id = ${params.id}; SELECT changes();`,
+        signal,
+      );
+      const n = Number(out);
+      return {
+        content: [
+          {
+            type: "text",
+            text:
+              n > 0
+                ? `Scheduled message #${params.id} cancelled.`
+                : `No scheduled message with id ${params.id}.`,
+          },
+        ],
+        details: { deleted: n },
+      };
+    },
+  });
 }
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFeedInterval = 15 * time.Minute
+	feedFetchTimeout    = 30 * time.Second
+	maxFeedBody         = 10 << 20
+	// feedEntryRetention is how long an entry that left its feed is
+	// remembered, in case it reappears.
+	feedEntryRetention = 30 * 24 * time.Hour
+	// maxFeedSummary bounds each entry's summary in the prompt.
+	maxFeedSummary = 300
+)
+
+// FeedsConfig enables the RSS/Atom poller.
+type FeedsConfig struct {
+	Interval time.Duration // OPENCROW_FEED_INTERVAL, default 15m
+	Feeds    []Feed        // OPENCROW_FEEDS_FILE (JSON list), default none
+}
+
+// Feed is one entry of OPENCROW_FEEDS_FILE.
+type Feed struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Filter is a regular expression; only new entries whose title or
+	// summary match it are reported. Empty reports every new entry.
+	Filter string `json:"filter"`
+
+	filter *regexp.Regexp
+}
+
+// feedEntry is an RSS item or Atom entry reduced to what the prompt needs.
+type feedEntry struct {
+	ID      string
+	Title   string
+	Link    string
+	Summary string
+}
+
+// loadFeeds reads and validates the JSON feed list at path. All errors
+// are reported at once.
+func loadFeeds(path string) ([]Feed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OPENCROW_FEEDS_FILE: %w", err)
+	}
+
+	var feeds []Feed
+	if err := json.Unmarshal(data, &feeds); err != nil {
+		return nil, fmt.Errorf("parsing OPENCROW_FEEDS_FILE: %w", err)
+	}
+
+	seen := make(map[string]bool, len(feeds))
+
+	var errs error
+
+	for i := range feeds {
+		f := &feeds[i]
+
+		if f.Name == "" {
+			errs = errors.Join(errs, fmt.Errorf("feed #%d: name is required", i+1))
+
+			continue
+		}
+
+		if seen[f.Name] {
+			errs = errors.Join(errs, fmt.Errorf("feed %q: duplicate name", f.Name))
+		}
+
+		seen[f.Name] = true
+
+		if !strings.HasPrefix(f.URL, "http://") && !strings.HasPrefix(f.URL, "https://") {
+			errs = errors.Join(errs, fmt.Errorf("feed %q: url must be http(s)", f.Name))
+		}
+
+		if f.Filter != "" {
+			if f.filter, err = regexp.Compile(f.Filter); err != nil {
+				errs = errors.Join(errs, fmt.Errorf("feed %q: filter: %w", f.Name, err))
+			}
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return feeds, nil
+}
+
+func (f *Feed) matches(e feedEntry) bool {
+	return f.filter == nil || f.filter.MatchString(e.Title) || f.filter.MatchString(e.Summary)
+}
+
+// feedDoc decodes RSS 2.0, RSS 1.0 (RDF) and Atom alike: encoding/xml
+// matches elements by local name, and each format fills different fields.
+type feedDoc struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"` // RSS 1.0 puts items next to the channel
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+}
+
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary string `xml:"summary"`
+	Content string `xml:"content"`
+}
+
+// parseFeed extracts the entries of an RSS or Atom document. Entries
+// without an ID fall back to their link, then to a hash of title and
+// summary, so every entry can be recognized on the next poll.
+func parseFeed(r io.Reader) ([]feedEntry, error) {
+	var doc feedDoc
+
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding feed: %w", err)
+	}
+
+	var entries []feedEntry
+
+	for _, it := range append(doc.Channel.Items, doc.Items...) {
+		entries = append(entries, feedEntry{ID: it.GUID, Title: it.Title, Link: it.Link, Summary: it.Description})
+	}
+
+	for _, e := range doc.Entries {
+		entry := feedEntry{ID: e.ID, Title: e.Title, Summary: cmp.Or(e.Summary, e.Content)}
+
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				entry.Link = l.Href
+
+				break
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		e.Title = strings.TrimSpace(e.Title)
+		e.Link = strings.TrimSpace(e.Link)
+		e.Summary = plainText(e.Summary)
+
+		e.ID = cmp.Or(strings.TrimSpace(e.ID), e.Link)
+		if e.ID == "" {
+			sum := sha256.Sum256([]byte(e.Title + "\x00" + e.Summary))
+			e.ID = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	}
+
+	return entries, nil
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// plainText reduces an HTML summary to collapsed, unescaped text.
+func plainText(s string) string {
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, " "))
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// feedPoller fetches the configured feeds and enqueues one trigger per
+// poll listing all new entries.
+type feedPoller struct {
+	w      *Worker
+	feeds  []Feed
+	client *http.Client
+}
+
+func newFeedPoller(w *Worker, feeds []Feed) *feedPoller {
+	return &feedPoller{w: w, feeds: feeds, client: &http.Client{Timeout: feedFetchTimeout}}
+}
+
+// startFeedPoller polls every interval until ctx is done, starting now.
+func startFeedPoller(ctx context.Context, p *feedPoller, interval time.Duration) {
+	slog.Info("feed poller started", "feeds", len(p.feeds), "interval", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		p.poll(ctx, time.Now())
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				p.poll(ctx, now)
+			}
+		}
+	}()
+}
+
+// poll fetches every feed and enqueues new, matching entries as a single
+// trigger. A feed without stored entries (first poll) is only recorded,
+// so adding a feed doesn't report its whole backlog. Reported entries are
+// recorded once the trigger is queued, so a failed enqueue reports them
+// again on the next poll.
+func (p *feedPoller) poll(ctx context.Context, now time.Time) {
+	var sb strings.Builder
+
+	count := 0
+	reported := make(map[string][]feedEntry) // by feed name
+
+	for i := range p.feeds {
+		f := &p.feeds[i]
+
+		fresh, err := p.newEntries(ctx, f, now)
+		if err != nil {
+			slog.Warn("feeds: poll failed", "feed", f.Name, "error", err)
+
+			continue
+		}
+
+		if len(fresh) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&sb, "\n## %s\n", f.Name)
+
+		for _, e := range fresh {
+			writeFeedEntry(&sb, e)
+		}
+
+		count += len(fresh)
+		reported[f.Name] = fresh
+	}
+
+	cutoff := now.UTC().Add(-feedEntryRetention).Format(time.RFC3339)
+	if err := p.w.inbox.queries.PruneFeedEntries(ctx, cutoff); err != nil {
+		slog.Warn("feeds: failed to prune entries", "error", err)
+	}
+
+	if count == 0 {
+		return
+	}
+
+	item := Inbox{
+		Priority: priorityUnset,
+		Label:    "feeds",
+		Content:  fmt.Sprintf("New feed entries (%d):\n%s", count, sb.String()),
+	}
+
+	if _, err := p.w.enqueueTrigger(ctx, item); err != nil {
+		slog.Error("feeds: failed to enqueue", "error", err)
+
+		return
+	}
+
+	for feed, entries := range reported {
+		for _, e := range entries {
+			if err := p.markSeen(ctx, feed, e.ID, now); err != nil {
+				slog.Warn("feeds: failed to record entry", "feed", feed, "error", err)
+			}
+		}
+	}
+
+	slog.Info("feeds: new entries", "count", count)
+}
+
+// newEntries fetches f and returns the entries not seen before that pass
+// its filter. Every other entry is recorded as seen; the returned ones are
+// left to the caller.
+func (p *feedPoller) newEntries(ctx context.Context, f *Feed, now time.Time) ([]feedEntry, error) {
+	entries, err := p.fetch(ctx, f.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := p.w.inbox.queries.ListFeedEntries(ctx, f.Name)
+	if err != nil {
+		return nil, fmt.Errorf("loading seen entries: %w", err)
+	}
+
+	seen := make(map[string]bool, len(known))
+	for _, id := range known {
+		seen[id] = true
+	}
+
+	seed := len(known) == 0
+
+	var fresh []feedEntry
+
+	for _, e := range entries {
+		if !seed && !seen[e.ID] && f.matches(e) {
+			// Entries repeated within one fetch are reported once.
+			seen[e.ID] = true
+			fresh = append(fresh, e)
+
+			continue
+		}
+
+		seen[e.ID] = true
+
+		if err := p.markSeen(ctx, f.Name, e.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return fresh, nil
+}
+
+// markSeen records entry id of feed, refreshing its retention.
+func (p *feedPoller) markSeen(ctx context.Context, feed, id string, now time.Time) error {
+	if err := p.w.inbox.queries.UpsertFeedEntry(ctx, UpsertFeedEntryParams{
+		Feed:   feed,
+		Guid:   id,
+		SeenAt: now.UTC().Format(time.RFC3339),
+	}); err != nil {
+		return fmt.Errorf("recording entry: %w", err)
+	}
+
+	return nil
+}
+
+func (p *feedPoller) fetch(ctx context.Context, url string) ([]feedEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "opencrow/"+version)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching feed: %s", resp.Status)
+	}
+
+	return parseFeed(io.LimitReader(resp.Body, maxFeedBody))
+}
+
+func writeFeedEntry(sb *strings.Builder, e feedEntry) {
+	sb.WriteString("- " + cmp.Or(e.Title, "(untitled)"))
+
+	if e.Link != "" {
+		sb.WriteString("\n  " + e.Link)
+	}
+
+	if e.Summary != "" {
+		summary := e.Summary
+		if r := []rune(summary); len(r) > maxFeedSummary {
+			summary = string(r[:maxFeedSummary]) + "…"
+		}
+
+		sb.WriteString("\n  " + summary)
+	}
+
+	sb.WriteByte('\n')
+}
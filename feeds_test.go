@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Releases</title>
+%s
+</channel></rss>`
+
+func testRSSItem(guid, title string) string {
+	return "<item><guid>" + guid + "</guid><title>" + title + "</title><link>https://example.com/" + guid +
+		"</link><description>&lt;p&gt;Notes for " + title + "&lt;/p&gt;</description></item>"
+}
+
+func TestParseFeed_Atom(t *testing.T) {
+	t.Parallel()
+
+	entries, err := parseFeed(strings.NewReader(`<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <id>tag:example.com,2025:1</id>
+    <title>First</title>
+    <link rel="self" href="https://example.com/self"/>
+    <link href="https://example.com/first"/>
+    <content type="html">&lt;b&gt;bold&lt;/b&gt; &amp;amp; text</content>
+  </entry>
+  <entry><title>No id</title><link href="https://example.com/second"/></entry>
+</feed>`))
+	must(t, err)
+
+	want := []feedEntry{
+		{ID: "tag:example.com,2025:1", Title: "First", Link: "https://example.com/first", Summary: "bold & text"},
+		{ID: "https://example.com/second", Title: "No id", Link: "https://example.com/second"},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestFeedPoller_EnqueuesOnlyNewEntries(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	var items atomic.Value
+	items.Store(testRSSItem("v1", "v1.0 released"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = rw.Write([]byte(strings.Replace(testRSS, "%s", items.Load().(string), 1)))
+	}))
+	t.Cleanup(srv.Close)
+
+	path := writeTestFile(t, "feeds.json", `[{"name": "releases", "url": "`+srv.URL+`", "filter": "released"}]`)
+	feeds, err := loadFeeds(path)
+	must(t, err)
+
+	inbox := newTestInbox(ctx, t)
+	p := newFeedPoller(NewWorker(inbox, PiConfig{}, "", ""), feeds)
+	now := time.Now()
+
+	// First poll only records the existing backlog.
+	p.poll(ctx, now)
+
+	if n, _ := inbox.Count(ctx); n != 0 {
+		t.Fatalf("inbox has %d items after first poll, want 0", n)
+	}
+
+	items.Store(testRSSItem("v1", "v1.0 released") + testRSSItem("v2", "v2.0 released") + testRSSItem("blog", "Team offsite"))
+	p.poll(ctx, now.Add(time.Minute))
+	p.poll(ctx, now.Add(2*time.Minute))
+
+	if n, _ := inbox.Count(ctx); n != 1 {
+		t.Fatalf("inbox has %d items, want 1", n)
+	}
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	for _, want := range []string{"New feed entries (1)", "## releases", "- v2.0 released\n  https://example.com/v2\n  Notes for v2.0 released"} {
+		if !strings.Contains(item.Content, want) {
+			t.Errorf("content %q missing %q", item.Content, want)
+		}
+	}
+
+	if strings.Contains(item.Content, "v1.0") || strings.Contains(item.Content, "offsite") {
+		t.Errorf("content %q reports a seen or filtered entry", item.Content)
+	}
+}
+
+func TestFeedPoller_FailedEnqueueReportsAgain(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	var items atomic.Value
+	items.Store(testRSSItem("v1", "v1.0 released"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write([]byte(strings.Replace(testRSS, "%s", items.Load().(string), 1)))
+	}))
+	t.Cleanup(srv.Close)
+
+	db := newTestDB(ctx, t)
+	inbox := newTestInboxWithDB(ctx, t, db)
+	p := newFeedPoller(NewWorker(inbox, PiConfig{}, "", ""), []Feed{{Name: "releases", URL: srv.URL}})
+	now := time.Now()
+
+	p.poll(ctx, now)
+	items.Store(testRSSItem("v1", "v1.0 released") + testRSSItem("v2", "v2.0 released"))
+
+	// Enqueueing fails while the inbox table is gone.
+	_, err := db.ExecContext(ctx, "DROP TABLE inbox")
+	must(t, err)
+	p.poll(ctx, now.Add(time.Minute))
+
+	_, err = db.ExecContext(ctx, dbSchema)
+	must(t, err)
+	p.poll(ctx, now.Add(2*time.Minute))
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	if !strings.Contains(item.Content, "v2.0 released") {
+		t.Errorf("content %q, want v2 reported after the failed enqueue", item.Content)
+	}
+}
+
+func TestLoadFeeds_ReportsAllErrors(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestFile(t, "feeds.json", `[
+		{"name": "ok", "url": "https://example.com/feed"},
+		{"name": "ok", "url": "https://example.com/feed"},
+		{"name": "bad-url", "url": "file:///etc/passwd"},
+		{"name": "bad-filter", "url": "https://example.com/feed", "filter": "(unclosed"}
+	]`)
+
+	_, err := loadFeeds(path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"duplicate name", "bad-url", "bad-filter"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+}
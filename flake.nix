@@ -30,6 +30,7 @@
         {
           opencrow = pkgs.callPackage ./nix/package.nix { };
           extension-memory = pkgs.callPackage ./nix/extension-memory.nix { };
+          extension-history = pkgs.callPackage ./nix/extension-history.nix { };
           extension-reminders = pkgs.callPackage ./nix/extension-reminders.nix { };
           sediment = pkgs.callPackage ./nix/sediment { };
           default = self.packages.${system}.opencrow;
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+const defaultWatchDebounce = 2 * time.Second
+
+// WatchRule is one entry of OPENCROW_WATCH_FILE: a directory whose new or
+// modified files are reported to the agent as a trigger. The rule name is
+// the trigger's source label, so a named trigger source can shape the
+// prompt.
+type WatchRule struct {
+	Name string `json:"name"`
+	// Path is the directory to watch; a leading "~/" is expanded.
+	Path string `json:"path"`
+	// Patterns are filepath.Match globs against file names; empty
+	// matches every file.
+	Patterns []string `json:"patterns"`
+	// Debounce is how long the directory must be quiet before changed
+	// files are reported together (Go duration, default 2s).
+	Debounce string `json:"debounce"`
+
+	debounce time.Duration
+}
+
+// loadWatchRules reads and validates the JSON rule list at path. All
+// errors are reported at once.
+func loadWatchRules(path string) ([]WatchRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OPENCROW_WATCH_FILE: %w", err)
+	}
+
+	var rules []WatchRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parsing OPENCROW_WATCH_FILE: %w", err)
+	}
+
+	seen := make(map[string]bool, len(rules))
+
+	var errs error
+
+	for i := range rules {
+		if err := rules[i].load(); err != nil {
+			errs = errors.Join(errs, err)
+
+			continue
+		}
+
+		if seen[rules[i].Name] {
+			errs = errors.Join(errs, fmt.Errorf("watch rule %q: duplicate name", rules[i].Name))
+		}
+
+		seen[rules[i].Name] = true
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return rules, nil
+}
+
+func (r *WatchRule) load() error {
+	if r.Name == "" {
+		return errors.New("watch rule: name is required")
+	}
+
+	if rest, ok := strings.CutPrefix(r.Path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("watch rule %q: expanding ~: %w", r.Name, err)
+		}
+
+		r.Path = filepath.Join(home, rest)
+	}
+
+	if !filepath.IsAbs(r.Path) {
+		return fmt.Errorf("watch rule %q: path must be absolute or start with ~/", r.Name)
+	}
+
+	r.Path = filepath.Clean(r.Path)
+
+	for _, p := range r.Patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("watch rule %q: pattern %q: %w", r.Name, p, err)
+		}
+	}
+
+	r.debounce = defaultWatchDebounce
+
+	if r.Debounce != "" {
+		d, err := time.ParseDuration(r.Debounce)
+		if err != nil || d < 0 {
+			return fmt.Errorf("watch rule %q: invalid debounce %q", r.Name, r.Debounce)
+		}
+
+		r.debounce = d
+	}
+
+	return nil
+}
+
+func (r *WatchRule) matches(name string) bool {
+	if len(r.Patterns) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(r.Patterns, func(p string) bool {
+		ok, _ := filepath.Match(p, name)
+
+		return ok
+	})
+}
+
+// watchedFile is the last reported state of a file.
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+}
+
+// fileWatcher turns directory events into debounced triggers. Each rule
+// keeps the state of the files it has reported, persisted in
+// watched_files, so events are only reported for real changes.
+type fileWatcher struct {
+	w     *Worker
+	rules []WatchRule
+
+	mu      sync.Mutex
+	state   []map[string]watchedFile // per rule, by path
+	pending []map[string]struct{}    // per rule, paths awaiting the debounce
+	timers  []*time.Timer
+	loaded  []bool // per rule, persisted state was read
+	seeded  []bool // per rule, the directory was seeded (see watch_rules)
+}
+
+// newFileWatcher loads persisted state; scanAll then queues what changed
+// while opencrow was down. A rule whose state can't be loaded is left
+// alone until loading it succeeds, so a database error doesn't turn into
+// lost or replayed files.
+func newFileWatcher(ctx context.Context, w *Worker, rules []WatchRule) *fileWatcher {
+	fw := &fileWatcher{
+		w:       w,
+		rules:   rules,
+		state:   make([]map[string]watchedFile, len(rules)),
+		pending: make([]map[string]struct{}, len(rules)),
+		timers:  make([]*time.Timer, len(rules)),
+		loaded:  make([]bool, len(rules)),
+		seeded:  make([]bool, len(rules)),
+	}
+
+	for i := range rules {
+		fw.state[i] = make(map[string]watchedFile)
+		fw.pending[i] = make(map[string]struct{})
+
+		if err := fw.load(ctx, i); err != nil {
+			slog.Error("watch: failed to load state, retrying on the next event", "rule", rules[i].Name, "error", err)
+		}
+	}
+
+	return fw
+}
+
+// scanAll rescans every rule's directory.
+func (fw *fileWatcher) scanAll(ctx context.Context) {
+	for i := range fw.rules {
+		fw.rescan(ctx, i)
+	}
+}
+
+// load reads rule i's persisted state. Rules persisted before
+// watch_rules existed count as seeded if they have recorded files.
+func (fw *fileWatcher) load(ctx context.Context, i int) error {
+	name := fw.rules[i].Name
+
+	seeded, err := fw.w.inbox.queries.WatchRuleSeeded(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	rows, err := fw.w.inbox.queries.ListWatchedFiles(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	for _, row := range rows {
+		modTime, _ := time.Parse(time.RFC3339Nano, row.ModTime)
+		fw.state[i][row.Path] = watchedFile{modTime: modTime, size: row.Size}
+	}
+
+	fw.loaded[i] = true
+	fw.seeded[i] = seeded > 0 || len(rows) > 0
+
+	return nil
+}
+
+// rescan checks rule i's directory for changes, seeding it instead if it
+// was never seeded. The first scan of a rule records the directory's
+// current files without reporting them, so enabling a watch doesn't dump
+// an existing directory on the agent. The rule is marked seeded only once
+// its directory could be read, so a directory that appears later is
+// seeded then. State that failed to load is loaded first.
+func (fw *fileWatcher) rescan(ctx context.Context, i int) {
+	fw.mu.Lock()
+	loaded := fw.loaded[i]
+	fw.mu.Unlock()
+
+	if !loaded {
+		if err := fw.load(ctx, i); err != nil {
+			slog.Error("watch: failed to load state, not scanning", "rule", fw.rules[i].Name, "error", err)
+
+			return
+		}
+	}
+
+	fw.mu.Lock()
+	seeded := fw.seeded[i]
+	fw.mu.Unlock()
+
+	if !fw.scan(ctx, i, !seeded) || seeded {
+		return
+	}
+
+	fw.mu.Lock()
+	fw.seeded[i] = true
+	fw.mu.Unlock()
+
+	if err := fw.w.inbox.queries.MarkWatchRuleSeeded(ctx, MarkWatchRuleSeededParams{
+		Rule:     fw.rules[i].Name,
+		SeededAt: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		slog.Warn("watch: failed to persist seeding", "rule", fw.rules[i].Name, "error", err)
+	}
+}
+
+// scan checks every matching file in rule i's directory. With seed set,
+// current files are recorded instead of reported. It reports whether the
+// directory could be read.
+func (fw *fileWatcher) scan(ctx context.Context, i int, seed bool) bool {
+	r := &fw.rules[i]
+
+	entries, err := os.ReadDir(r.Path)
+	if err != nil {
+		slog.Warn("watch: failed to read directory", "rule", r.Name, "path", r.Path, "error", err)
+
+		return false
+	}
+
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !r.matches(e.Name()) {
+			continue
+		}
+
+		path := filepath.Join(r.Path, e.Name())
+
+		if seed {
+			if st, ok := statWatched(path); ok {
+				fw.record(ctx, i, path, st)
+			}
+
+			continue
+		}
+
+		fw.changed(ctx, i, path)
+	}
+
+	return true
+}
+
+// changed notes an event for path under rule i and (re)starts the
+// rule's debounce timer. Without loaded state every file would look new,
+// so for such a rule the directory is rescanned instead, which loads the
+// state first.
+func (fw *fileWatcher) changed(ctx context.Context, i int, path string) {
+	fw.mu.Lock()
+
+	if !fw.loaded[i] {
+		fw.mu.Unlock()
+		fw.rescan(ctx, i)
+
+		return
+	}
+
+	fw.pending[i][path] = struct{}{}
+
+	if fw.timers[i] != nil {
+		fw.timers[i].Stop()
+	}
+
+	fw.timers[i] = time.AfterFunc(fw.rules[i].debounce, func() { fw.flush(ctx, i) })
+	fw.mu.Unlock()
+}
+
+// flush reports rule i's pending files that really changed as a single
+// trigger. Their state is recorded only once the trigger is queued; if
+// that fails they stay pending for the rule's next flush.
+func (fw *fileWatcher) flush(ctx context.Context, i int) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	fw.mu.Lock()
+	paths := make([]string, 0, len(fw.pending[i]))
+
+	for p := range fw.pending[i] {
+		paths = append(paths, p)
+	}
+
+	clear(fw.pending[i])
+	fw.mu.Unlock()
+
+	slices.Sort(paths)
+
+	var (
+		lines   []string
+		changed = make(map[string]watchedFile)
+	)
+
+	for _, path := range paths {
+		st, ok := statWatched(path)
+		if !ok {
+			continue
+		}
+
+		fw.mu.Lock()
+		prev, known := fw.state[i][path]
+		fw.mu.Unlock()
+
+		if known && prev.modTime.Equal(st.modTime) && prev.size == st.size {
+			continue
+		}
+
+		verb := "Created"
+		if known {
+			verb = "Modified"
+		}
+
+		lines = append(lines, backend.AttachmentText(verb+" "+filepath.Base(path), path))
+		changed[path] = st
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+
+	r := &fw.rules[i]
+	item := Inbox{
+		Priority: priorityUnset,
+		Label:    r.Name,
+		Content:  fmt.Sprintf("Files changed in %s (watch %q):\n%s", r.Path, r.Name, strings.Join(lines, "\n")),
+	}
+
+	if _, err := fw.w.enqueueTrigger(ctx, item); err != nil {
+		slog.Error("watch: failed to enqueue", "rule", r.Name, "error", err)
+
+		fw.mu.Lock()
+		for path := range changed {
+			fw.pending[i][path] = struct{}{}
+		}
+		fw.mu.Unlock()
+
+		return
+	}
+
+	for path, st := range changed {
+		fw.record(ctx, i, path, st)
+	}
+
+	slog.Info("watch: files changed", "rule", r.Name, "count", len(lines))
+}
+
+func (fw *fileWatcher) record(ctx context.Context, i int, path string, st watchedFile) {
+	fw.mu.Lock()
+	fw.state[i][path] = st
+	fw.mu.Unlock()
+
+	if err := fw.w.inbox.queries.UpsertWatchedFile(ctx, UpsertWatchedFileParams{
+		Rule:    fw.rules[i].Name,
+		Path:    path,
+		ModTime: st.modTime.UTC().Format(time.RFC3339Nano),
+		Size:    st.size,
+	}); err != nil {
+		slog.Warn("watch: failed to persist state", "rule", fw.rules[i].Name, "error", err)
+	}
+}
+
+func statWatched(path string) (watchedFile, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return watchedFile{}, false
+	}
+
+	return watchedFile{modTime: info.ModTime(), size: info.Size()}, true
+}
+
+// startFileWatcher watches every rule's directory until ctx is done, then
+// scans them, so nothing written before the watches were added is
+// missed. A directory is rescanned when inotify drops events and when it
+// starts being watched late, so changes made meanwhile are still
+// reported.
+func startFileWatcher(ctx context.Context, w *Worker, rules []WatchRule) error {
+	fw := newFileWatcher(ctx, w, rules)
+
+	dirs := make([]string, len(rules))
+	for i, r := range rules {
+		dirs[i] = r.Path
+	}
+
+	onEvent := func(dir int, name string) {
+		if fw.rules[dir].matches(name) {
+			fw.changed(ctx, dir, filepath.Join(fw.rules[dir].Path, name))
+		}
+	}
+
+	onRescan := func(dir int) { fw.rescan(ctx, dir) }
+
+	err := watchDirs(ctx, dirs, onEvent, onRescan)
+	if err != nil {
+		return err
+	}
+
+	fw.scanAll(ctx)
+
+	slog.Info("file watcher started", "rules", len(rules))
+
+	return nil
+}
@@ -0,0 +1,153 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// watchRetryInterval is how often directories that couldn't be watched
+// (e.g. not created or mounted yet) are retried.
+const watchRetryInterval = time.Minute
+
+// watchDirs reports files written or moved into dirs via inotify, calling
+// onEvent with the index of the directory and the file name. Writes are
+// seen on close, so half-written files are not reported. onRescan is
+// called for every directory when the kernel queue overflowed and events
+// were lost, and for a directory that starts being watched after a failed
+// attempt. Such a directory is retried every watchRetryInterval. Runs
+// until ctx is done.
+func watchDirs(ctx context.Context, dirs []string, onEvent func(dir int, name string), onRescan func(dir int)) error {
+	return watchDirsRetrying(ctx, dirs, onEvent, onRescan, watchRetryInterval)
+}
+
+func watchDirsRetrying(ctx context.Context, dirs []string, onEvent func(dir int, name string), onRescan func(dir int), retry time.Duration) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("inotify init: %w", err)
+	}
+
+	// Non-blocking, so the runtime poller serves reads and Close unblocks them.
+	f := os.NewFile(uintptr(fd), "inotify")
+
+	var (
+		mu      sync.Mutex
+		byWatch = make(map[int32][]int, len(dirs))
+	)
+
+	// addWatches watches dirs[i] for every i in missing and returns the
+	// ones that still failed.
+	addWatches := func(missing []int) []int {
+		var failed []int
+
+		for _, i := range missing {
+			wd, err := syscall.InotifyAddWatch(fd, dirs[i], syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO)
+			if err != nil {
+				failed = append(failed, i)
+
+				continue
+			}
+
+			mu.Lock()
+			byWatch[int32(wd)] = append(byWatch[int32(wd)], i) //nolint:gosec // watch descriptors are small
+			mu.Unlock()
+		}
+
+		return failed
+	}
+
+	all := make([]int, len(dirs))
+	for i := range dirs {
+		all[i] = i
+	}
+
+	missing := addWatches(all)
+	for _, i := range missing {
+		slog.Error("watch: not watching directory yet, retrying", "path", dirs[i], "every", retry)
+	}
+
+	context.AfterFunc(ctx, func() { f.Close() })
+
+	if len(missing) > 0 {
+		go func() {
+			ticker := time.NewTicker(retry)
+			defer ticker.Stop()
+
+			for len(missing) > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				failed := addWatches(missing)
+
+				for _, i := range missing {
+					if !slices.Contains(failed, i) {
+						slog.Info("watch: now watching directory", "path", dirs[i])
+						onRescan(i)
+					}
+				}
+
+				missing = failed
+			}
+		}()
+	}
+
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				if ctx.Err() == nil && !errors.Is(err, os.ErrClosed) {
+					slog.Error("watch: inotify read failed", "error", err)
+				}
+
+				return
+			}
+
+			for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+				ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off])) //nolint:gosec // kernel-defined layout
+				nameStart := off + syscall.SizeofInotifyEvent
+				off = nameStart + int(ev.Len)
+
+				if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+					slog.Warn("watch: inotify queue overflowed, rescanning")
+
+					for i := range dirs {
+						onRescan(i)
+					}
+
+					continue
+				}
+
+				if ev.Len == 0 || off > n {
+					continue
+				}
+
+				name := strings.TrimRight(string(buf[nameStart:off]), "\x00")
+
+				mu.Lock()
+				watching := byWatch[ev.Wd]
+				mu.Unlock()
+
+				for _, dir := range watching {
+					onEvent(dir, name)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchDirs_WatchesDirectoryCreatedLater(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := filepath.Join(t.TempDir(), "share")
+
+	inbox := newTestInbox(ctx, t)
+	w := NewWorker(inbox, PiConfig{}, "", "")
+	fw := newFileWatcher(ctx, w, []WatchRule{{Name: "share", Path: dir, debounce: 10 * time.Millisecond}})
+
+	onEvent := func(_ int, name string) { fw.changed(ctx, 0, filepath.Join(dir, name)) }
+	onRescan := func(i int) { fw.rescan(ctx, i) }
+	must(t, watchDirsRetrying(ctx, []string{dir}, onEvent, onRescan, 10*time.Millisecond))
+
+	// Files already there when the directory appears are seeded, later
+	// ones are reported.
+	staging := filepath.Join(filepath.Dir(dir), "staging")
+	must(t, os.Mkdir(staging, 0o700))
+	must(t, os.WriteFile(filepath.Join(staging, "old.pdf"), []byte("old"), 0o600))
+	must(t, os.Rename(staging, dir))
+
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		fw.mu.Lock()
+		seeded := fw.seeded[0]
+		fw.mu.Unlock()
+
+		if seeded {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("directory never seeded after it was created")
+		}
+	}
+
+	must(t, os.WriteFile(filepath.Join(dir, "new.pdf"), []byte("new"), 0o600))
+	waitForInboxCount(ctx, t, inbox, 1)
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	if !strings.Contains(item.Content, "new.pdf") || strings.Contains(item.Content, "old.pdf") {
+		t.Errorf("content = %q, want only new.pdf reported", item.Content)
+	}
+}
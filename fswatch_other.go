@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// watchDirs: file watching uses inotify and is Linux-only; production
+// runs in NixOS containers.
+func watchDirs(_ context.Context, _ []string, _ func(dir int, name string), _ func(dir int)) error {
+	return errors.New("OPENCROW_WATCH_FILE requires Linux (inotify)")
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadWatchRules_ReportsAllErrors(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestFile(t, "watch.json", `[
+		{"name": "ok", "path": "/tmp"},
+		{"name": "ok", "path": "/tmp"},
+		{"name": "relative", "path": "inbox"},
+		{"name": "bad-pattern", "path": "/tmp", "patterns": ["[a-"]},
+		{"name": "bad-debounce", "path": "/tmp", "debounce": "soon"}
+	]`)
+
+	_, err := loadWatchRules(path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"duplicate name", "relative", "bad-pattern", "bad-debounce"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+}
+
+func TestFileWatcher_ReportsNewFilesOnce(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	must(t, os.WriteFile(filepath.Join(dir, "old.pdf"), []byte("old"), 0o600))
+
+	inbox := newTestInboxWithDB(ctx, t, newTestDBAt(ctx, t, t.TempDir()+"/test.db"))
+	w := NewWorker(inbox, PiConfig{}, "", "")
+
+	rules := []WatchRule{{Name: "scans", Path: dir, Patterns: []string{"*.pdf"}, debounce: 10 * time.Millisecond}}
+	must(t, startFileWatcher(ctx, w, rules))
+
+	// Ignored by pattern, then two matching writes in one debounce window.
+	must(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o600))
+	must(t, os.WriteFile(filepath.Join(dir, "a.pdf"), []byte("a"), 0o600))
+	must(t, os.WriteFile(filepath.Join(dir, "b.pdf"), []byte("b"), 0o600))
+
+	waitForInboxCount(ctx, t, inbox, 1)
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	for _, want := range []string{"Created a.pdf", filepath.Join(dir, "b.pdf")} {
+		if !strings.Contains(item.Content, want) {
+			t.Errorf("content %q missing %q", item.Content, want)
+		}
+	}
+
+	if strings.Contains(item.Content, "old.pdf") || strings.Contains(item.Content, "notes.txt") {
+		t.Errorf("content %q reports a pre-existing or unmatched file", item.Content)
+	}
+
+	if item.Label != "scans" {
+		t.Errorf("Label = %q, want scans", item.Label)
+	}
+
+	// The reported files are recorded once the trigger is queued.
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		rows, err := inbox.queries.ListWatchedFiles(ctx, "scans")
+		if err == nil && len(rows) == 3 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("watched files = %d, %v, want old, a and b recorded", len(rows), err)
+		}
+	}
+
+	// A restart with persisted state only reports what changed meanwhile.
+	cancel()
+
+	ctx2 := t.Context()
+	must(t, os.WriteFile(filepath.Join(dir, "c.pdf"), []byte("c"), 0o600))
+
+	newFileWatcher(ctx2, w, rules).scanAll(ctx2)
+	waitForInboxCount(ctx2, t, inbox, 1)
+
+	item, err = inbox.Dequeue(ctx2)
+	must(t, err)
+
+	if !strings.Contains(item.Content, "c.pdf") || strings.Contains(item.Content, "a.pdf") {
+		t.Errorf("content after restart = %q, want only c.pdf", item.Content)
+	}
+}
+
+func TestFileWatcher_ReportsFilesAddedWhileDownToEmptyDirectory(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+
+	inbox := newTestInbox(ctx, t)
+	w := NewWorker(inbox, PiConfig{}, "", "")
+	rules := []WatchRule{{Name: "scans", Path: dir, debounce: 10 * time.Millisecond}}
+
+	// The first start seeds the empty directory; the next one must not
+	// seed again just because nothing was recorded.
+	newFileWatcher(ctx, w, rules).scanAll(ctx)
+	must(t, os.WriteFile(filepath.Join(dir, "a.pdf"), []byte("a"), 0o600))
+	newFileWatcher(ctx, w, rules).scanAll(ctx)
+
+	waitForInboxCount(ctx, t, inbox, 1)
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	if !strings.Contains(item.Content, "Created a.pdf") {
+		t.Errorf("content = %q, want a.pdf reported", item.Content)
+	}
+}
+
+// Without its state a rule would report every file as new, so neither
+// scans nor events touch it until the state loads.
+func TestFileWatcher_IgnoresRuleWhoseStateFailsToLoad(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+	db := newTestDB(ctx, t)
+
+	w := NewWorker(newTestInboxWithDB(ctx, t, db), PiConfig{}, "", "")
+	path := filepath.Join(dir, "a.pdf")
+	must(t, os.WriteFile(path, []byte("a"), 0o600))
+	must(t, db.Close())
+
+	fw := newFileWatcher(ctx, w, []WatchRule{{Name: "scans", Path: dir, debounce: time.Hour}})
+	fw.scanAll(ctx)
+	fw.changed(ctx, 0, path)
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if len(fw.state[0]) != 0 || len(fw.pending[0]) != 0 || fw.seeded[0] {
+		t.Errorf("state = %v, pending = %v, seeded = %v; want the rule left alone", fw.state[0], fw.pending[0], fw.seeded[0])
+	}
+}
+
+func TestFileWatcher_SkipsMissingDirectory(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+
+	inbox := newTestInbox(ctx, t)
+	w := NewWorker(inbox, PiConfig{}, "", "")
+
+	rules := []WatchRule{
+		{Name: "gone", Path: filepath.Join(dir, "missing"), debounce: 10 * time.Millisecond},
+		{Name: "scans", Path: dir, debounce: 10 * time.Millisecond},
+	}
+	must(t, startFileWatcher(ctx, w, rules))
+
+	must(t, os.WriteFile(filepath.Join(dir, "a.pdf"), []byte("a"), 0o600))
+	waitForInboxCount(ctx, t, inbox, 1)
+}
+
+func TestFileWatcher_KeepsChangesWhenEnqueueFails(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+	db := newTestDB(ctx, t)
+
+	w := NewWorker(newTestInboxWithDB(ctx, t, db), PiConfig{}, "", "")
+	fw := newFileWatcher(ctx, w, []WatchRule{{Name: "scans", Path: dir, debounce: time.Hour}})
+
+	path := filepath.Join(dir, "a.pdf")
+	must(t, os.WriteFile(path, []byte("a"), 0o600))
+	must(t, db.Close())
+
+	fw.changed(ctx, 0, path)
+	fw.flush(ctx, 0)
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if _, ok := fw.state[0][path]; ok {
+		t.Error("file recorded as reported although the trigger was not queued")
+	}
+
+	if _, ok := fw.pending[0][path]; !ok {
+		t.Error("file dropped from pending after a failed enqueue")
+	}
+}
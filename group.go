@@ -0,0 +1,117 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// defaultGroupContext is how many unaddressed group messages are kept
+// as context for the next turn.
+const defaultGroupContext = 20
+
+// GroupConfig controls how the bot takes part in group conversations.
+type GroupConfig struct {
+	MentionOnly bool   // OPENCROW_GROUP_MENTION_ONLY
+	Prefix      string // OPENCROW_GROUP_PREFIX, e.g. "crow:"
+	Context     int    // OPENCROW_GROUP_CONTEXT, default 20
+}
+
+// groupBuffer holds the group messages that did not address the bot,
+// per conversation, until the next message that does. It lives in
+// memory only: after a restart the agent simply sees less backlog.
+type groupBuffer struct {
+	mu      sync.Mutex
+	pending map[string][]chainMessage
+}
+
+func newGroupBuffer() *groupBuffer {
+	return &groupBuffer{pending: make(map[string][]chainMessage)}
+}
+
+// add buffers m for conversationID, dropping the oldest beyond limit.
+func (g *groupBuffer) add(conversationID string, m chainMessage, limit int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	msgs := append(g.pending[conversationID], m)
+	if len(msgs) > limit {
+		msgs = msgs[len(msgs)-limit:]
+	}
+
+	g.pending[conversationID] = msgs
+}
+
+// take returns and clears the buffered messages of conversationID.
+func (g *groupBuffer) take(conversationID string) []chainMessage {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	msgs := g.pending[conversationID]
+	delete(g.pending, conversationID)
+
+	return msgs
+}
+
+// addressesBot reports whether a group message should start a turn: it
+// mentions the bot, replies to one of its messages, or starts with the
+// configured prefix. text is the message with the prefix removed.
+func (a *App) addressesBot(ctx context.Context, msg backend.Message) (text string, ok bool) {
+	if a.group.Prefix != "" {
+		if rest, found := cutPrefixFold(strings.TrimSpace(msg.Text), a.group.Prefix); found {
+			return strings.TrimSpace(rest), true
+		}
+	}
+
+	if msg.MentionsBot || a.repliesToBot(ctx, msg) {
+		return msg.Text, true
+	}
+
+	return msg.Text, false
+}
+
+func (a *App) repliesToBot(ctx context.Context, msg backend.Message) bool {
+	if msg.ReplyToID == "" {
+		return false
+	}
+
+	if msg.Quote != nil && msg.Quote.FromBot {
+		return true
+	}
+
+	m, ok := a.history.Get(ctx, msg.ConversationID, msg.ReplyToID)
+
+	return ok && m.Direction == historyOut
+}
+
+// bufferGroupMessage keeps an unaddressed group message as context for
+// the next turn in that conversation.
+func (a *App) bufferGroupMessage(msg backend.Message) {
+	a.groupCtx.add(msg.ConversationID, chainMessage{
+		Speaker: cmp.Or(msg.SenderName, msg.SenderID),
+		Text:    msg.Text,
+	}, a.group.Context)
+}
+
+// formatGroupContext renders buffered group messages as a quoted block
+// ending in a newline, ready to prepend to the message that addressed
+// the bot.
+func formatGroupContext(msgs []chainMessage) string {
+	var sb strings.Builder
+
+	sb.WriteString("[group messages since you were last addressed, oldest first]\n")
+	writeQuoted(&sb, msgs)
+
+	return sb.String()
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+
+	return s[len(prefix):], true
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+func TestApp_GroupMentionOnly(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		msg         backend.Message
+		wantQueued  bool
+		wantContent string
+	}{
+		{
+			name: "unaddressed is buffered",
+			msg:  backend.Message{Text: "lunch?"},
+		},
+		{
+			name:        "mention",
+			msg:         backend.Message{Text: "@crow what do you think?", MentionsBot: true},
+			wantQueued:  true,
+			wantContent: "@crow what do you think?",
+		},
+		{
+			name:        "prefix is stripped",
+			msg:         backend.Message{Text: "Crow: what do you think?"},
+			wantQueued:  true,
+			wantContent: "\nwhat do you think?",
+		},
+		{
+			name:        "reply to bot",
+			msg:         backend.Message{Text: "why?", ReplyToID: "bot-1"},
+			wantQueued:  true,
+			wantContent: "> you: pizza\nwhy?",
+		},
+		{
+			name:       "reply to someone else",
+			msg:        backend.Message{Text: "agreed", ReplyToID: "human-1"},
+			wantQueued: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			app, _ := newTestApp(t)
+			app.group = GroupConfig{MentionOnly: true, Prefix: "crow:", Context: defaultGroupContext}
+
+			app.history.Record(ctx, historyEntry{ConversationID: testRoom, Direction: historyOut, MessageID: "bot-1", Text: "pizza"})
+			app.history.Record(ctx, historyEntry{ConversationID: testRoom, Direction: historyIn, MessageID: "human-1", Text: "pasta"})
+
+			app.HandleMessage(ctx, backend.Message{ConversationID: testRoom, SenderID: "@bob:example.com", SenderName: "Bob", Text: "I'm in", Group: true})
+
+			tc.msg.ConversationID = testRoom
+			tc.msg.SenderID = "@alice:example.com"
+			tc.msg.Group = true
+			app.HandleMessage(ctx, tc.msg)
+
+			count, err := app.inbox.Count(ctx)
+			must(t, err)
+
+			if !tc.wantQueued {
+				if count != 0 {
+					t.Fatalf("inbox count = %d, want 0", count)
+				}
+
+				return
+			}
+
+			item, err := app.inbox.Dequeue(ctx)
+			must(t, err)
+
+			if !strings.HasPrefix(item.Content, "[group messages since you were last addressed, oldest first]\n> Bob: I'm in\n") {
+				t.Errorf("Content = %q, missing buffered group context", item.Content)
+			}
+
+			if !strings.HasSuffix(item.Content, tc.wantContent) {
+				t.Errorf("Content = %q, want suffix %q", item.Content, tc.wantContent)
+			}
+
+			if item.Sender != "@alice:example.com" {
+				t.Errorf("Sender = %q, want @alice:example.com", item.Sender)
+			}
+
+			// The buffer is consumed by the turn that addressed the bot.
+			if rest := app.groupCtx.take(testRoom); len(rest) != 0 {
+				t.Errorf("buffer after turn = %v, want empty", rest)
+			}
+		})
+	}
+}
+
+func TestApp_GroupAllMessagesByDefault(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, _ := newTestApp(t)
+
+	app.HandleMessage(ctx, backend.Message{ConversationID: testRoom, SenderID: "@bob:example.com", Text: "lunch?", Group: true})
+
+	count, err := app.inbox.Count(ctx)
+	must(t, err)
+
+	if count != 1 {
+		t.Fatalf("inbox count = %d, want 1", count)
+	}
+}
+
+func TestGroupBuffer_Limit(t *testing.T) {
+	t.Parallel()
+
+	g := newGroupBuffer()
+	for _, text := range []string{"one", "two", "three"} {
+		g.add(testRoom, chainMessage{Speaker: "Bob", Text: text}, 2)
+	}
+
+	got := g.take(testRoom)
+	if len(got) != 2 || got[0].Text != "two" || got[1].Text != "three" {
+		t.Errorf("take = %v, want [two three]", got)
+	}
+}
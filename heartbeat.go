@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log/slog"
@@ -13,13 +14,16 @@ import (
 // reasonable precision even when heartbeat is set to 30m or disabled.
 const reminderTick = 1 * time.Minute
 
-// startHeartbeat runs two background loops:
+// startHeartbeat runs three background loops:
 //   - a reminder dispatcher (every reminderTick) that fires due one-shot
 //     reminders from the reminders table as trigger items
+//   - a scheduled-message dispatcher (every reminderTick) that posts due
+//     rows from scheduled_messages verbatim, bypassing the agent
 //   - a heartbeat ticker (every cfg.Interval, if > 0) that enqueues a
 //     heartbeat marker so the worker sends the configured heartbeat prompt
 func startHeartbeat(ctx context.Context, w *Worker, cfg HeartbeatConfig) {
-	go reminderLoop(ctx, w)
+	go reminderLoop(ctx, w, cfg)
+	go scheduledMessageLoop(ctx, w)
 
 	if cfg.Interval <= 0 {
 		slog.Info("heartbeat disabled (interval not set)")
@@ -59,9 +63,10 @@ func startHeartbeat(ctx context.Context, w *Worker, cfg HeartbeatConfig) {
 
 // reminderLoop polls the reminders table and enqueues any due reminders
 // as trigger items. The scheduler owns cleanup: DueReminders is a
-// DELETE … RETURNING, so fired reminders are removed atomically.
-func reminderLoop(ctx context.Context, w *Worker) {
-	slog.Info("reminder dispatcher started", "tick", reminderTick)
+// DELETE … RETURNING, so fired reminders are removed atomically. The
+// same tick re-fires unacknowledged important reminders.
+func reminderLoop(ctx context.Context, w *Worker, cfg HeartbeatConfig) {
+	slog.Info("reminder dispatcher started", "tick", reminderTick, "refire", cfg.ReminderRefire)
 
 	ticker := time.NewTicker(reminderTick)
 	defer ticker.Stop()
@@ -76,6 +81,7 @@ func reminderLoop(ctx context.Context, w *Worker) {
 			return
 		case <-ticker.C:
 			dispatchDueReminders(ctx, w)
+			maintainReminderDeliveries(ctx, w, cfg)
 		}
 	}
 }
@@ -95,15 +101,16 @@ func dispatchDueReminders(ctx context.Context, w *Worker) {
 
 		content := fmt.Sprintf("Reminder (set for %s): %s", r.FireAt, r.Prompt)
 
-		if err := w.inbox.Enqueue(ctx, PriorityTrigger, sourceTrigger, content, ""); err != nil {
+		if err := enqueueReminder(ctx, w, r.FireAt, r.Prompt, r.Important, 0, content); err != nil {
 			// DueReminders is DELETE…RETURNING, so the row is already gone.
 			// Re-insert it so the next tick retries instead of silently
 			// dropping the reminder.
 			slog.Error("reminder: failed to enqueue, re-inserting", "id", r.ID, "error", err)
 
 			if rerr := w.inbox.queries.InsertReminder(ctx, InsertReminderParams{
-				FireAt: r.FireAt,
-				Prompt: r.Prompt,
+				FireAt:    r.FireAt,
+				Prompt:    r.Prompt,
+				Important: r.Important,
 			}); rerr != nil {
 				slog.Error("reminder: re-insert failed, reminder lost", "id", r.ID, "error", rerr)
 			}
@@ -115,6 +122,74 @@ func dispatchDueReminders(ctx context.Context, w *Worker) {
 	}
 }
 
+// scheduledMessageLoop polls scheduled_messages and sends due rows
+// straight through the backend. Unlike reminders they never reach the
+// agent, so sending does not wait for (or disturb) a running turn.
+func scheduledMessageLoop(ctx context.Context, w *Worker) {
+	slog.Info("scheduled message dispatcher started", "tick", reminderTick)
+
+	ticker := time.NewTicker(reminderTick)
+	defer ticker.Stop()
+
+	dispatchScheduledMessages(ctx, w)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			dispatchScheduledMessages(ctx, w)
+		}
+	}
+}
+
+func dispatchScheduledMessages(ctx context.Context, w *Worker) {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	due, err := w.inbox.queries.DueScheduledMessages(ctx, now)
+	if err != nil {
+		slog.Error("scheduled: failed to query due messages", "error", err)
+
+		return
+	}
+
+	for _, m := range due {
+		convID := cmp.Or(m.ConversationID, w.resolveRoomID())
+		if convID == "" {
+			// Same as triggers without a room: keep the row (it was
+			// deleted by the RETURNING query) so the next tick retries
+			// once a conversation exists.
+			slog.Warn("scheduled: no room ID yet, re-inserting", "id", m.ID)
+
+			if err := w.inbox.queries.InsertScheduledMessage(ctx, InsertScheduledMessageParams{
+				SendAt: m.SendAt,
+				Text:   m.Text,
+				Files:  m.Files,
+			}); err != nil {
+				slog.Error("scheduled: re-insert failed, message lost", "id", m.ID, "error", err)
+			}
+
+			continue
+		}
+
+		slog.Info("scheduled: sending", "id", m.ID, "send_at", m.SendAt, "conversation", convID)
+		w.app.sendVerbatim(ctx, convID, m.Text, parseScheduledFiles(m.Files), sourceScheduled)
+	}
+}
+
+// parseScheduledFiles splits the newline-separated files column.
+func parseScheduledFiles(files string) []string {
+	var paths []string
+
+	for line := range strings.SplitSeq(files, "\n") {
+		if p := strings.TrimSpace(line); p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	return paths
+}
+
 // parseHeartbeatItems extracts active checklist items from HEARTBEAT.md.
 // Only `- text` lines count; `- [paused] text` is skipped. Everything else
 // (headers, blank lines, prose) is ignored. No completed/priority metadata —
@@ -156,15 +231,24 @@ func buildHeartbeatPrompt(basePrompt string, items []string) string {
 
 // shouldSuppressReply returns true if the reply should not be forwarded
 // to the user. The HEARTBEAT_OK sentinel is honoured only for heartbeat
-// items — triggers are explicit external events and must always surface,
-// otherwise the model can silently swallow them by emitting the sentinel.
-func shouldSuppressReply(reply, source string) bool {
+// items, and TRIGGER_OK only for triggers their sender marked silent —
+// other triggers are explicit external events and must always surface,
+// otherwise the model can silently swallow them by emitting a sentinel.
+func shouldSuppressReply(reply string, item Inbox) bool {
+	source := item.Source
+
 	if source == sourceHeartbeat && strings.Contains(reply, "HEARTBEAT_OK") {
 		slog.Info(source + ": HEARTBEAT_OK, suppressing")
 
 		return true
 	}
 
+	if source == sourceTrigger && item.Silent != 0 && strings.TrimSpace(reply) == silentTriggerOK {
+		slog.Info(source+": "+silentTriggerOK+", suppressing", "label", item.Label)
+
+		return true
+	}
+
 	if reply == "" {
 		slog.Info(source + ": empty response, suppressing")
 
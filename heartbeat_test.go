@@ -134,3 +134,50 @@ func TestDueRemindersTimestampFormats(t *testing.T) {
 		t.Errorf("got %d due, want %d; variants not normalized: %v", len(due), len(variants), variants)
 	}
 }
+
+func TestDispatchScheduledMessages(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, mb := newTestApp(t)
+	app.worker.roomID.Store(testRoom)
+
+	past := time.Now().UTC().Add(-1 * time.Minute).Format(time.RFC3339)
+	future := time.Now().UTC().Add(1 * time.Hour).Format(time.RFC3339)
+
+	if _, err := app.outbox.db.ExecContext(ctx,
+		`INSERT INTO scheduled_messages (send_at, text, files) VALUES (?, ?, ?), (?, ?, ?)`,
+		past, "stand-up in 5 minutes", "/tmp/agenda.md\n/tmp/notes.txt",
+		future, "future message", "",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatchScheduledMessages(ctx, app.worker)
+
+	// Sent verbatim, never through the agent.
+	if n, _ := app.inbox.Count(ctx); n != 0 {
+		t.Errorf("inbox count = %d, want 0", n)
+	}
+
+	mb.mu.Lock()
+
+	if len(mb.sentMessages) != 1 || mb.sentMessages[0].text != "stand-up in 5 minutes" {
+		t.Errorf("sent = %v, want the due message verbatim", mb.sentMessages)
+	}
+
+	if len(mb.sentFiles) != 2 || mb.sentFiles[1].filePath != "/tmp/notes.txt" {
+		t.Errorf("files = %v, want both attachments", mb.sentFiles)
+	}
+
+	mb.mu.Unlock()
+
+	var remaining int
+	if err := app.outbox.db.QueryRowContext(ctx, `SELECT count(*) FROM scheduled_messages`).Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining != 1 {
+		t.Errorf("scheduled messages remaining = %d, want 1", remaining)
+	}
+}
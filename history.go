@@ -0,0 +1,214 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	historyIn  = "in"
+	historyOut = "out"
+
+	// sourceScheduled marks verbatim scheduled messages in the history;
+	// they never pass through the inbox.
+	sourceScheduled = "scheduled"
+	// sourceCommand marks the output of script commands, which also
+	// bypass the inbox.
+	sourceCommand = "command"
+
+	searchResultLimit = 10
+	// maxSearchSnippet bounds each hit in the !search reply.
+	maxSearchSnippet = 200
+)
+
+// historyEntry is one message for the conversation log.
+type historyEntry struct {
+	ConversationID string
+	Direction      string // historyIn or historyOut
+	Sender         string
+	MessageID      string
+	ReplyTo        string
+	Source         string
+	Text           string
+	Attachments    []string
+}
+
+// historyStore is the full conversation log with an FTS5 index. Unlike
+// outboxStore it keeps every message, so past chat can be searched after
+// compaction has dropped it from the agent's context.
+type historyStore struct {
+	db      *sql.DB
+	queries *Queries
+}
+
+// newHistoryStore wraps an existing database connection. The caller owns
+// the DB lifecycle.
+func newHistoryStore(db *sql.DB) *historyStore {
+	return &historyStore{db: db, queries: New(db)}
+}
+
+// searchMessagesSQL is written by hand rather than in sqlc/queries.sql:
+// sqlc can't analyze FTS5 MATCH and rank, and fails to generate.
+const searchMessagesSQL = `
+SELECT m.id, m.conversation_id, m.direction, m.sender, m.message_id, m.reply_to, m.source, m.text, m.attachments, m.created_at
+FROM messages_fts
+JOIN messages m ON m.id = messages_fts.rowid
+WHERE messages_fts MATCH ? AND m.conversation_id = ?
+ORDER BY rank
+LIMIT ?`
+
+// Record appends e to the log. Failures are logged, not returned: a
+// missing history row must never stop a message from being handled.
+func (s *historyStore) Record(ctx context.Context, e historyEntry) {
+	if strings.TrimSpace(e.Text) == "" && len(e.Attachments) == 0 {
+		return
+	}
+
+	if err := s.queries.InsertMessage(ctx, InsertMessageParams{
+		ConversationID: e.ConversationID,
+		Direction:      e.Direction,
+		Sender:         e.Sender,
+		MessageID:      e.MessageID,
+		ReplyTo:        e.ReplyTo,
+		Source:         e.Source,
+		Text:           e.Text,
+		Attachments:    strings.Join(e.Attachments, "\n"),
+		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		slog.Warn("failed to record message history", "conversation", e.ConversationID, "error", err)
+	}
+}
+
+// Get returns the most recent logged message with the backend ID messageID.
+func (s *historyStore) Get(ctx context.Context, conversationID, messageID string) (Messages, bool) {
+	if messageID == "" {
+		return Messages{}, false
+	}
+
+	m, err := s.queries.GetMessage(ctx, GetMessageParams{
+		ConversationID: conversationID,
+		MessageID:      messageID,
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, context.Canceled) {
+			slog.Error("unexpected error reading message history", "message", messageID, "error", err)
+		}
+
+		return Messages{}, false
+	}
+
+	return m, true
+}
+
+// Search returns up to limit messages of conversationID matching query,
+// best match first.
+func (s *historyStore) Search(ctx context.Context, conversationID, query string, limit int) ([]Messages, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, searchMessagesSQL, match, conversationID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("searching history: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []Messages
+
+	for rows.Next() {
+		var m Messages
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.Direction, &m.Sender, &m.MessageID,
+			&m.ReplyTo, &m.Source, &m.Text, &m.Attachments, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("searching history: %w", err)
+		}
+
+		hits = append(hits, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("searching history: %w", err)
+	}
+
+	return hits, nil
+}
+
+// ftsQuery turns free text into an FTS5 query that matches messages
+// containing every word. Each word is quoted, so punctuation such as
+// "-" or ":" is never parsed as FTS5 syntax; a trailing "*" is kept as
+// a prefix match.
+func ftsQuery(query string) string {
+	var terms []string
+
+	for _, word := range strings.Fields(query) {
+		prefix := strings.HasSuffix(word, "*")
+
+		word = strings.TrimRight(word, "*")
+		if word == "" {
+			continue
+		}
+
+		term := `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// attachmentPathRe matches the file path in backend.AttachmentText lines.
+var attachmentPathRe = regexp.MustCompile(`(?m)^\[User sent a file \(.*\): (.+)\]$`)
+
+// inboundAttachments returns the local paths of files the backend
+// announced in an inbound message's text.
+func inboundAttachments(text string) []string {
+	var paths []string
+
+	for _, m := range attachmentPathRe.FindAllStringSubmatch(text, -1) {
+		paths = append(paths, m[1])
+	}
+
+	return paths
+}
+
+// formatSearchResults renders hits for !search, with times shown in loc.
+func formatSearchResults(query string, hits []Messages, loc *time.Location) string {
+	if len(hits) == 0 {
+		return fmt.Sprintf("No messages found for %q.", query)
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Found %d message(s) for %q:", len(hits), query)
+
+	for _, m := range hits {
+		who := "bot"
+		if m.Direction == historyIn {
+			who = cmp.Or(m.Sender, "user")
+		}
+
+		when := m.CreatedAt
+		if t, err := time.Parse(time.RFC3339, m.CreatedAt); err == nil {
+			when = t.In(loc).Format("2006-01-02 15:04")
+		}
+
+		text := strings.Join(strings.Fields(m.Text), " ")
+		if r := []rune(text); len(r) > maxSearchSnippet {
+			text = string(r[:maxSearchSnippet]) + "…"
+		}
+
+		fmt.Fprintf(&sb, "\n\n%s %s: %s", when, who, text)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+func TestFtsQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in, want string
+	}{
+		{"deploy", `"deploy"`},
+		{"  two   words ", `"two" "words"`},
+		{"foo-bar NOT", `"foo-bar" "NOT"`},
+		{`say "hi"`, `"say" """hi"""`},
+		{"deplo*", `"deplo"*`},
+		{"*", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ftsQuery(tt.in); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInboundAttachments(t *testing.T) {
+	t.Parallel()
+
+	text := "look at these\n" +
+		backend.AttachmentText("photo (1).jpg", "/tmp/a/photo (1).jpg") + "\n" +
+		backend.AttachmentText("", "") + "\n" +
+		backend.AttachmentText("notes", "/tmp/b/notes.txt")
+
+	got := strings.Join(inboundAttachments(text), ",")
+	if got != "/tmp/a/photo (1).jpg,/tmp/b/notes.txt" {
+		t.Errorf("attachments = %q", got)
+	}
+}
+
+func TestHistory_RecordAndSearch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, mb := newTestApp(t)
+
+	app.HandleMessage(ctx, backend.Message{
+		ConversationID: testRoom,
+		SenderID:       "@alice:example.com",
+		Text:           "When is the dentist appointment?",
+		MessageID:      "in-1",
+	})
+	app.sendReplyWithFiles(ctx, testRoom, "Your dentist-appointment is Tuesday.\n<sendfile>/tmp/card.ics</sendfile>", "in-1", sourceUser, replyFooter{})
+	app.sendVerbatim(ctx, "!other", "dentist reminder for another room", nil, sourceScheduled)
+
+	hits, err := app.history.Search(ctx, testRoom, "dentist", 10)
+	must(t, err)
+
+	if len(hits) != 2 {
+		t.Fatalf("got %d hits, want 2: %+v", len(hits), hits)
+	}
+
+	byDir := map[string]Messages{}
+	for _, h := range hits {
+		byDir[h.Direction] = h
+	}
+
+	if in := byDir[historyIn]; in.Sender != "@alice:example.com" || in.MessageID != "in-1" || in.Source != sourceUser {
+		t.Errorf("inbound row = %+v", in)
+	}
+
+	if out := byDir[historyOut]; out.Attachments != "/tmp/card.ics" || out.Source != sourceUser || strings.Contains(out.Text, "sendfile") {
+		t.Errorf("outbound row = %+v", out)
+	}
+
+	// Punctuation and prefixes don't trip the FTS5 parser.
+	for _, q := range []string{"dentist-appointment", "appoint*", "tues*"} {
+		hits, err := app.history.Search(ctx, testRoom, q, 10)
+		must(t, err)
+
+		if len(hits) == 0 {
+			t.Errorf("search %q found nothing", q)
+		}
+	}
+
+	sendCommand(app, "!search dentist tuesday")
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	reply := mb.sentMessages[len(mb.sentMessages)-1].text
+	if !strings.Contains(reply, "Found 1 message(s)") || !strings.Contains(reply, "bot: Your dentist-appointment is Tuesday.") {
+		t.Errorf("search reply = %q", reply)
+	}
+}
+
+func TestFormatSearchResults_UsesLocation(t *testing.T) {
+	t.Parallel()
+
+	hits := []Messages{{Direction: historyIn, Sender: "Alice", Text: "dentist at 3", CreatedAt: "2025-06-16T07:00:00Z"}}
+
+	got := formatSearchResults("dentist", hits, time.FixedZone("UTC+2", 2*60*60))
+	if !strings.Contains(got, "2025-06-16 09:00 Alice: dentist at 3") {
+		t.Errorf("results = %q, want the time in the configured zone", got)
+	}
+}
+
+// One pi process serves every room, so history_search must see the
+// conversation of each turn, not the one pi was started for.
+func TestWorker_TurnConversationFollowsEachTurn(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+	mb := &mockBackend{}
+	w.SetBackend(mb)
+	w.SetApp(NewApp(mb, w, w.inbox, newTestDB(ctx, t)))
+
+	w.processPrompt(ctx, Inbox{Source: sourceUser, Content: "record-conversation", ConversationID: "!a"})
+
+	w.mu.Lock()
+	pi := w.pi
+	w.mu.Unlock()
+
+	w.processPrompt(ctx, Inbox{Source: sourceTrigger, Content: "record-conversation", ConversationID: "!b"})
+
+	w.mu.Lock()
+	restarted := w.pi != pi
+	w.mu.Unlock()
+
+	if restarted {
+		t.Fatal("pi was restarted between the turns")
+	}
+
+	data, err := os.ReadFile(filepath.Join(w.piCfg.SessionDir, "conversations.log"))
+	must(t, err)
+
+	if got := strings.Fields(string(data)); !slices.Equal(got, []string{"!a", "!b"}) {
+		t.Errorf("turn conversations = %q, want !a then !b", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(w.piCfg.SessionDir, turnConversationFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("turn conversation file left after the turn: %v", err)
+	}
+}
@@ -0,0 +1,638 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRRulePeriods bounds recurrence expansion (e.g. ~27 years of a daily
+// rule past the window start) so a malformed rule can't spin forever.
+const maxRRulePeriods = 10000
+
+// icsEvent is a VEVENT reduced to what pre-event triggers need. An event
+// with RecurrenceID set overrides one instance of the recurring event with
+// the same UID.
+type icsEvent struct {
+	UID          string
+	Summary      string
+	Location     string
+	Description  string
+	Attendees    []string
+	Start        time.Time
+	End          time.Time
+	Duration     time.Duration
+	AllDay       bool
+	Cancelled    bool
+	RRule        *rrule
+	ExDates      []time.Time
+	RecurrenceID time.Time
+
+	// badRRule is why the RRULE could not be used. Such an event is
+	// skipped: firing only its first occurrence would be wrong too.
+	badRRule error
+}
+
+// eventInstance is one occurrence of an event.
+type eventInstance struct {
+	Event *icsEvent
+	Start time.Time
+}
+
+// Key identifies the instance for deduplication. It includes the actual
+// start, so an instance that is moved fires again at its new time.
+func (in eventInstance) Key() string {
+	return in.Event.UID + "@" + in.Start.UTC().Format(time.RFC3339)
+}
+
+// parseICS reads the VEVENTs of an iCalendar stream. Events with
+// unsupported recurrence rules (e.g. FREQ=HOURLY) are logged and skipped.
+func parseICS(r io.Reader) ([]icsEvent, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events []icsEvent
+		cur    *icsEvent
+		depth  int // nesting inside the current VEVENT (VALARM etc.)
+		errs   error
+	)
+
+	for _, line := range lines {
+		name, params, value := splitICSLine(line)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT" && cur == nil:
+			cur = &icsEvent{}
+		case cur == nil:
+			continue
+		case name == "BEGIN":
+			depth++
+		case name == "END" && depth > 0:
+			depth--
+		case name == "END" && value == "VEVENT":
+			if cur.Duration == 0 && !cur.End.IsZero() {
+				cur.Duration = cur.End.Sub(cur.Start)
+			}
+
+			switch {
+			case cur.badRRule != nil:
+				slog.Warn("calendar: skipping event with unsupported recurrence", "event", cur.UID, "error", cur.badRRule)
+			case cur.UID != "" && !cur.Start.IsZero():
+				events = append(events, *cur)
+			}
+
+			cur = nil
+		case depth == 0:
+			if err := cur.setProperty(name, params, value); err != nil {
+				errs = errors.Join(errs, fmt.Errorf("event %q: %s: %w", cur.UID, name, err))
+			}
+		}
+	}
+
+	if errs != nil {
+		slog.Warn("calendar: skipped invalid properties", "error", errs)
+	}
+
+	return events, nil
+}
+
+func (ev *icsEvent) setProperty(name string, params map[string]string, value string) error {
+	var err error
+
+	switch name {
+	case "UID":
+		ev.UID = value
+	case "SUMMARY":
+		ev.Summary = unescapeICSText(value)
+	case "LOCATION":
+		ev.Location = unescapeICSText(value)
+	case "DESCRIPTION":
+		ev.Description = unescapeICSText(value)
+	case "STATUS":
+		ev.Cancelled = strings.EqualFold(value, "CANCELLED")
+	case "ATTENDEE":
+		ev.Attendees = append(ev.Attendees, attendeeName(params["CN"], value))
+	case "DTSTART":
+		ev.Start, ev.AllDay, err = parseICSTime(value, params)
+	case "DTEND":
+		ev.End, _, err = parseICSTime(value, params)
+	case "DURATION":
+		ev.Duration, err = parseICSDuration(value)
+	case "RRULE":
+		if ev.RRule, err = parseRRule(value, params); err != nil {
+			ev.badRRule, err = err, nil
+		}
+	case "EXDATE":
+		for v := range strings.SplitSeq(value, ",") {
+			t, _, perr := parseICSTime(v, params)
+			if perr != nil {
+				return perr
+			}
+
+			ev.ExDates = append(ev.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		ev.RecurrenceID, _, err = parseICSTime(value, params)
+	}
+
+	return err
+}
+
+func attendeeName(cn, value string) string {
+	if cn != "" {
+		return cn
+	}
+
+	if addr, ok := strings.CutPrefix(strings.ToLower(value), "mailto:"); ok {
+		return addr
+	}
+
+	return value
+}
+
+// unfoldICS splits r into logical lines, joining RFC 5545 continuation
+// lines (starting with a space or tab).
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading calendar: %w", err)
+	}
+
+	return lines, nil
+}
+
+// splitICSLine splits `NAME;PARAM=x;PARAM="y:z":value`.
+func splitICSLine(line string) (string, map[string]string, string) {
+	inQuote := false
+	colon := -1
+
+	for i, c := range line {
+		if c == '"' {
+			inQuote = !inQuote
+		} else if c == ':' && !inQuote {
+			colon = i
+
+			break
+		}
+	}
+
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+var icsTextUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescapeICSText(s string) string {
+	return icsTextUnescaper.Replace(s)
+}
+
+// parseICSTime parses a DATE or DATE-TIME value: UTC with a "Z" suffix,
+// in the TZID parameter's zone, or floating (local time).
+func parseICSTime(value string, params map[string]string) (time.Time, bool, error) {
+	loc := time.Local
+
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		} else {
+			slog.Debug("calendar: unknown TZID, using local time", "tzid", tzid)
+		}
+	}
+
+	if len(value) == len("20060102") || params["VALUE"] == "DATE" {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return t, true, fmt.Errorf("parsing date %q: %w", value, err)
+		}
+
+		return t, true, nil
+	}
+
+	if v, ok := strings.CutSuffix(value, "Z"); ok {
+		t, err := time.ParseInLocation("20060102T150405", v, time.UTC)
+		if err != nil {
+			return t, false, fmt.Errorf("parsing time %q: %w", value, err)
+		}
+
+		return t, false, nil
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return t, false, fmt.Errorf("parsing time %q: %w", value, err)
+	}
+
+	return t, false, nil
+}
+
+// parseICSDuration parses an RFC 5545 duration such as "PT1H30M" or "P1W".
+func parseICSDuration(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+
+	rest := strings.TrimPrefix(s, "+")
+	if r, ok := strings.CutPrefix(rest, "-"); ok {
+		sign, rest = -1, r
+	}
+
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+
+	var d time.Duration
+
+	num := ""
+
+	for i := range len(rest) {
+		c := rest[i]
+
+		switch {
+		case c == 'T':
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case units[c] != 0 && num != "":
+			n, _ := strconv.Atoi(num)
+			d += time.Duration(n) * units[c]
+			num = ""
+		default:
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+	}
+
+	return sign * d, nil
+}
+
+// rrule is the supported subset of RFC 5545 recurrence rules: FREQ
+// (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYDAY (with
+// ordinals for MONTHLY/YEARLY), BYMONTHDAY and BYMONTH.
+type rrule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []time.Month
+}
+
+// weekdayNum is a BYDAY entry: n is the ordinal within the month (1 =
+// first, -1 = last), 0 for every such weekday.
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func parseRRule(value string, params map[string]string) (*rrule, error) {
+	r := &rrule{interval: 1}
+
+	for part := range strings.SplitSeq(value, ";") {
+		k, v, _ := strings.Cut(part, "=")
+
+		var err error
+
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.freq = strings.ToUpper(v)
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(v)
+		case "COUNT":
+			r.count, err = strconv.Atoi(v)
+		case "UNTIL":
+			var allDay bool
+			if r.until, allDay, err = parseICSTime(v, params); allDay {
+				r.until = r.until.Add(24*time.Hour - time.Second)
+			}
+		case "BYDAY":
+			r.byDay, err = parseByDay(v)
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseInts(v)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(v)
+
+			for _, m := range months {
+				r.byMonth = append(r.byMonth, time.Month(m))
+			}
+		case "WKST":
+			// Weeks always start on Monday, the RFC 5545 default.
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %q", k)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("RRULE %s: %w", k, err)
+		}
+	}
+
+	switch r.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported RRULE FREQ %q", r.freq)
+	}
+
+	if r.interval < 1 {
+		return nil, errors.New("RRULE INTERVAL must be positive")
+	}
+
+	return r, nil
+}
+
+func parseByDay(v string) ([]weekdayNum, error) {
+	var days []weekdayNum
+
+	for s := range strings.SplitSeq(v, ",") {
+		if len(s) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", s)
+		}
+
+		day, ok := icsWeekdays[strings.ToUpper(s[len(s)-2:])]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", s)
+		}
+
+		var n int
+
+		if prefix := s[:len(s)-2]; prefix != "" {
+			var err error
+			if n, err = strconv.Atoi(prefix); err != nil {
+				return nil, fmt.Errorf("invalid BYDAY %q", s)
+			}
+		}
+
+		days = append(days, weekdayNum{n: n, day: day})
+	}
+
+	return days, nil
+}
+
+func parseInts(v string) ([]int, error) {
+	var out []int
+
+	for s := range strings.SplitSeq(v, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", s)
+		}
+
+		out = append(out, n)
+	}
+
+	return out, nil
+}
+
+// all yields the rule's occurrences in order, starting with dtstart or,
+// for rules without COUNT, shortly before after. Each occurrence keeps
+// dtstart's wall-clock time in its location, so events stay at 09:00
+// across DST changes.
+func (r *rrule) all(dtstart, after time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		loc := dtstart.Location()
+		y, m, d := dtstart.Date()
+		hh, mm, ss := dtstart.Clock()
+		emitted := 0
+		first := r.firstPeriod(dtstart, after)
+
+		for n := first; n < first+maxRRulePeriods; n++ {
+			for _, day := range r.periodDays(n, y, m, d, dtstart.Weekday(), loc) {
+				t := time.Date(day.Year(), day.Month(), day.Day(), hh, mm, ss, 0, loc)
+				if t.Before(dtstart) {
+					continue
+				}
+
+				if !r.until.IsZero() && t.After(r.until) {
+					return
+				}
+
+				if r.count > 0 && emitted >= r.count {
+					return
+				}
+
+				emitted++
+
+				if !yield(t) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// firstPeriod returns a period that starts before after, so long-running
+// series are expanded from near the window instead of from dtstart. A
+// COUNT must be counted from the start, so those rules begin at 0.
+func (r *rrule) firstPeriod(dtstart, after time.Time) int {
+	if r.count > 0 || !after.After(dtstart) {
+		return 0
+	}
+
+	var periods int
+
+	switch r.freq {
+	case "DAILY":
+		periods = int(after.Sub(dtstart).Hours() / 24)
+	case "WEEKLY":
+		periods = int(after.Sub(dtstart).Hours() / (24 * 7))
+	case "MONTHLY":
+		periods = (after.Year()-dtstart.Year())*12 + int(after.Month()-dtstart.Month())
+	case "YEARLY":
+		periods = after.Year() - dtstart.Year()
+	}
+
+	// One period of slack covers DST shifts and partial periods.
+	return max(periods/r.interval-1, 0)
+}
+
+// periodDays returns the sorted candidate days of the n-th period.
+func (r *rrule) periodDays(n, y int, m time.Month, d int, wd time.Weekday, loc *time.Location) []time.Time {
+	var days []time.Time
+
+	switch r.freq {
+	case "DAILY":
+		day := time.Date(y, m, d+n*r.interval, 0, 0, 0, 0, loc)
+		if r.inMonth(day) && r.onDay(day) && r.onMonthDay(day) {
+			days = append(days, day)
+		}
+	case "WEEKLY":
+		monday := time.Date(y, m, d-(int(wd)+6)%7+7*n*r.interval, 0, 0, 0, 0, loc)
+
+		for i := range 7 {
+			day := monday.AddDate(0, 0, i)
+
+			if (len(r.byDay) == 0 && day.Weekday() != wd) || !r.onDay(day) || !r.inMonth(day) {
+				continue
+			}
+
+			days = append(days, day)
+		}
+	case "MONTHLY":
+		first := time.Date(y, m+time.Month(n*r.interval), 1, 0, 0, 0, 0, loc)
+		if r.inMonth(first) {
+			days = r.monthDays(first, d)
+		}
+	case "YEARLY":
+		months := r.byMonth
+		if len(months) == 0 {
+			months = []time.Month{m}
+		}
+
+		for _, mo := range months {
+			days = append(days, r.monthDays(time.Date(y+n*r.interval, mo, 1, 0, 0, 0, 0, loc), d)...)
+		}
+	}
+
+	slices.SortFunc(days, time.Time.Compare)
+
+	return slices.CompactFunc(days, time.Time.Equal)
+}
+
+// monthDays returns the days of first's month selected by BYMONTHDAY,
+// BYDAY or, without either, the start day d.
+func (r *rrule) monthDays(first time.Time, d int) []time.Time {
+	daysIn := first.AddDate(0, 1, -1).Day()
+
+	var days []time.Time
+
+	switch {
+	case len(r.byMonthDay) > 0:
+		for _, md := range r.byMonthDay {
+			if md < 0 {
+				md = daysIn + md + 1
+			}
+
+			if md >= 1 && md <= daysIn {
+				if day := first.AddDate(0, 0, md-1); r.onDay(day) {
+					days = append(days, day)
+				}
+			}
+		}
+	case len(r.byDay) > 0:
+		for _, bd := range r.byDay {
+			var matching []time.Time
+
+			for i := range daysIn {
+				if day := first.AddDate(0, 0, i); day.Weekday() == bd.day {
+					matching = append(matching, day)
+				}
+			}
+
+			switch {
+			case bd.n == 0:
+				days = append(days, matching...)
+			case bd.n > 0 && bd.n <= len(matching):
+				days = append(days, matching[bd.n-1])
+			case bd.n < 0 && -bd.n <= len(matching):
+				days = append(days, matching[len(matching)+bd.n])
+			}
+		}
+	case d <= daysIn:
+		days = append(days, first.AddDate(0, 0, d-1))
+	}
+
+	return days
+}
+
+func (r *rrule) onDay(day time.Time) bool {
+	return len(r.byDay) == 0 || slices.ContainsFunc(r.byDay, func(bd weekdayNum) bool { return bd.day == day.Weekday() })
+}
+
+func (r *rrule) onMonthDay(day time.Time) bool {
+	return len(r.byMonthDay) == 0 || slices.Contains(r.byMonthDay, day.Day())
+}
+
+func (r *rrule) inMonth(day time.Time) bool {
+	return len(r.byMonth) == 0 || slices.Contains(r.byMonth, day.Month())
+}
+
+// expandEvents returns the instances of events starting in (from, to],
+// with overrides (RECURRENCE-ID) replacing the instances they modify and
+// cancelled instances and EXDATEs removed, sorted by start.
+func expandEvents(events []icsEvent, from, to time.Time) []eventInstance {
+	overridden := make(map[string]bool)
+
+	for _, ev := range events {
+		if !ev.RecurrenceID.IsZero() {
+			overridden[ev.UID+"@"+ev.RecurrenceID.UTC().Format(time.RFC3339)] = true
+		}
+	}
+
+	inWindow := func(t time.Time) bool { return t.After(from) && !t.After(to) }
+
+	var out []eventInstance
+
+	for i := range events {
+		ev := &events[i]
+
+		if !ev.RecurrenceID.IsZero() || ev.RRule == nil {
+			if !ev.Cancelled && inWindow(ev.Start) {
+				out = append(out, eventInstance{Event: ev, Start: ev.Start})
+			}
+
+			continue
+		}
+
+		if ev.Cancelled {
+			continue
+		}
+
+		for t := range ev.RRule.all(ev.Start, from) {
+			if t.After(to) {
+				break
+			}
+
+			if !inWindow(t) || overridden[ev.UID+"@"+t.UTC().Format(time.RFC3339)] ||
+				slices.ContainsFunc(ev.ExDates, t.Equal) {
+				continue
+			}
+
+			out = append(out, eventInstance{Event: ev, Start: t})
+		}
+	}
+
+	slices.SortFunc(out, func(a, b eventInstance) int { return a.Start.Compare(b.Start) })
+
+	return out
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICS(t *testing.T) {
+	t.Parallel()
+
+	events, err := parseICS(strings.NewReader(strings.ReplaceAll(`BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:standup@example.com
+SUMMARY:Standup\, daily
+LOCATION:Room 1
+DESCRIPTION:Agenda:\n- blockers
+ and wins
+ATTENDEE;CN="Alice A.";ROLE=REQ-PARTICIPANT:mailto:alice@example.com
+ATTENDEE:MAILTO:bob@example.com
+DTSTART;TZID=Europe/Berlin:20250616T090000
+DURATION:PT15M
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR
+BEGIN:VALARM
+DESCRIPTION:alarm text
+END:VALARM
+END:VEVENT
+END:VCALENDAR
+`, "\n", "\r\n")))
+	must(t, err)
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	ev := events[0]
+
+	if ev.Summary != "Standup, daily" || ev.Location != "Room 1" {
+		t.Errorf("summary/location = %q/%q", ev.Summary, ev.Location)
+	}
+
+	if ev.Description != "Agenda:\n- blockersand wins" {
+		t.Errorf("description = %q (alarm text or folding wrong)", ev.Description)
+	}
+
+	if got := strings.Join(ev.Attendees, ";"); got != "Alice A.;bob@example.com" {
+		t.Errorf("attendees = %q", got)
+	}
+
+	if ev.Duration != 15*time.Minute || ev.Start.Location().String() != "Europe/Berlin" {
+		t.Errorf("duration = %v, location = %v", ev.Duration, ev.Start.Location())
+	}
+}
+
+func TestExpandEvents(t *testing.T) {
+	t.Parallel()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	must(t, err)
+
+	at := func(s string) time.Time {
+		t.Helper()
+
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		must(t, err)
+
+		return tm
+	}
+
+	tests := []struct {
+		name     string
+		ics      string
+		from, to string
+		want     []string
+	}{
+		{
+			name: "weekly by day across DST",
+			ics: `DTSTART;TZID=Europe/Berlin:20250321T090000
+RRULE:FREQ=WEEKLY;BYDAY=MO,FR`,
+			from: "2025-03-27 00:00", to: "2025-04-01 00:00",
+			want: []string{"2025-03-28 09:00", "2025-03-31 09:00"},
+		},
+		{
+			name: "monthly last friday with count",
+			ics: `DTSTART;TZID=Europe/Berlin:20250131T170000
+RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3`,
+			from: "2025-01-01 00:00", to: "2025-12-31 00:00",
+			want: []string{"2025-01-31 17:00", "2025-02-28 17:00", "2025-03-28 17:00"},
+		},
+		{
+			name: "monthly on the 31st skips short months",
+			ics: `DTSTART;TZID=Europe/Berlin:20250131T080000
+RRULE:FREQ=MONTHLY;UNTIL=20250601T000000Z`,
+			from: "2025-01-01 00:00", to: "2025-12-31 00:00",
+			want: []string{"2025-01-31 08:00", "2025-03-31 08:00", "2025-05-31 08:00"},
+		},
+		{
+			name: "daily with exdate",
+			ics: `DTSTART;TZID=Europe/Berlin:20250601T070000
+RRULE:FREQ=DAILY;INTERVAL=2
+EXDATE;TZID=Europe/Berlin:20250605T070000`,
+			from: "2025-06-02 00:00", to: "2025-06-08 00:00",
+			want: []string{"2025-06-03 07:00", "2025-06-07 07:00"},
+		},
+		{
+			name: "yearly by month",
+			ics: `DTSTART;TZID=Europe/Berlin:20240115T120000
+RRULE:FREQ=YEARLY;BYMONTH=1,7`,
+			from: "2025-01-01 00:00", to: "2025-12-31 00:00",
+			want: []string{"2025-01-15 12:00", "2025-07-15 12:00"},
+		},
+		{
+			name: "daily series older than the expansion bound",
+			ics: `DTSTART;TZID=Europe/Berlin:19900101T080000
+RRULE:FREQ=DAILY`,
+			from: "2025-06-02 00:00", to: "2025-06-04 00:00",
+			want: []string{"2025-06-02 08:00", "2025-06-03 08:00"},
+		},
+		{
+			name: "unsupported frequency is skipped",
+			ics: `DTSTART;TZID=Europe/Berlin:20250602T080000
+RRULE:FREQ=HOURLY`,
+			from: "2025-06-02 00:00", to: "2025-06-04 00:00",
+		},
+		{
+			name: "single event outside window",
+			ics:  `DTSTART:20250601T070000Z`,
+			from: "2025-06-02 00:00", to: "2025-06-08 00:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			events, err := parseICS(strings.NewReader("BEGIN:VEVENT\nUID:x\n" + tt.ics + "\nEND:VEVENT\n"))
+			must(t, err)
+
+			var got []string
+			for _, in := range expandEvents(events, at(tt.from), at(tt.to)) {
+				got = append(got, in.Start.In(berlin).Format("2006-01-02 15:04"))
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("instances = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandEvents_Overrides(t *testing.T) {
+	t.Parallel()
+
+	events, err := parseICS(strings.NewReader(`BEGIN:VEVENT
+UID:sync
+SUMMARY:Sync
+DTSTART:20250602T100000Z
+RRULE:FREQ=DAILY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:sync
+SUMMARY:Sync (moved)
+RECURRENCE-ID:20250603T100000Z
+DTSTART:20250603T140000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:sync
+RECURRENCE-ID:20250604T100000Z
+DTSTART:20250604T100000Z
+STATUS:CANCELLED
+END:VEVENT
+`))
+	must(t, err)
+
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	var got []string
+	for _, in := range expandEvents(events, from, from.AddDate(0, 0, 7)) {
+		got = append(got, in.Event.Summary+" "+in.Start.Format("01-02 15:04"))
+	}
+
+	want := "Sync 06-02 10:00,Sync (moved) 06-03 14:00"
+	if strings.Join(got, ",") != want {
+		t.Errorf("instances = %v, want %s", got, want)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"cmp"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"slices"
+	"time"
 )
 
 // Priority levels for inbox items. Lower number = higher priority.
@@ -54,10 +56,103 @@ func (s *InboxStore) Enqueue(ctx context.Context, priority int64, source, conten
 	return nil
 }
 
+// EnqueueUser inserts a user message along with who sent it.
+func (s *InboxStore) EnqueueUser(ctx context.Context, item Inbox) error {
+	if err := s.queries.EnqueueInbox(ctx, EnqueueInboxParams{
+		Priority:         PriorityUser,
+		Source:           sourceUser,
+		Content:          item.Content,
+		ReplyTo:          item.ReplyTo,
+		Sender:           item.Sender,
+		ConversationName: item.ConversationName,
+	}); err != nil {
+		return fmt.Errorf("enqueuing user message: %w", err)
+	}
+
+	slog.Info("inbox: enqueued", "source", sourceUser, "priority", PriorityUser)
+
+	return nil
+}
+
+// EnqueueReminder inserts a fired reminder as a trigger item linked to its
+// reminder_deliveries row, so the worker can record which message
+// delivered it once the agent has replied.
+func (s *InboxStore) EnqueueReminder(ctx context.Context, content string, deliveryID int64) error {
+	if err := s.queries.EnqueueInbox(ctx, EnqueueInboxParams{
+		Priority:   PriorityTrigger,
+		Source:     sourceTrigger,
+		Content:    content,
+		ReminderID: deliveryID,
+	}); err != nil {
+		return fmt.Errorf("enqueuing reminder: %w", err)
+	}
+
+	slog.Info("inbox: enqueued", "source", sourceTrigger, "priority", PriorityTrigger, "reminder", deliveryID)
+
+	return nil
+}
+
+// EnqueueTrigger inserts a trigger item carrying the optional metadata of
+// a structured trigger (label, dedup key, silent flag, target
+// conversation) and returns its inbox ID. Returns 0 without inserting if
+// item.DedupKey is set and an item with the same key is still queued.
+func (s *InboxStore) EnqueueTrigger(ctx context.Context, item Inbox) (int64, error) {
+	id, err := s.queries.EnqueueInboxUnlessQueued(ctx, EnqueueInboxUnlessQueuedParams{
+		Priority:       item.Priority,
+		Source:         sourceTrigger,
+		Content:        item.Content,
+		ReplyTo:        item.ReplyTo,
+		Label:          item.Label,
+		DedupKey:       item.DedupKey,
+		Silent:         item.Silent,
+		ConversationID: item.ConversationID,
+		WaiterID:       item.WaiterID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Info("inbox: duplicate trigger dropped", "dedup_key", item.DedupKey)
+
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("enqueuing trigger: %w", err)
+	}
+
+	slog.Info("inbox: enqueued", "source", sourceTrigger, "priority", item.Priority, "label", item.Label, "id", id)
+
+	return id, nil
+}
+
 // Dequeue removes and returns the highest-priority (lowest number) item.
 // Returns sql.ErrNoRows if the inbox is empty.
 func (s *InboxStore) Dequeue(ctx context.Context) (Inbox, error) {
-	return s.queries.DequeueInbox(ctx)
+	return s.DequeueHolding(ctx, 0)
+}
+
+// DequeueHolding is Dequeue, except that mergeable triggers younger than
+// hold stay queued. Returns sql.ErrNoRows if nothing else is queued.
+func (s *InboxStore) DequeueHolding(ctx context.Context, hold time.Duration) (Inbox, error) {
+	return s.queries.DequeueInbox(ctx, time.Now().UTC().Add(-hold).Format(inboxTimeLayout))
+}
+
+// OldestMergeableTrigger returns when the oldest queued mergeable trigger
+// was created, or false if there is none.
+func (s *InboxStore) OldestMergeableTrigger(ctx context.Context) (time.Time, bool) {
+	createdAt, err := s.queries.OldestMergeableTrigger(ctx)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("inbox: failed to look up held triggers", "error", err)
+		}
+
+		return time.Time{}, false
+	}
+
+	created, err := time.Parse(inboxTimeLayout, createdAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return created, true
 }
 
 // Requeue re-inserts an item that was interrupted. Heartbeat markers are
@@ -68,10 +163,18 @@ func (s *InboxStore) Requeue(ctx context.Context, item Inbox) error {
 	}
 
 	if err := s.queries.EnqueueInbox(ctx, EnqueueInboxParams{
-		Priority: item.Priority,
-		Source:   item.Source,
-		Content:  item.Content,
-		ReplyTo:  item.ReplyTo,
+		Priority:         item.Priority,
+		Source:           item.Source,
+		Content:          item.Content,
+		ReplyTo:          item.ReplyTo,
+		ReminderID:       item.ReminderID,
+		Label:            item.Label,
+		DedupKey:         item.DedupKey,
+		Silent:           item.Silent,
+		ConversationID:   item.ConversationID,
+		WaiterID:         item.WaiterID,
+		Sender:           item.Sender,
+		ConversationName: item.ConversationName,
 	}); err != nil {
 		return fmt.Errorf("requeueing %s item: %w", item.Source, err)
 	}
@@ -96,6 +199,26 @@ func (s *InboxStore) DequeueUserBatch(ctx context.Context) ([]Inbox, error) {
 	return items, nil
 }
 
+// DequeueMergeableTriggers atomically removes the triggers that
+// mergeTriggerItems may fold into one for conversationID and label,
+// returning them sorted by ID (insertion order).
+func (s *InboxStore) DequeueMergeableTriggers(ctx context.Context, conversationID, label string) ([]Inbox, error) {
+	items, err := s.queries.DequeueMergeableTriggers(ctx, DequeueMergeableTriggersParams{
+		ConversationID: conversationID,
+		Label:          label,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dequeuing mergeable triggers: %w", err)
+	}
+
+	// SQLite's DELETE ... RETURNING doesn't guarantee order.
+	slices.SortFunc(items, func(a, b Inbox) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return items, nil
+}
+
 // Count returns the number of items in the inbox.
 func (s *InboxStore) Count(ctx context.Context) (int64, error) {
 	return s.queries.CountInbox(ctx)
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"database/sql"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,6 +29,12 @@ func newTestDBAt(ctx context.Context, t *testing.T, path string) *sql.DB {
 		t.Fatal(err)
 	}
 
+	// Each connection to ":memory:" opens its own empty database, so a
+	// second pooled connection would see no tables.
+	if path == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+
 	if _, err := db.ExecContext(ctx, dbSchema); err != nil {
 		db.Close()
 		t.Fatal(err)
@@ -238,8 +246,10 @@ func TestWorker_MergeUserItems(t *testing.T) {
 	first := Inbox{Source: sourceUser, Content: "first", ReplyTo: "reply-1"}
 	merged := worker.mergeUserItems(ctx, first)
 
-	if merged.Content != "first\nsecond\nthird" {
-		t.Errorf("Content = %q, want %q", merged.Content, "first\nsecond\nthird")
+	for _, want := range []string{"[3 messages sent while you were busy", "[message 1 of 3, a reply]\nfirst", "[message 3 of 3, sent "} {
+		if !strings.Contains(merged.Content, want) {
+			t.Errorf("Content = %q, missing %q", merged.Content, want)
+		}
 	}
 
 	if merged.ReplyTo != "reply-3" {
@@ -254,6 +264,36 @@ func TestWorker_MergeUserItems(t *testing.T) {
 	}
 }
 
+func TestMergeUserMessages(t *testing.T) {
+	t.Parallel()
+
+	// A made-up zone, so the test can't pass by using the system's.
+	loc := time.FixedZone("UTC+13", 13*60*60)
+
+	merged := mergeUserMessages([]Inbox{
+		{Source: sourceUser, Content: "about that", ReplyTo: "bot-1", Sender: "Alice", CreatedAt: "2025-06-16T09:00:00.000Z"},
+		{Source: sourceUser, Content: "here\n[User sent a file (log): /tmp/log.txt]", Sender: "Bob", CreatedAt: "2025-06-16T09:04:00.000Z"},
+		{Source: sourceUser, Content: "thoughts?", Sender: "Alice", CreatedAt: "2025-06-16T09:05:00.000Z"},
+	}, loc)
+
+	// The newest message replying to something wins over trailing
+	// messages that reply to nothing.
+	if merged.ReplyTo != "bot-1" {
+		t.Errorf("ReplyTo = %q, want bot-1", merged.ReplyTo)
+	}
+
+	// Different senders move the name from the envelope into each message.
+	if merged.Sender != "" {
+		t.Errorf("Sender = %q, want empty for a mixed batch", merged.Sender)
+	}
+
+	want := "[message 2 of 3, from Bob, sent 2025-06-16 22:04:00 UTC+13, 1 file(s) attached]\nhere\n"
+
+	if !strings.Contains(merged.Content, want) {
+		t.Errorf("Content = %q, missing %q", merged.Content, want)
+	}
+}
+
 func TestInbox_DequeueUserBatch(t *testing.T) {
 	t.Parallel()
 
@@ -330,6 +370,56 @@ func TestOpenDB_Pragmas(t *testing.T) {
 	}
 }
 
+// TestOpenDB_MigratesColumns opens a database created before the
+// reminder lifecycle columns existed and checks openDB adds them, so
+// upgrading does not fail every insert with "no such column".
+func TestOpenDB_MigratesColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	old, err := sql.Open("sqlite", filepath.Join(dir, opencrowDBFile)+sqliteDSNParams)
+	must(t, err)
+
+	_, err = old.ExecContext(ctx, `
+		CREATE TABLE reminders (id INTEGER PRIMARY KEY AUTOINCREMENT, fire_at TEXT NOT NULL, prompt TEXT NOT NULL);
+		CREATE TABLE inbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, priority INTEGER NOT NULL DEFAULT 2,
+			source TEXT NOT NULL, content TEXT NOT NULL DEFAULT '', reply_to TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+		);
+		INSERT INTO reminders (fire_at, prompt) VALUES ('2025-01-01T00:00:00Z', 'old');
+	`)
+	must(t, err)
+	must(t, old.Close())
+
+	db, err := openDB(ctx, dir)
+	must(t, err)
+
+	defer db.Close()
+
+	q := New(db)
+	must(t, q.InsertReminder(ctx, InsertReminderParams{FireAt: "2025-01-01T00:00:00Z", Prompt: "new", Important: 1}))
+
+	due, err := q.DueReminders(ctx, "2025-06-01T00:00:00Z")
+	must(t, err)
+
+	if len(due) != 2 {
+		t.Fatalf("due = %d reminders, want 2", len(due))
+	}
+
+	inbox := newTestInboxWithDB(ctx, t, db)
+	must(t, inbox.EnqueueReminder(ctx, "r", 7))
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	if item.ReminderID != 7 {
+		t.Errorf("ReminderID = %d, want 7", item.ReminderID)
+	}
+}
+
 func must(t *testing.T, err error) {
 	t.Helper()
 
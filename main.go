@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/pinpox/opencrow/backend"
 	"github.com/pinpox/opencrow/matrix"
@@ -36,6 +37,10 @@ func main() {
 		os.Exit(0)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "trigger" {
+		os.Exit(runTriggerCLI(os.Args[2:], os.Stdin, os.Stderr))
+	}
+
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		Level: parseLogLevel(os.Getenv("OPENCROW_LOG_LEVEL")),
 		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
@@ -114,6 +119,10 @@ func run() int {
 	cancel()
 	<-workerDone
 
+	if !worker.app.waitScripts(scriptShutdownTimeout) {
+		slog.Warn("script commands still running at shutdown, abandoning them")
+	}
+
 	_ = b.Close()
 
 	return exitCode
@@ -142,6 +151,12 @@ func openDB(ctx context.Context, sessionDir string) (*sql.DB, error) {
 		return nil, fmt.Errorf("migrating schema: %w", err)
 	}
 
+	if err := migrateColumns(ctx, db); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
 	if err := migrateLegacyOutbox(ctx, db, sessionDir); err != nil {
 		slog.Warn("failed to migrate legacy sent_messages.db", "error", err)
 	}
@@ -149,6 +164,47 @@ func openDB(ctx context.Context, sessionDir string) (*sql.DB, error) {
 	return db, nil
 }
 
+// addedColumns lists columns introduced after their table first shipped.
+// CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so each is
+// added with ALTER TABLE when an older opencrow.db lacks it. The decl must
+// carry a default so existing rows stay valid.
+var addedColumns = []struct{ table, column, decl string }{
+	{"reminders", "important", "INTEGER NOT NULL DEFAULT 0"},
+	{"inbox", "reminder_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"inbox", "label", "TEXT NOT NULL DEFAULT ''"},
+	{"inbox", "dedup_key", "TEXT NOT NULL DEFAULT ''"},
+	{"inbox", "silent", "INTEGER NOT NULL DEFAULT 0"},
+	{"inbox", "conversation_id", "TEXT NOT NULL DEFAULT ''"},
+	{"inbox", "waiter_id", "TEXT NOT NULL DEFAULT ''"},
+	{"inbox", "sender", "TEXT NOT NULL DEFAULT ''"},
+	{"inbox", "conversation_name", "TEXT NOT NULL DEFAULT ''"},
+	{"messages", "reply_to", "TEXT NOT NULL DEFAULT ''"},
+}
+
+func migrateColumns(ctx context.Context, db *sql.DB) error {
+	for _, c := range addedColumns {
+		var n int
+		if err := db.QueryRowContext(ctx,
+			"SELECT count(*) FROM pragma_table_info(?) WHERE name = ?", c.table, c.column,
+		).Scan(&n); err != nil {
+			return fmt.Errorf("inspecting %s.%s: %w", c.table, c.column, err)
+		}
+
+		if n > 0 {
+			continue
+		}
+
+		slog.Info("adding column to existing table", "table", c.table, "column", c.column)
+
+		// Identifiers come from the static list above, never from input.
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.decl)); err != nil {
+			return fmt.Errorf("adding %s.%s: %w", c.table, c.column, err)
+		}
+	}
+
+	return nil
+}
+
 func migrateLegacyOutbox(ctx context.Context, db *sql.DB, sessionDir string) error {
 	legacyPath := filepath.Join(sessionDir, legacyOutboxDBFile)
 
@@ -191,6 +247,9 @@ func migrateLegacyOutbox(ctx context.Context, db *sql.DB, sessionDir string) err
 func wireServices(ctx context.Context, cfg *Config, db *sql.DB, inbox *InboxStore) (backend.Backend, *Worker, error) { //nolint:ireturn // factory returns interface by design
 	// Phase 1: create objects with nil cross-references.
 	worker := NewWorker(inbox, cfg.Pi, cfg.Heartbeat.Prompt, defaultTriggerPrompt)
+	worker.triggerCfg = cfg.Trigger
+	worker.promptCfg = cfg.Prompt
+	worker.toolsCfg = cfg.Tools
 
 	var app *App
 
@@ -204,6 +263,14 @@ func wireServices(ctx context.Context, cfg *Config, db *sql.DB, inbox *InboxStor
 
 	// Phase 2: wire cross-references.
 	app = NewApp(b, worker, inbox, db)
+	app.reply = cfg.Reply
+	app.group = cfg.Group
+	app.commands.prefixes = cfg.Commands.Prefixes
+
+	if err := app.registerCustomCommands(cfg.Commands.Commands); err != nil {
+		return nil, nil, fmt.Errorf("OPENCROW_COMMANDS_FILE: %w", err)
+	}
+
 	worker.SetApp(app)
 	worker.SetBackend(b)
 
@@ -216,6 +283,33 @@ func wireServices(ctx context.Context, cfg *Config, db *sql.DB, inbox *InboxStor
 	// Start background services.
 	startHeartbeat(ctx, worker, cfg.Heartbeat)
 	startTriggerPipe(ctx, worker, cfg.Pi.SessionDir)
+	startTriggerSocket(ctx, worker, cfg.Pi.SessionDir)
+
+	if cfg.Webhook.Listen != "" {
+		if err := startWebhookServer(ctx, worker, cfg.Webhook); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(cfg.Watch) > 0 {
+		if err := startFileWatcher(ctx, worker, cfg.Watch); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(cfg.Feeds.Feeds) > 0 {
+		startFeedPoller(ctx, newFeedPoller(worker, cfg.Feeds.Feeds), cfg.Feeds.Interval)
+	}
+
+	if len(cfg.Calendar.Paths) > 0 {
+		startCalendar(ctx, newCalendarWatcher(worker, cfg.Calendar))
+	}
+
+	if len(cfg.Cron) > 0 {
+		app.cron = newCronScheduler(ctx, worker, cfg.Cron, time.Now())
+		startCron(ctx, app.cron)
+	}
+
 	worker.StartIdleReaper(ctx)
 
 	return b, worker, nil
@@ -8,12 +8,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"log/slog"
 	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -58,6 +60,8 @@ type Backend struct {
 	roomMu     sync.Mutex
 	activeRoom string
 
+	roomNames sync.Map // id.RoomID → room name, fetched once, updated on m.room.name
+
 	// onRoomCleanup is called when a room is cleaned up (leave/ban).
 	// Wired by the caller to kill pi processes and stop trigger pipes.
 	onRoomCleanup func(roomID string)
@@ -201,6 +205,12 @@ func (b *Backend) Run(ctx context.Context) error {
 		go b.handleMessage(ctx, evt)
 	})
 
+	syncer.OnEventType(event.StateRoomName, func(_ context.Context, evt *event.Event) {
+		if content, ok := evt.Content.Parsed.(*event.RoomNameEventContent); ok {
+			b.roomNames.Store(evt.RoomID, content.Name)
+		}
+	})
+
 	syncer.OnSync(func(_ context.Context, resp *mautrix.RespSync, since string) bool {
 		if since != "" {
 			b.initialSynced.Store(true)
@@ -282,6 +292,67 @@ func (b *Backend) SendMessage(ctx context.Context, conversationID string, text s
 	return lastEventID
 }
 
+// SendMessageWithDetails sends text with a collapsed <details> section
+// below it. Text too long for one event is sent by SendMessage with the
+// section inlined.
+func (b *Backend) SendMessageWithDetails(ctx context.Context, conversationID, text, summary, details, replyToID string) string {
+	if len(text)+len(summary)+len(details) > maxMessageLen {
+		return b.SendMessage(ctx, conversationID, strings.TrimLeft(text+"\n\n"+summary+"\n\n"+details, "\n"), replyToID)
+	}
+
+	content := format.RenderMarkdown(text, true, false)
+	content.FormattedBody = formattedBody(content) + "<details><summary>" +
+		formattedBody(format.RenderMarkdown(summary, true, false)) + "</summary>" +
+		formattedBody(format.RenderMarkdown(details, true, false)) + "</details>"
+	content.Format = event.FormatHTML
+	content.Body = strings.TrimLeft(content.Body+"\n\n"+summary+"\n\n"+details, "\n")
+
+	if replyToID != "" {
+		content.RelatesTo = (&event.RelatesTo{}).SetReplyTo(id.EventID(replyToID))
+	}
+
+	resp, err := b.client.SendMessageEvent(ctx, id.RoomID(conversationID), event.EventMessage, &content)
+	if err != nil {
+		slog.Error("failed to send message", "room", conversationID, "error", err)
+
+		return ""
+	}
+
+	return string(resp.EventID)
+}
+
+// formattedBody returns the HTML of rendered content. RenderMarkdown
+// leaves it empty for text without formatting.
+func formattedBody(content event.MessageEventContent) string {
+	if content.Format == event.FormatHTML {
+		return content.FormattedBody
+	}
+
+	return strings.ReplaceAll(html.EscapeString(content.Body), "\n", "<br>")
+}
+
+// EditMessage replaces the text of an earlier message with an m.replace
+// edit. Unlike SendMessage it doesn't split long text.
+func (b *Backend) EditMessage(ctx context.Context, conversationID, messageID, text string) error {
+	content := format.RenderMarkdown(text, true, false)
+	content.SetEdit(id.EventID(messageID))
+
+	if _, err := b.client.SendMessageEvent(ctx, id.RoomID(conversationID), event.EventMessage, &content); err != nil {
+		return fmt.Errorf("editing message %s: %w", messageID, err)
+	}
+
+	return nil
+}
+
+// RedactMessage redacts an earlier message.
+func (b *Backend) RedactMessage(ctx context.Context, conversationID, messageID string) error {
+	if _, err := b.client.RedactEvent(ctx, id.RoomID(conversationID), id.EventID(messageID)); err != nil {
+		return fmt.Errorf("redacting message %s: %w", messageID, err)
+	}
+
+	return nil
+}
+
 // SendFile uploads and sends a file to a Matrix room.
 func (b *Backend) SendFile(ctx context.Context, conversationID string, filePath string) error {
 	roomID := id.RoomID(conversationID)
@@ -630,14 +701,115 @@ func (b *Backend) handleMessage(ctx context.Context, evt *event.Event) {
 	}
 
 	b.handler(ctx, backend.Message{
-		ConversationID: roomID,
-		SenderID:       string(evt.Sender),
-		Text:           text,
-		MessageID:      string(evt.ID),
-		ReplyToID:      replyToID,
+		ConversationID:   roomID,
+		ConversationName: b.roomName(ctx, evt.RoomID),
+		SenderID:         string(evt.Sender),
+		SenderName:       b.displayName(ctx, evt.RoomID, evt.Sender),
+		Text:             text,
+		MessageID:        string(evt.ID),
+		ReplyToID:        replyToID,
+		Group:            b.isGroupRoom(ctx, evt.RoomID),
+		MentionsBot:      b.mentionsBot(msg),
 	})
 }
 
+// isGroupRoom reports whether the room has members besides the bot and
+// one other user.
+func (b *Backend) isGroupRoom(ctx context.Context, roomID id.RoomID) bool {
+	if b.client.StateStore == nil {
+		return false
+	}
+
+	members, err := b.client.StateStore.GetRoomJoinedOrInvitedMembers(ctx, roomID)
+	if err != nil {
+		slog.Debug("failed to count room members", "room", roomID, "error", err)
+
+		return false
+	}
+
+	return len(members) > 2
+}
+
+// mentionsBot reports whether msg @-mentions the bot, via m.mentions or,
+// for clients that predate it, a pill or the bare user ID in the text.
+func (b *Backend) mentionsBot(msg *event.MessageEventContent) bool {
+	if msg.Mentions != nil && slices.Contains(msg.Mentions.UserIDs, b.userID) {
+		return true
+	}
+
+	return strings.Contains(msg.Body, string(b.userID)) ||
+		strings.Contains(msg.FormattedBody, "matrix.to/#/"+string(b.userID))
+}
+
+// displayName returns the sender's display name in the room from the
+// state store, or "" if unknown.
+func (b *Backend) displayName(ctx context.Context, roomID id.RoomID, userID id.UserID) string {
+	if b.client.StateStore == nil {
+		return ""
+	}
+
+	member, err := b.client.StateStore.TryGetMember(ctx, roomID, userID)
+	if err != nil || member == nil {
+		return ""
+	}
+
+	return member.Displayname
+}
+
+// roomName returns the room's name, fetching it on first use. Unnamed
+// rooms (including most DMs) return "".
+func (b *Backend) roomName(ctx context.Context, roomID id.RoomID) string {
+	if cached, ok := b.roomNames.Load(roomID); ok {
+		if name, ok := cached.(string); ok {
+			return name
+		}
+	}
+
+	var content event.RoomNameEventContent
+	if err := b.client.StateEvent(ctx, roomID, event.StateRoomName, "", &content); err != nil {
+		slog.Debug("no room name", "room", roomID, "error", err)
+	}
+
+	b.roomNames.Store(roomID, content.Name)
+
+	return content.Name
+}
+
+// FetchMessage loads a message event from the homeserver, decrypting it if
+// needed. Used to resolve reply targets the core has no record of.
+func (b *Backend) FetchMessage(ctx context.Context, conversationID, messageID string) (backend.Quote, error) {
+	evt, err := b.client.GetEvent(ctx, id.RoomID(conversationID), id.EventID(messageID))
+	if err != nil {
+		return backend.Quote{}, fmt.Errorf("fetching event: %w", err)
+	}
+
+	evt.RoomID = id.RoomID(conversationID)
+
+	if err := evt.Content.ParseRaw(evt.Type); err != nil && !errors.Is(err, event.ErrContentAlreadyParsed) {
+		return backend.Quote{}, fmt.Errorf("parsing event: %w", err)
+	}
+
+	if evt.Type == event.EventEncrypted {
+		if evt, err = b.cryptoHelper.Decrypt(ctx, evt); err != nil {
+			return backend.Quote{}, fmt.Errorf("decrypting event: %w", err)
+		}
+	}
+
+	msg := evt.Content.AsMessage()
+	if msg == nil || msg.Body == "" {
+		return backend.Quote{}, fmt.Errorf("event %s is not a message", messageID)
+	}
+
+	msg.RemoveReplyFallback()
+
+	return backend.Quote{
+		SenderID:  string(evt.Sender),
+		Text:      msg.Body,
+		FromBot:   evt.Sender == b.userID,
+		ReplyToID: string(msg.RelatesTo.GetReplyTo()),
+	}, nil
+}
+
 // filterMessage checks whether the event should be processed and returns
 // the message content, or nil if the event should be dropped.
 func (b *Backend) filterMessage(evt *event.Event) *event.MessageEventContent {
@@ -0,0 +1,12 @@
+{ runCommand, sqlite }:
+runCommand "opencrow-extension-history"
+  {
+    src = ../extensions/history;
+    inherit sqlite;
+  }
+  ''
+    mkdir -p $out
+    cp -r $src/* $out/
+    substituteInPlace $out/index.ts \
+      --replace-fail '"@@SQLITE_BIN@@"' "\"$sqlite/bin/sqlite3\""
+  ''
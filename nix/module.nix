@@ -89,7 +89,8 @@ let
           (opencrow exports OPENCROW_PI_EXTENSIONS for the bot to forward).
 
           Bundled extensions: `memory` (cross-session recall via sediment),
-          `reminders` (remind_at/list/cancel tools backed by opencrow.db).
+          `reminders` (remind_at/list/cancel tools backed by opencrow.db),
+          `history` (history_search tool over the chat log in opencrow.db).
         '';
         example = lib.literalExpression ''
           {
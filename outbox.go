@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log/slog"
+	"time"
 )
 
 const maxOutboxPerConversation = 500
@@ -105,3 +106,45 @@ func (s *outboxStore) Get(ctx context.Context, conversationID, messageID string)
 
 	return text
 }
+
+// RecordReminderDelivery links a fired reminder to the backend message that
+// delivered it, so a later reply to that message can snooze or acknowledge
+// the reminder. Only pending deliveries are updated; a requeued item that
+// is answered twice keeps its first message. An empty messageID (reply
+// suppressed, or a backend without message IDs) still marks the delivery
+// delivered, so important reminders re-fire.
+func (s *outboxStore) RecordReminderDelivery(ctx context.Context, deliveryID int64, conversationID, messageID string) {
+	if deliveryID == 0 {
+		return
+	}
+
+	if err := s.queries.MarkReminderDelivered(ctx, MarkReminderDeliveredParams{
+		ConversationID: conversationID,
+		MessageID:      messageID,
+		DeliveredAt:    time.Now().UTC().Format(time.RFC3339),
+		ID:             deliveryID,
+	}); err != nil {
+		slog.Warn("failed to record reminder delivery", "reminder", deliveryID, "error", err)
+	}
+}
+
+// ReminderDelivery returns the reminder delivered by messageID, if any.
+func (s *outboxStore) ReminderDelivery(ctx context.Context, conversationID, messageID string) (ReminderDeliveries, bool) {
+	if messageID == "" {
+		return ReminderDeliveries{}, false
+	}
+
+	d, err := s.queries.GetReminderDelivery(ctx, GetReminderDeliveryParams{
+		ConversationID: conversationID,
+		MessageID:      messageID,
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, context.Canceled) {
+			slog.Error("unexpected error reading reminder delivery", "message", messageID, "error", err)
+		}
+
+		return ReminderDeliveries{}, false
+	}
+
+	return d, true
+}
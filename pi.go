@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -16,12 +17,58 @@ import (
 
 const scannerBufSize = 1 << 20 // 1 MB
 
+// turnConversationFile holds the conversation of the running turn, for
+// extensions that scope their tools to it. One pi process serves every
+// room, so .room_id can't be used for this.
+const turnConversationFile = ".turn_conversation"
+
+// setTurnConversation records conversationID as the running turn's
+// conversation in sessionDir; "" clears it. The file is replaced
+// atomically, and removed if that fails, so an extension never reads
+// another room's ID.
+func setTurnConversation(sessionDir, conversationID string) error {
+	path := filepath.Join(sessionDir, turnConversationFile)
+
+	if conversationID != "" {
+		tmp := path + ".tmp"
+
+		err := os.WriteFile(tmp, []byte(conversationID), 0o600)
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		_ = os.Remove(tmp)
+		_ = os.Remove(path)
+
+		return fmt.Errorf("writing turn conversation: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("clearing turn conversation: %w", err)
+	}
+
+	return nil
+}
+
 // ToolCallEvent contains information about a tool invocation relayed from pi.
 type ToolCallEvent struct {
+	ID       string // pi's tool call ID, shared with the matching ToolResultEvent
 	ToolName string
 	Args     map[string]any
 }
 
+// ToolResultEvent describes how a tool invocation ended.
+type ToolResultEvent struct {
+	ID       string
+	ToolName string
+	IsError  bool
+	Output   string // text content of the result
+}
+
 // PiProcess manages a single pi --mode rpc subprocess.
 // The caller (Worker) is responsible for serializing access — only one
 // goroutine calls sendAndWait at a time, so no mutex is needed.
@@ -35,11 +82,13 @@ type ToolCallEvent struct {
 // goroutine never writes to stdin directly; extension UI requests are
 // handled by the caller when it processes events.
 type PiProcess struct {
-	cmd        *exec.Cmd
-	stdin      io.WriteCloser
-	done       chan struct{}
-	events     <-chan rpcParsed    // single persistent reader feeds all waiters
-	onToolCall func(ToolCallEvent) // optional callback for tool_execution_start events
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+	done         chan struct{}
+	events       <-chan rpcParsed      // single persistent reader feeds all waiters
+	onToolCall   func(ToolCallEvent)   // optional callback for tool_execution_start events
+	onThinking   func(string)          // optional callback for thinking deltas
+	onToolResult func(ToolResultEvent) // optional callback for tool_execution_end events
 }
 
 // StartPi spawns a pi --mode rpc subprocess for the given room.
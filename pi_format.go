@@ -13,7 +13,7 @@ func logRPCEvent(evt rpcEvent) {
 	case rpcTypeToolExecutionStart:
 		slog.Info("pi: tool started", logToolArgs(evt)...)
 	case rpcTypeToolExecutionEnd:
-		slog.Info("pi: tool finished", "tool", evt.ToolName)
+		slog.Info("pi: tool finished", "tool", evt.ToolName, "is_error", evt.IsError)
 	case rpcTypeAutoRetryStart:
 		logAutoRetryStart(evt)
 	case rpcTypeAutoRetryEnd:
@@ -37,9 +37,12 @@ type rpcEvent struct {
 	// extension_ui_request fields
 	Method string `json:"method,omitempty"`
 
-	// tool_execution_start fields — camelCase is dictated by the pi protocol.
-	ToolName string         `json:"toolName,omitempty"` //nolint:tagliatelle // pi protocol uses camelCase
-	Args     map[string]any `json:"args,omitempty"`
+	// tool_execution_start/end fields — camelCase is dictated by the pi protocol.
+	ToolCallID string          `json:"toolCallId,omitempty"` //nolint:tagliatelle // pi protocol uses camelCase
+	ToolName   string          `json:"toolName,omitempty"`   //nolint:tagliatelle // pi protocol uses camelCase
+	Args       map[string]any  `json:"args,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	IsError    bool            `json:"isError,omitempty"` //nolint:tagliatelle // pi protocol uses camelCase
 
 	// auto_retry_start fields — camelCase is dictated by the pi protocol.
 	Attempt      int    `json:"attempt,omitempty"`
@@ -81,6 +84,11 @@ type agentMessage struct {
 	ErrorMessage string          `json:"errorMessage,omitempty"` //nolint:tagliatelle // pi protocol uses camelCase
 }
 
+// toolResult is the result payload of a tool_execution_end event.
+type toolResult struct {
+	Content json.RawMessage `json:"content"`
+}
+
 // contentBlock represents a content block in an assistant message.
 type contentBlock struct {
 	Type string `json:"type"`
@@ -259,20 +267,38 @@ func (p *PiProcess) nextEvent() (rpcParsed, error) {
 }
 
 // handleSideEffects processes events that are common to all commands:
-// extension UI auto-cancel and tool call notifications.
+// extension UI auto-cancel and tool call and thinking notifications.
 func (p *PiProcess) handleSideEffects(evt rpcEvent) error {
 	switch evt.Type {
 	case rpcTypeExtensionUIRequest:
 		p.autoRespondExtensionUI(evt)
 
+	case rpcTypeMessageUpdate:
+		if p.onThinking != nil && evt.AssistantMessageEvent != nil &&
+			evt.AssistantMessageEvent.Type == "thinking_delta" &&
+			evt.AssistantMessageEvent.Delta != "" {
+			p.onThinking(evt.AssistantMessageEvent.Delta)
+		}
+
 	case rpcTypeToolExecutionStart:
 		if p.onToolCall != nil {
 			p.onToolCall(ToolCallEvent{
+				ID:       evt.ToolCallID,
 				ToolName: evt.ToolName,
 				Args:     evt.Args,
 			})
 		}
 
+	case rpcTypeToolExecutionEnd:
+		if p.onToolResult != nil {
+			p.onToolResult(ToolResultEvent{
+				ID:       evt.ToolCallID,
+				ToolName: evt.ToolName,
+				IsError:  evt.IsError,
+				Output:   parseToolResult(evt.Result),
+			})
+		}
+
 	case rpcTypeResponse:
 		if evt.Success != nil && !*evt.Success {
 			return fmt.Errorf("pi rejected command %q: %s", evt.Command, evt.Error)
@@ -545,3 +571,13 @@ func parseAssistantContent(raw json.RawMessage) string {
 
 	return strings.Join(parts, "\n")
 }
+
+// parseToolResult returns the text content of a tool result.
+func parseToolResult(raw json.RawMessage) string {
+	var r toolResult
+	if len(raw) == 0 || json.Unmarshal(raw, &r) != nil || len(r.Content) == 0 {
+		return ""
+	}
+
+	return parseAssistantContent(r.Content)
+}
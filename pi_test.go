@@ -122,6 +122,30 @@ func TestWaitForResult_RetryRescindsError(t *testing.T) {
 	}
 }
 
+func TestHandleSideEffects_ToolResult(t *testing.T) {
+	t.Parallel()
+
+	line := `{"type":"tool_execution_end","toolCallId":"call-7","toolName":"bash",` +
+		`"result":{"content":[{"type":"text","text":"boom\n\nCommand exited with code 2"}]},"isError":true}`
+
+	var evt rpcEvent
+	if err := json.Unmarshal([]byte(line), &evt); err != nil {
+		t.Fatal(err)
+	}
+
+	var got ToolResultEvent
+
+	p := &PiProcess{onToolResult: func(e ToolResultEvent) { got = e }}
+	if err := p.handleSideEffects(evt); err != nil {
+		t.Fatal(err)
+	}
+
+	want := ToolResultEvent{ID: "call-7", ToolName: "bash", IsError: true, Output: "boom\n\nCommand exited with code 2"}
+	if got != want {
+		t.Errorf("onToolResult got %+v, want %+v", got, want)
+	}
+}
+
 func agentEnd(stop, errMsg, text string) rpcEvent {
 	msg := agentMessage{
 		Role:         "assistant",
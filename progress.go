@@ -0,0 +1,236 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+const (
+	defaultProgressInterval = 5 * time.Minute
+	// maxProgressText bounds the quoted model text in a progress update.
+	maxProgressText = 200
+	// progressTextKeep bounds the streamed text kept for quoting.
+	progressTextKeep = 4096
+)
+
+// progressReporter posts an update every interval of a long turn, built
+// from the turn's tool calls and the model's latest text: "⏳ still
+// working · 20m13s: 14 tools (6 bash, 5 read, 3 edit), 2 failed, now bash
+// `go test`". Later updates edit the first where the backend supports
+// it. It runs on its own goroutine while pi callbacks feed it from the
+// turn's, so unlike toolNotifier it locks.
+type progressReporter struct {
+	be             Backend
+	editor         backend.MessageEditor   // nil if the backend can't edit
+	redactor       backend.MessageRedactor // nil if the backend can't redact
+	flavor         backend.MarkdownFlavor
+	templates      toolTemplates
+	conversationID string
+	start          time.Time
+
+	mu      sync.Mutex
+	calls   int
+	failed  int
+	counts  map[string]int
+	names   []string // tool names in first-use order
+	current string   // label of the last tool started
+	text    strings.Builder
+
+	messageID string // the progress message, "" until posted
+}
+
+func newProgressReporter(be Backend, cfg ToolsConfig, conversationID string) *progressReporter {
+	p := &progressReporter{
+		be:             be,
+		flavor:         be.MarkdownFlavor(),
+		templates:      cfg.Templates,
+		conversationID: conversationID,
+		start:          time.Now(),
+		counts:         make(map[string]int),
+	}
+
+	if editor, ok := be.(backend.MessageEditor); ok {
+		p.editor = editor
+	}
+
+	if redactor, ok := be.(backend.MessageRedactor); ok {
+		p.redactor = redactor
+	}
+
+	return p
+}
+
+func (p *progressReporter) toolStarted(evt ToolCallEvent) {
+	tmpl := p.templates.lookup(evt.ToolName)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.counts[evt.ToolName] == 0 {
+		p.names = append(p.names, evt.ToolName)
+	}
+
+	p.calls++
+	p.counts[evt.ToolName]++
+
+	if !tmpl.Hide {
+		p.current = toolLabel(evt, tmpl, p.flavor)
+	}
+}
+
+func (p *progressReporter) toolFinished(evt ToolResultEvent) {
+	if !evt.IsError {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failed++
+}
+
+// textDelta records streamed reply text, keeping only its end, cut at a
+// rune boundary.
+func (p *progressReporter) textDelta(delta string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.text.WriteString(delta)
+
+	if p.text.Len() > 2*progressTextKeep {
+		s := p.text.String()
+
+		cut := len(s) - progressTextKeep
+		for cut < len(s) && !utf8.RuneStart(s[cut]) {
+			cut++
+		}
+
+		p.text.Reset()
+		p.text.WriteString(s[cut:])
+	}
+}
+
+// run posts an update every interval until ctx is done.
+func (p *progressReporter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.post(ctx)
+		}
+	}
+}
+
+func (p *progressReporter) post(ctx context.Context) {
+	text := p.render(false)
+
+	if p.editor != nil && p.messageID != "" {
+		if err := p.editor.EditMessage(ctx, p.conversationID, p.messageID, text); err != nil {
+			slog.Warn("failed to edit progress message", "conversation", p.conversationID, "error", err)
+		}
+
+		return
+	}
+
+	p.messageID = p.be.SendMessage(ctx, p.conversationID, text, "")
+}
+
+// finish edits the last update to say the turn is over, like
+// toolNotifier.done, so no stale "still working" stays above the reply.
+// Must not run concurrently with run. Updates on backends without edits
+// stay as they are.
+func (p *progressReporter) finish(ctx context.Context) {
+	if p.editor == nil || p.messageID == "" {
+		return
+	}
+
+	if err := p.editor.EditMessage(ctx, p.conversationID, p.messageID, p.render(true)); err != nil {
+		slog.Warn("failed to edit progress message", "conversation", p.conversationID, "error", err)
+	}
+}
+
+// discard redacts the progress message when the turn ends without a
+// reply, like toolNotifier.discard, so a silent turn leaves neither a
+// "done" line nor quoted model text behind. Must not run concurrently
+// with run. Updates on backends without redaction stay as they are.
+func (p *progressReporter) discard(ctx context.Context) {
+	if p.redactor == nil || p.messageID == "" {
+		return
+	}
+
+	if err := p.redactor.RedactMessage(ctx, p.conversationID, p.messageID); err != nil {
+		slog.Warn("failed to redact progress message", "conversation", p.conversationID, "error", err)
+	}
+}
+
+// render describes the turn so far; once final, only what it did.
+func (p *progressReporter) render(final bool) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := "⏳ still working · " + formatElapsed(time.Since(p.start))
+	if final {
+		s = "✓ done · " + formatElapsed(time.Since(p.start))
+	}
+
+	if p.calls > 0 {
+		tools := "1 tool"
+		if p.calls != 1 {
+			tools = fmt.Sprintf("%d tools", p.calls)
+		}
+
+		names := slices.Clone(p.names)
+		slices.SortStableFunc(names, func(x, y string) int { return cmp.Compare(p.counts[y], p.counts[x]) })
+
+		counts := make([]string, len(names))
+		for i, name := range names {
+			counts[i] = fmt.Sprintf("%d %s", p.counts[name], name)
+		}
+
+		s += fmt.Sprintf(": %s (%s)", tools, strings.Join(counts, ", "))
+
+		if p.failed > 0 {
+			s += fmt.Sprintf(", %d failed", p.failed)
+		}
+
+		if p.current != "" && !final {
+			s += ", now " + p.current
+		}
+	}
+
+	if final {
+		return s
+	}
+
+	if last := lastLine(p.text.String()); last != "" {
+		s += "\n\n“" + truncateRunes(last, maxProgressText) + "”"
+	}
+
+	return s
+}
+
+// lastLine returns the last non-blank line of text.
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+
+	return ""
+}
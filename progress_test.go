@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+func TestProgressReporter_Render(t *testing.T) {
+	t.Parallel()
+
+	p := newProgressReporter(&mockBackend{markdownFlavor: backend.MarkdownFull}, ToolsConfig{}, testRoom)
+
+	if got, want := p.render(false), `^⏳ still working · \S+$`; !regexp.MustCompile(want).MatchString(got) {
+		t.Errorf("render before any tools = %q, want match for %q", got, want)
+	}
+
+	p.toolStarted(ToolCallEvent{ID: "1", ToolName: "read", Args: map[string]any{"path": "a.go"}})
+	p.toolFinished(ToolResultEvent{ID: "1", ToolName: "read"})
+	p.toolStarted(ToolCallEvent{ID: "2", ToolName: "bash", Args: map[string]any{"command": "go test"}})
+	p.toolFinished(ToolResultEvent{ID: "2", ToolName: "bash", IsError: true})
+	p.toolStarted(ToolCallEvent{ID: "3", ToolName: "bash", Args: map[string]any{"command": "go test -run X"}})
+	p.textDelta("Running the tests.\n\nFixing 2 fail")
+	p.textDelta("ures…\n")
+
+	want := "^⏳ still working · \\S+: 3 tools \\(2 bash, 1 read\\), 1 failed, now bash `go test -run X`\n\n“Fixing 2 failures…”$"
+	if got := p.render(false); !regexp.MustCompile(want).MatchString(got) {
+		t.Errorf("render = %q, want match for %q", got, want)
+	}
+
+	want = "^✓ done · \\S+: 3 tools \\(2 bash, 1 read\\), 1 failed$"
+	if got := p.render(true); !regexp.MustCompile(want).MatchString(got) {
+		t.Errorf("final render = %q, want match for %q", got, want)
+	}
+}
+
+func TestProgressReporter_TrimsTextAtRuneBoundary(t *testing.T) {
+	t.Parallel()
+
+	p := newProgressReporter(&mockBackend{}, ToolsConfig{}, testRoom)
+
+	// An even byte offset puts the cut in the middle of a two-byte rune.
+	p.textDelta("xy" + strings.Repeat("ä", progressTextKeep))
+
+	if got := p.text.String(); !utf8.ValidString(got) {
+		t.Errorf("kept text is not valid UTF-8: %q…", got[:8])
+	}
+}
+
+func TestProgressReporter_EditsLastUpdate(t *testing.T) {
+	t.Parallel()
+
+	eb := &editingBackend{}
+	p := newProgressReporter(eb, ToolsConfig{}, testRoom)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		p.run(ctx, time.Millisecond)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		eb.mu.Lock()
+		edits := len(eb.edits)
+		eb.mu.Unlock()
+
+		if edits >= 2 || time.Now().After(deadline) {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	p.finish(context.Background())
+
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if len(eb.sentMessages) != 1 {
+		t.Errorf("sent %d progress messages, want 1", len(eb.sentMessages))
+	}
+
+	if len(eb.edits) < 2 {
+		t.Fatalf("progress message edited %d times, want at least 2", len(eb.edits))
+	}
+
+	for _, e := range eb.edits {
+		if e.messageID != "msg-1" {
+			t.Errorf("edited %q, want msg-1", e.messageID)
+		}
+	}
+
+	if last := eb.edits[len(eb.edits)-1].text; !strings.HasPrefix(last, "✓ done") {
+		t.Errorf("last edit = %q, want the finished state", last)
+	}
+}
@@ -10,6 +10,17 @@ import (
 	"database/sql"
 )
 
+const calendarEventFired = `-- name: CalendarEventFired :one
+SELECT count(*) FROM calendar_fired WHERE event_key = ?
+`
+
+func (q *Queries) CalendarEventFired(ctx context.Context, eventKey string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, calendarEventFired, eventKey)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countInbox = `-- name: CountInbox :one
 SELECT count(*) FROM inbox
 `
@@ -74,14 +85,17 @@ const dequeueInbox = `-- name: DequeueInbox :one
 DELETE FROM inbox
 WHERE id = (
     SELECT id FROM inbox
+    WHERE NOT (source = 'trigger' AND reminder_id = 0 AND waiter_id = '' AND created_at > ?)
     ORDER BY priority ASC, id ASC
     LIMIT 1
 )
-RETURNING id, priority, source, content, reply_to, created_at
+RETURNING id, priority, source, content, reply_to, created_at, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name
 `
 
-func (q *Queries) DequeueInbox(ctx context.Context) (Inbox, error) {
-	row := q.db.QueryRowContext(ctx, dequeueInbox)
+// Mergeable triggers (see DequeueMergeableTriggers) created after the
+// given time are held back, so a burst can land in one turn.
+func (q *Queries) DequeueInbox(ctx context.Context, createdAt string) (Inbox, error) {
+	row := q.db.QueryRowContext(ctx, dequeueInbox, createdAt)
 	var i Inbox
 	err := row.Scan(
 		&i.ID,
@@ -90,14 +104,74 @@ func (q *Queries) DequeueInbox(ctx context.Context) (Inbox, error) {
 		&i.Content,
 		&i.ReplyTo,
 		&i.CreatedAt,
+		&i.ReminderID,
+		&i.Label,
+		&i.DedupKey,
+		&i.Silent,
+		&i.ConversationID,
+		&i.WaiterID,
+		&i.Sender,
+		&i.ConversationName,
 	)
 	return i, err
 }
 
+const dequeueMergeableTriggers = `-- name: DequeueMergeableTriggers :many
+DELETE FROM inbox
+WHERE source = 'trigger' AND reminder_id = 0 AND waiter_id = '' AND conversation_id = ? AND label = ?
+RETURNING id, priority, source, content, reply_to, created_at, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name
+`
+
+type DequeueMergeableTriggersParams struct {
+	ConversationID string
+	Label          string
+}
+
+// Triggers without their own reply lifecycle (reminder delivery or
+// synchronous caller) from the same source, bound for the same
+// conversation.
+func (q *Queries) DequeueMergeableTriggers(ctx context.Context, arg DequeueMergeableTriggersParams) ([]Inbox, error) {
+	rows, err := q.db.QueryContext(ctx, dequeueMergeableTriggers, arg.ConversationID, arg.Label)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Inbox
+	for rows.Next() {
+		var i Inbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.Priority,
+			&i.Source,
+			&i.Content,
+			&i.ReplyTo,
+			&i.CreatedAt,
+			&i.ReminderID,
+			&i.Label,
+			&i.DedupKey,
+			&i.Silent,
+			&i.ConversationID,
+			&i.WaiterID,
+			&i.Sender,
+			&i.ConversationName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const dequeueUserItems = `-- name: DequeueUserItems :many
 DELETE FROM inbox
 WHERE source = 'user'
-RETURNING id, priority, source, content, reply_to, created_at
+RETURNING id, priority, source, content, reply_to, created_at, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name
 `
 
 func (q *Queries) DequeueUserItems(ctx context.Context) ([]Inbox, error) {
@@ -116,6 +190,14 @@ func (q *Queries) DequeueUserItems(ctx context.Context) ([]Inbox, error) {
 			&i.Content,
 			&i.ReplyTo,
 			&i.CreatedAt,
+			&i.ReminderID,
+			&i.Label,
+			&i.DedupKey,
+			&i.Silent,
+			&i.ConversationID,
+			&i.WaiterID,
+			&i.Sender,
+			&i.ConversationName,
 		); err != nil {
 			return nil, err
 		}
@@ -133,7 +215,7 @@ func (q *Queries) DequeueUserItems(ctx context.Context) ([]Inbox, error) {
 const dueReminders = `-- name: DueReminders :many
 DELETE FROM reminders
 WHERE datetime(fire_at) <= datetime(?)
-RETURNING id, fire_at, prompt
+RETURNING id, fire_at, prompt, important
 `
 
 // datetime() normalizes ISO 8601 variants (Z vs +00:00, T vs space) so
@@ -147,7 +229,47 @@ func (q *Queries) DueReminders(ctx context.Context, datetime interface{}) ([]Rem
 	var items []Reminders
 	for rows.Next() {
 		var i Reminders
-		if err := rows.Scan(&i.ID, &i.FireAt, &i.Prompt); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.FireAt,
+			&i.Prompt,
+			&i.Important,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const dueScheduledMessages = `-- name: DueScheduledMessages :many
+DELETE FROM scheduled_messages
+WHERE datetime(send_at) <= datetime(?)
+RETURNING id, send_at, text, files, conversation_id
+`
+
+func (q *Queries) DueScheduledMessages(ctx context.Context, datetime interface{}) ([]ScheduledMessages, error) {
+	rows, err := q.db.QueryContext(ctx, dueScheduledMessages, datetime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ScheduledMessages
+	for rows.Next() {
+		var i ScheduledMessages
+		if err := rows.Scan(
+			&i.ID,
+			&i.SendAt,
+			&i.Text,
+			&i.Files,
+			&i.ConversationID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -172,15 +294,23 @@ func (q *Queries) EnqueueHeartbeatIfEmpty(ctx context.Context, priority int64) (
 }
 
 const enqueueInbox = `-- name: EnqueueInbox :exec
-INSERT INTO inbox (priority, source, content, reply_to)
-VALUES (?, ?, ?, ?)
+INSERT INTO inbox (priority, source, content, reply_to, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 type EnqueueInboxParams struct {
-	Priority int64
-	Source   string
-	Content  string
-	ReplyTo  string
+	Priority         int64
+	Source           string
+	Content          string
+	ReplyTo          string
+	ReminderID       int64
+	Label            string
+	DedupKey         string
+	Silent           int64
+	ConversationID   string
+	WaiterID         string
+	Sender           string
+	ConversationName string
 }
 
 func (q *Queries) EnqueueInbox(ctx context.Context, arg EnqueueInboxParams) error {
@@ -189,10 +319,106 @@ func (q *Queries) EnqueueInbox(ctx context.Context, arg EnqueueInboxParams) erro
 		arg.Source,
 		arg.Content,
 		arg.ReplyTo,
+		arg.ReminderID,
+		arg.Label,
+		arg.DedupKey,
+		arg.Silent,
+		arg.ConversationID,
+		arg.WaiterID,
+		arg.Sender,
+		arg.ConversationName,
 	)
 	return err
 }
 
+const enqueueInboxUnlessQueued = `-- name: EnqueueInboxUnlessQueued :one
+INSERT INTO inbox (priority, source, content, reply_to, label, dedup_key, silent, conversation_id, waiter_id)
+SELECT ?1, ?2, ?3, ?4, ?5,
+       ?6, ?7, ?8, ?9
+WHERE NOT EXISTS (SELECT 1 FROM inbox WHERE dedup_key != '' AND dedup_key = ?6)
+RETURNING id
+`
+
+type EnqueueInboxUnlessQueuedParams struct {
+	Priority       int64
+	Source         string
+	Content        string
+	ReplyTo        string
+	Label          string
+	DedupKey       string
+	Silent         int64
+	ConversationID string
+	WaiterID       string
+}
+
+// Skips the insert (no row returned) if an item with the same non-empty
+// dedup_key is queued.
+func (q *Queries) EnqueueInboxUnlessQueued(ctx context.Context, arg EnqueueInboxUnlessQueuedParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, enqueueInboxUnlessQueued,
+		arg.Priority,
+		arg.Source,
+		arg.Content,
+		arg.ReplyTo,
+		arg.Label,
+		arg.DedupKey,
+		arg.Silent,
+		arg.ConversationID,
+		arg.WaiterID,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getConversationSettings = `-- name: GetConversationSettings :one
+SELECT conversation_id, tool_calls, thinking, debug_timing
+FROM conversation_settings
+WHERE conversation_id = ?
+`
+
+func (q *Queries) GetConversationSettings(ctx context.Context, conversationID string) (ConversationSettings, error) {
+	row := q.db.QueryRowContext(ctx, getConversationSettings, conversationID)
+	var i ConversationSettings
+	err := row.Scan(
+		&i.ConversationID,
+		&i.ToolCalls,
+		&i.Thinking,
+		&i.DebugTiming,
+	)
+	return i, err
+}
+
+const getMessage = `-- name: GetMessage :one
+SELECT id, conversation_id, direction, sender, message_id, reply_to, source, text, attachments, created_at
+FROM messages
+WHERE conversation_id = ? AND message_id = ?
+ORDER BY id DESC
+LIMIT 1
+`
+
+type GetMessageParams struct {
+	ConversationID string
+	MessageID      string
+}
+
+func (q *Queries) GetMessage(ctx context.Context, arg GetMessageParams) (Messages, error) {
+	row := q.db.QueryRowContext(ctx, getMessage, arg.ConversationID, arg.MessageID)
+	var i Messages
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.Direction,
+		&i.Sender,
+		&i.MessageID,
+		&i.ReplyTo,
+		&i.Source,
+		&i.Text,
+		&i.Attachments,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getOutbox = `-- name: GetOutbox :one
 SELECT text FROM sent_messages
 WHERE conversation_id = ? AND message_id = ?
@@ -210,22 +436,268 @@ func (q *Queries) GetOutbox(ctx context.Context, arg GetOutboxParams) (string, e
 	return text, err
 }
 
+const getReminderDelivery = `-- name: GetReminderDelivery :one
+SELECT id, fire_at, prompt, important, refires, state, conversation_id, message_id, delivered_at
+FROM reminder_deliveries
+WHERE conversation_id = ? AND message_id = ?
+`
+
+type GetReminderDeliveryParams struct {
+	ConversationID string
+	MessageID      string
+}
+
+func (q *Queries) GetReminderDelivery(ctx context.Context, arg GetReminderDeliveryParams) (ReminderDeliveries, error) {
+	row := q.db.QueryRowContext(ctx, getReminderDelivery, arg.ConversationID, arg.MessageID)
+	var i ReminderDeliveries
+	err := row.Scan(
+		&i.ID,
+		&i.FireAt,
+		&i.Prompt,
+		&i.Important,
+		&i.Refires,
+		&i.State,
+		&i.ConversationID,
+		&i.MessageID,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const insertMessage = `-- name: InsertMessage :exec
+INSERT INTO messages (conversation_id, direction, sender, message_id, reply_to, source, text, attachments, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type InsertMessageParams struct {
+	ConversationID string
+	Direction      string
+	Sender         string
+	MessageID      string
+	ReplyTo        string
+	Source         string
+	Text           string
+	Attachments    string
+	CreatedAt      string
+}
+
+func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) error {
+	_, err := q.db.ExecContext(ctx, insertMessage,
+		arg.ConversationID,
+		arg.Direction,
+		arg.Sender,
+		arg.MessageID,
+		arg.ReplyTo,
+		arg.Source,
+		arg.Text,
+		arg.Attachments,
+		arg.CreatedAt,
+	)
+	return err
+}
+
 const insertReminder = `-- name: InsertReminder :exec
-INSERT INTO reminders (fire_at, prompt) VALUES (?, ?)
+INSERT INTO reminders (fire_at, prompt, important) VALUES (?, ?, ?)
 `
 
 type InsertReminderParams struct {
-	FireAt string
-	Prompt string
+	FireAt    string
+	Prompt    string
+	Important int64
 }
 
 func (q *Queries) InsertReminder(ctx context.Context, arg InsertReminderParams) error {
-	_, err := q.db.ExecContext(ctx, insertReminder, arg.FireAt, arg.Prompt)
+	_, err := q.db.ExecContext(ctx, insertReminder, arg.FireAt, arg.Prompt, arg.Important)
+	return err
+}
+
+const insertReminderDelivery = `-- name: InsertReminderDelivery :one
+INSERT INTO reminder_deliveries (fire_at, prompt, important, refires)
+VALUES (?, ?, ?, ?)
+RETURNING id
+`
+
+type InsertReminderDeliveryParams struct {
+	FireAt    string
+	Prompt    string
+	Important int64
+	Refires   int64
+}
+
+func (q *Queries) InsertReminderDelivery(ctx context.Context, arg InsertReminderDeliveryParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertReminderDelivery,
+		arg.FireAt,
+		arg.Prompt,
+		arg.Important,
+		arg.Refires,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertScheduledMessage = `-- name: InsertScheduledMessage :exec
+INSERT INTO scheduled_messages (send_at, text, files, conversation_id)
+VALUES (?, ?, ?, ?)
+`
+
+type InsertScheduledMessageParams struct {
+	SendAt         string
+	Text           string
+	Files          string
+	ConversationID string
+}
+
+func (q *Queries) InsertScheduledMessage(ctx context.Context, arg InsertScheduledMessageParams) error {
+	_, err := q.db.ExecContext(ctx, insertScheduledMessage,
+		arg.SendAt,
+		arg.Text,
+		arg.Files,
+		arg.ConversationID,
+	)
+	return err
+}
+
+const listCronRuns = `-- name: ListCronRuns :many
+SELECT name, last_run FROM cron_runs
+`
+
+func (q *Queries) ListCronRuns(ctx context.Context) ([]CronRuns, error) {
+	rows, err := q.db.QueryContext(ctx, listCronRuns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CronRuns
+	for rows.Next() {
+		var i CronRuns
+		if err := rows.Scan(&i.Name, &i.LastRun); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeedEntries = `-- name: ListFeedEntries :many
+SELECT guid FROM feed_entries WHERE feed = ?
+`
+
+func (q *Queries) ListFeedEntries(ctx context.Context, feed string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedEntries, feed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var guid string
+		if err := rows.Scan(&guid); err != nil {
+			return nil, err
+		}
+		items = append(items, guid)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWatchedFiles = `-- name: ListWatchedFiles :many
+SELECT rule, path, mod_time, size FROM watched_files WHERE rule = ?
+`
+
+func (q *Queries) ListWatchedFiles(ctx context.Context, rule string) ([]WatchedFiles, error) {
+	rows, err := q.db.QueryContext(ctx, listWatchedFiles, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WatchedFiles
+	for rows.Next() {
+		var i WatchedFiles
+		if err := rows.Scan(
+			&i.Rule,
+			&i.Path,
+			&i.ModTime,
+			&i.Size,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markReminderDelivered = `-- name: MarkReminderDelivered :exec
+UPDATE reminder_deliveries
+SET state = 'delivered', conversation_id = ?, message_id = ?, delivered_at = ?
+WHERE id = ? AND state = 'pending'
+`
+
+type MarkReminderDeliveredParams struct {
+	ConversationID string
+	MessageID      string
+	DeliveredAt    string
+	ID             int64
+}
+
+func (q *Queries) MarkReminderDelivered(ctx context.Context, arg MarkReminderDeliveredParams) error {
+	_, err := q.db.ExecContext(ctx, markReminderDelivered,
+		arg.ConversationID,
+		arg.MessageID,
+		arg.DeliveredAt,
+		arg.ID,
+	)
 	return err
 }
 
+const markWatchRuleSeeded = `-- name: MarkWatchRuleSeeded :exec
+INSERT INTO watch_rules (rule, seeded_at) VALUES (?, ?)
+ON CONFLICT(rule) DO NOTHING
+`
+
+type MarkWatchRuleSeededParams struct {
+	Rule     string
+	SeededAt string
+}
+
+func (q *Queries) MarkWatchRuleSeeded(ctx context.Context, arg MarkWatchRuleSeededParams) error {
+	_, err := q.db.ExecContext(ctx, markWatchRuleSeeded, arg.Rule, arg.SeededAt)
+	return err
+}
+
+const oldestMergeableTrigger = `-- name: OldestMergeableTrigger :one
+SELECT created_at FROM inbox
+WHERE source = 'trigger' AND reminder_id = 0 AND waiter_id = ''
+ORDER BY created_at ASC
+LIMIT 1
+`
+
+func (q *Queries) OldestMergeableTrigger(ctx context.Context) (string, error) {
+	row := q.db.QueryRowContext(ctx, oldestMergeableTrigger)
+	var created_at string
+	err := row.Scan(&created_at)
+	return created_at, err
+}
+
 const peekInbox = `-- name: PeekInbox :one
-SELECT id, priority, source, content, reply_to, created_at
+SELECT id, priority, source, content, reply_to, created_at, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name
 FROM inbox
 ORDER BY priority ASC, id ASC
 LIMIT 1
@@ -241,10 +713,224 @@ func (q *Queries) PeekInbox(ctx context.Context) (Inbox, error) {
 		&i.Content,
 		&i.ReplyTo,
 		&i.CreatedAt,
+		&i.ReminderID,
+		&i.Label,
+		&i.DedupKey,
+		&i.Silent,
+		&i.ConversationID,
+		&i.WaiterID,
+		&i.Sender,
+		&i.ConversationName,
 	)
 	return i, err
 }
 
+const pruneCalendarFired = `-- name: PruneCalendarFired :exec
+DELETE FROM calendar_fired WHERE datetime(fired_at) < datetime(?)
+`
+
+func (q *Queries) PruneCalendarFired(ctx context.Context, datetime interface{}) error {
+	_, err := q.db.ExecContext(ctx, pruneCalendarFired, datetime)
+	return err
+}
+
+const pruneFeedEntries = `-- name: PruneFeedEntries :exec
+DELETE FROM feed_entries WHERE datetime(seen_at) < datetime(?)
+`
+
+func (q *Queries) PruneFeedEntries(ctx context.Context, datetime interface{}) error {
+	_, err := q.db.ExecContext(ctx, pruneFeedEntries, datetime)
+	return err
+}
+
+const pruneReminderDeliveries = `-- name: PruneReminderDeliveries :exec
+DELETE FROM reminder_deliveries WHERE datetime(fire_at) < datetime(?)
+`
+
+func (q *Queries) PruneReminderDeliveries(ctx context.Context, datetime interface{}) error {
+	_, err := q.db.ExecContext(ctx, pruneReminderDeliveries, datetime)
+	return err
+}
+
+const pruneTriggerHistory = `-- name: PruneTriggerHistory :exec
+DELETE FROM trigger_history WHERE datetime(seen_at) < datetime(?)
+`
+
+func (q *Queries) PruneTriggerHistory(ctx context.Context, datetime interface{}) error {
+	_, err := q.db.ExecContext(ctx, pruneTriggerHistory, datetime)
+	return err
+}
+
+const recordCalendarFired = `-- name: RecordCalendarFired :exec
+INSERT OR IGNORE INTO calendar_fired (event_key, fired_at) VALUES (?, ?)
+`
+
+type RecordCalendarFiredParams struct {
+	EventKey string
+	FiredAt  string
+}
+
+func (q *Queries) RecordCalendarFired(ctx context.Context, arg RecordCalendarFiredParams) error {
+	_, err := q.db.ExecContext(ctx, recordCalendarFired, arg.EventKey, arg.FiredAt)
+	return err
+}
+
+const recordTriggerSeen = `-- name: RecordTriggerSeen :exec
+INSERT INTO trigger_history (dedup_key, seen_at) VALUES (?, ?)
+ON CONFLICT(dedup_key) DO UPDATE SET seen_at = excluded.seen_at
+`
+
+type RecordTriggerSeenParams struct {
+	DedupKey string
+	SeenAt   string
+}
+
+func (q *Queries) RecordTriggerSeen(ctx context.Context, arg RecordTriggerSeenParams) error {
+	_, err := q.db.ExecContext(ctx, recordTriggerSeen, arg.DedupKey, arg.SeenAt)
+	return err
+}
+
+const refireReminderDeliveries = `-- name: RefireReminderDeliveries :many
+UPDATE reminder_deliveries SET state = 'refired'
+WHERE state = 'delivered' AND important = 1 AND refires < ?
+  AND datetime(delivered_at) <= datetime(?)
+RETURNING id, fire_at, prompt, important, refires, state, conversation_id, message_id, delivered_at
+`
+
+type RefireReminderDeliveriesParams struct {
+	Refires  int64
+	Datetime interface{}
+}
+
+func (q *Queries) RefireReminderDeliveries(ctx context.Context, arg RefireReminderDeliveriesParams) ([]ReminderDeliveries, error) {
+	rows, err := q.db.QueryContext(ctx, refireReminderDeliveries, arg.Refires, arg.Datetime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReminderDeliveries
+	for rows.Next() {
+		var i ReminderDeliveries
+		if err := rows.Scan(
+			&i.ID,
+			&i.FireAt,
+			&i.Prompt,
+			&i.Important,
+			&i.Refires,
+			&i.State,
+			&i.ConversationID,
+			&i.MessageID,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const settleReminderDeliveries = `-- name: SettleReminderDeliveries :execrows
+UPDATE reminder_deliveries SET state = ?
+WHERE fire_at = ? AND prompt = ? AND state NOT IN ('acked', 'snoozed')
+`
+
+type SettleReminderDeliveriesParams struct {
+	State  string
+	FireAt string
+	Prompt string
+}
+
+// Settles every delivery of one reminder (the original plus any re-fires)
+// so acknowledging an older message also stops later re-fires. Settled
+// deliveries are left alone, so a second reply changes nothing.
+func (q *Queries) SettleReminderDeliveries(ctx context.Context, arg SettleReminderDeliveriesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, settleReminderDeliveries, arg.State, arg.FireAt, arg.Prompt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const triggerSeenSince = `-- name: TriggerSeenSince :one
+SELECT count(*) FROM trigger_history
+WHERE dedup_key = ? AND datetime(seen_at) >= datetime(?)
+`
+
+type TriggerSeenSinceParams struct {
+	DedupKey string
+	Datetime interface{}
+}
+
+func (q *Queries) TriggerSeenSince(ctx context.Context, arg TriggerSeenSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, triggerSeenSince, arg.DedupKey, arg.Datetime)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const upsertConversationSettings = `-- name: UpsertConversationSettings :exec
+INSERT INTO conversation_settings (conversation_id, tool_calls, thinking, debug_timing)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(conversation_id) DO UPDATE SET
+    tool_calls = excluded.tool_calls,
+    thinking = excluded.thinking,
+    debug_timing = excluded.debug_timing
+`
+
+type UpsertConversationSettingsParams struct {
+	ConversationID string
+	ToolCalls      int64
+	Thinking       int64
+	DebugTiming    int64
+}
+
+func (q *Queries) UpsertConversationSettings(ctx context.Context, arg UpsertConversationSettingsParams) error {
+	_, err := q.db.ExecContext(ctx, upsertConversationSettings,
+		arg.ConversationID,
+		arg.ToolCalls,
+		arg.Thinking,
+		arg.DebugTiming,
+	)
+	return err
+}
+
+const upsertCronRun = `-- name: UpsertCronRun :exec
+INSERT INTO cron_runs (name, last_run) VALUES (?, ?)
+ON CONFLICT(name) DO UPDATE SET last_run = excluded.last_run
+`
+
+type UpsertCronRunParams struct {
+	Name    string
+	LastRun string
+}
+
+func (q *Queries) UpsertCronRun(ctx context.Context, arg UpsertCronRunParams) error {
+	_, err := q.db.ExecContext(ctx, upsertCronRun, arg.Name, arg.LastRun)
+	return err
+}
+
+const upsertFeedEntry = `-- name: UpsertFeedEntry :exec
+INSERT INTO feed_entries (feed, guid, seen_at) VALUES (?, ?, ?)
+ON CONFLICT(feed, guid) DO UPDATE SET seen_at = excluded.seen_at
+`
+
+type UpsertFeedEntryParams struct {
+	Feed   string
+	Guid   string
+	SeenAt string
+}
+
+func (q *Queries) UpsertFeedEntry(ctx context.Context, arg UpsertFeedEntryParams) error {
+	_, err := q.db.ExecContext(ctx, upsertFeedEntry, arg.Feed, arg.Guid, arg.SeenAt)
+	return err
+}
+
 const upsertOutbox = `-- name: UpsertOutbox :exec
 INSERT INTO sent_messages (conversation_id, message_id, text)
 VALUES (?, ?, ?)
@@ -261,3 +947,36 @@ func (q *Queries) UpsertOutbox(ctx context.Context, arg UpsertOutboxParams) erro
 	_, err := q.db.ExecContext(ctx, upsertOutbox, arg.ConversationID, arg.MessageID, arg.Text)
 	return err
 }
+
+const upsertWatchedFile = `-- name: UpsertWatchedFile :exec
+INSERT INTO watched_files (rule, path, mod_time, size) VALUES (?, ?, ?, ?)
+ON CONFLICT(rule, path) DO UPDATE SET mod_time = excluded.mod_time, size = excluded.size
+`
+
+type UpsertWatchedFileParams struct {
+	Rule    string
+	Path    string
+	ModTime string
+	Size    int64
+}
+
+func (q *Queries) UpsertWatchedFile(ctx context.Context, arg UpsertWatchedFileParams) error {
+	_, err := q.db.ExecContext(ctx, upsertWatchedFile,
+		arg.Rule,
+		arg.Path,
+		arg.ModTime,
+		arg.Size,
+	)
+	return err
+}
+
+const watchRuleSeeded = `-- name: WatchRuleSeeded :one
+SELECT count(*) FROM watch_rules WHERE rule = ?
+`
+
+func (q *Queries) WatchRuleSeeded(ctx context.Context, rule string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, watchRuleSeeded, rule)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// Reminder delivery states set from Go. 'pending', 'delivered' and
+// 'refired' are only ever written by the queries themselves.
+const (
+	reminderAcked   = "acked"
+	reminderSnoozed = "snoozed"
+)
+
+const (
+	// defaultSnooze applies when the user replies a bare "snooze".
+	defaultSnooze = 10 * time.Minute
+	// defaultSnoozeHour is the time of day for "tomorrow" without a time.
+	defaultSnoozeHour = 9
+	// reminderDeliveryRetention bounds how long fired reminders stay
+	// snoozable. Older deliveries are pruned on the reminder tick.
+	reminderDeliveryRetention = 30 * 24 * time.Hour
+)
+
+// enqueueReminder records a delivery row for a fired reminder and enqueues
+// it as a trigger. A failed delivery insert only costs the reply lifecycle,
+// so the reminder is still enqueued unlinked rather than dropped.
+func enqueueReminder(ctx context.Context, w *Worker, fireAt, prompt string, important, refires int64, content string) error {
+	deliveryID, err := w.inbox.queries.InsertReminderDelivery(ctx, InsertReminderDeliveryParams{
+		FireAt:    fireAt,
+		Prompt:    prompt,
+		Important: important,
+		Refires:   refires,
+	})
+	if err != nil {
+		slog.Warn("reminder: failed to record delivery, snooze replies won't work", "error", err)
+
+		deliveryID = 0
+	}
+
+	return w.inbox.EnqueueReminder(ctx, content, deliveryID)
+}
+
+// maintainReminderDeliveries re-fires important reminders that were
+// delivered but neither acknowledged nor snoozed within cfg.ReminderRefire,
+// and prunes deliveries past reminderDeliveryRetention.
+func maintainReminderDeliveries(ctx context.Context, w *Worker, cfg HeartbeatConfig) {
+	now := time.Now().UTC()
+
+	if err := w.inbox.queries.PruneReminderDeliveries(ctx, now.Add(-reminderDeliveryRetention).Format(time.RFC3339)); err != nil {
+		slog.Warn("reminder: failed to prune deliveries", "error", err)
+	}
+
+	if cfg.ReminderRefire <= 0 {
+		return
+	}
+
+	stale, err := w.inbox.queries.RefireReminderDeliveries(ctx, RefireReminderDeliveriesParams{
+		Refires:  int64(cfg.ReminderMaxRefires),
+		Datetime: now.Add(-cfg.ReminderRefire).Format(time.RFC3339),
+	})
+	if err != nil {
+		slog.Error("reminder: failed to query unacknowledged reminders", "error", err)
+
+		return
+	}
+
+	for _, d := range stale {
+		slog.Info("reminder: re-firing unacknowledged", "id", d.ID, "refires", d.Refires+1)
+
+		content := fmt.Sprintf("Reminder (set for %s, still unacknowledged — repeat %d of %d): %s\n"+
+			`The user can reply "done" to acknowledge it or "snooze 1h" to postpone it.`,
+			d.FireAt, d.Refires+1, cfg.ReminderMaxRefires, d.Prompt)
+
+		if err := enqueueReminder(ctx, w, d.FireAt, d.Prompt, d.Important, d.Refires+1, content); err != nil {
+			slog.Error("reminder: failed to enqueue re-fire", "id", d.ID, "error", err)
+
+			continue
+		}
+
+		w.Notify(PriorityTrigger)
+	}
+}
+
+// reminderAction is what a reply to a delivered reminder asks for: either
+// acknowledge it, or snooze it until a given time.
+type reminderAction struct {
+	ack   bool
+	until time.Time
+}
+
+// parseReminderReply recognises the short replies users send to a
+// delivered reminder: "done"/"ok" to acknowledge, and "snooze 1h",
+// "in 30m", "2d", "tomorrow 9", "tomorrow 9:30am" or "at 17:00" to
+// postpone it. now fixes both the reference time and the timezone used for
+// clock times. Returns false for anything else, so ordinary replies still
+// reach the agent.
+func parseReminderReply(text string, now time.Time) (reminderAction, bool) {
+	s := strings.ToLower(strings.TrimSpace(text))
+	s = strings.TrimRight(s, ".! ")
+
+	switch s {
+	case "done", "ok", "okay", "ack", "acknowledged", "dismiss", "got it", "👍", "✅":
+		return reminderAction{ack: true}, true
+	}
+
+	rest, snooze := strings.CutPrefix(s, "snooze")
+	rest = strings.TrimSpace(rest)
+
+	if snooze && rest == "" {
+		return reminderAction{until: now.Add(defaultSnooze)}, true
+	}
+
+	for _, p := range []string{"for ", "in ", "until "} {
+		rest = strings.TrimPrefix(rest, p)
+	}
+
+	if until, ok := parseSnoozeTime(rest, now); ok {
+		return reminderAction{until: until}, true
+	}
+
+	return reminderAction{}, false
+}
+
+// parseSnoozeTime handles the time part of a snooze reply.
+func parseSnoozeTime(s string, now time.Time) (time.Time, bool) {
+	if d, ok := parseSnoozeDuration(s); ok {
+		return now.Add(d), true
+	}
+
+	if rest, ok := strings.CutPrefix(s, "tomorrow"); ok {
+		hour, minute := defaultSnoozeHour, 0
+
+		if rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "at")); rest != "" {
+			if hour, minute, ok = parseClock(rest); !ok {
+				return time.Time{}, false
+			}
+		}
+
+		y, m, d := now.AddDate(0, 0, 1).Date()
+
+		return time.Date(y, m, d, hour, minute, 0, 0, now.Location()), true
+	}
+
+	if rest, ok := strings.CutPrefix(s, "at "); ok {
+		hour, minute, ok := parseClock(rest)
+		if !ok {
+			return time.Time{}, false
+		}
+
+		y, m, d := now.Date()
+
+		t := time.Date(y, m, d, hour, minute, 0, 0, now.Location())
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
+// parseSnoozeDuration accepts Go durations ("1h30m") plus whole days ("2d").
+func parseSnoozeDuration(s string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+
+	d, err := time.ParseDuration(strings.ReplaceAll(s, " ", ""))
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+
+	return d, true
+}
+
+// settledState names a settled delivery state for the user.
+func settledState(state string) string {
+	if state == reminderSnoozed {
+		return "snoozed"
+	}
+
+	return "acknowledged"
+}
+
+// parseClock parses "9", "09:30", "9am", "5:15pm" into hour and minute.
+func parseClock(s string) (int, int, bool) {
+	s = strings.ReplaceAll(s, " ", "")
+
+	meridiem := ""
+
+	switch {
+	case strings.HasSuffix(s, "am"):
+		s, meridiem = strings.TrimSuffix(s, "am"), "am"
+	case strings.HasSuffix(s, "pm"):
+		s, meridiem = strings.TrimSuffix(s, "pm"), "pm"
+	}
+
+	hs, ms, hasMinutes := strings.Cut(s, ":")
+
+	hour, err := strconv.Atoi(hs)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	minute := 0
+	if hasMinutes {
+		if minute, err = strconv.Atoi(ms); err != nil {
+			return 0, 0, false
+		}
+	}
+
+	if meridiem != "" && (hour < 1 || hour > 12) {
+		return 0, 0, false
+	}
+
+	switch {
+	case meridiem == "am" && hour == 12:
+		hour = 0 // 12am is midnight
+	case meridiem == "pm" && hour < 12:
+		hour += 12
+	}
+
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, 0, false
+	}
+
+	return hour, minute, true
+}
+
+// handleReminderReply snoozes or acknowledges a reminder when msg replies
+// to the message that delivered it with a recognised short command. These
+// are handled natively so they never cost an agent turn. Returns false if
+// msg is not such a reply and should be processed normally.
+func (a *App) handleReminderReply(ctx context.Context, msg backend.Message) bool {
+	if msg.ReplyToID == "" {
+		return false
+	}
+
+	d, ok := a.outbox.ReminderDelivery(ctx, msg.ConversationID, msg.ReplyToID)
+	if !ok {
+		return false
+	}
+
+	action, ok := parseReminderReply(msg.Text, a.worker.promptCfg.now())
+	if !ok {
+		return false
+	}
+
+	state := reminderAcked
+	if !action.ack {
+		state = reminderSnoozed
+	}
+
+	settled, err := a.inbox.queries.SettleReminderDeliveries(ctx, SettleReminderDeliveriesParams{
+		State:  state,
+		FireAt: d.FireAt,
+		Prompt: d.Prompt,
+	})
+	if err != nil {
+		slog.Error("reminder: failed to settle delivery", "id", d.ID, "error", err)
+		a.backend.SendMessage(ctx, msg.ConversationID, fmt.Sprintf("Error: %v", err), "")
+
+		return true
+	}
+
+	if settled == 0 {
+		// An earlier reply already acknowledged or snoozed it; snoozing
+		// again would schedule a duplicate.
+		a.backend.SendMessage(ctx, msg.ConversationID, "This reminder was already "+settledState(d.State)+".", "")
+
+		return true
+	}
+
+	if action.ack {
+		slog.Info("reminder: acknowledged", "id", d.ID)
+		a.backend.SendMessage(ctx, msg.ConversationID, "✅ Reminder acknowledged.", "")
+
+		return true
+	}
+
+	if err := a.inbox.queries.InsertReminder(ctx, InsertReminderParams{
+		FireAt:    action.until.UTC().Format(time.RFC3339),
+		Prompt:    d.Prompt,
+		Important: d.Important,
+	}); err != nil {
+		slog.Error("reminder: failed to snooze", "id", d.ID, "error", err)
+		a.backend.SendMessage(ctx, msg.ConversationID, fmt.Sprintf("Snooze failed: %v", err), "")
+
+		return true
+	}
+
+	slog.Info("reminder: snoozed", "id", d.ID, "until", action.until)
+	a.backend.SendMessage(ctx, msg.ConversationID,
+		fmt.Sprintf("⏰ Snoozed until %s.", action.until.Format("Mon Jan 2 15:04")), "")
+
+	return true
+}
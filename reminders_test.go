@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+func TestParseReminderReply(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("CEST", 2*60*60)
+	now := time.Date(2025, 6, 15, 14, 0, 0, 0, loc)
+
+	cases := []struct {
+		text  string
+		ok    bool
+		ack   bool
+		until time.Time
+	}{
+		{"done", true, true, time.Time{}},
+		{"  OK! ", true, true, time.Time{}},
+		{"snooze", true, false, now.Add(defaultSnooze)},
+		{"snooze 1h", true, false, now.Add(time.Hour)},
+		{"Snooze for 1h30m", true, false, now.Add(90 * time.Minute)},
+		{"in 30m", true, false, now.Add(30 * time.Minute)},
+		{"2d", true, false, now.Add(48 * time.Hour)},
+		{"tomorrow", true, false, time.Date(2025, 6, 16, 9, 0, 0, 0, loc)},
+		{"tomorrow 9", true, false, time.Date(2025, 6, 16, 9, 0, 0, 0, loc)},
+		{"snooze until tomorrow 5:15pm", true, false, time.Date(2025, 6, 16, 17, 15, 0, 0, loc)},
+		{"at 17:00", true, false, time.Date(2025, 6, 15, 17, 0, 0, 0, loc)},
+		{"at 9", true, false, time.Date(2025, 6, 16, 9, 0, 0, 0, loc)},
+		{"tomorrow 12am", true, false, time.Date(2025, 6, 16, 0, 0, 0, 0, loc)},
+		{"tomorrow 12:30pm", true, false, time.Date(2025, 6, 16, 12, 30, 0, 0, loc)},
+		{"tomorrow 13pm", false, false, time.Time{}},
+		{"tomorrow 25", false, false, time.Time{}},
+		{"what was this about?", false, false, time.Time{}},
+		{"snoozefest", false, false, time.Time{}},
+	}
+
+	for _, tc := range cases {
+		got, ok := parseReminderReply(tc.text, now)
+		if ok != tc.ok {
+			t.Errorf("parseReminderReply(%q) ok = %v, want %v", tc.text, ok, tc.ok)
+
+			continue
+		}
+
+		if got.ack != tc.ack || !got.until.Equal(tc.until) {
+			t.Errorf("parseReminderReply(%q) = %+v, want ack=%v until=%v", tc.text, got, tc.ack, tc.until)
+		}
+	}
+}
+
+// deliverTestReminder fires a reminder through the same path as the
+// dispatcher and records it as delivered by messageID.
+func deliverTestReminder(ctx context.Context, t *testing.T, app *App, important int64, messageID string) Inbox {
+	t.Helper()
+
+	fireAt := time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339)
+	must(t, enqueueReminder(ctx, app.worker, fireAt, "water the plants", important, 0, "Reminder: water the plants"))
+
+	item, err := app.inbox.Dequeue(ctx)
+	must(t, err)
+
+	app.outbox.RecordReminderDelivery(ctx, item.ReminderID, testRoom, messageID)
+
+	return item
+}
+
+func TestApp_ReminderReplySnoozes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, mb := newTestApp(t)
+
+	deliverTestReminder(ctx, t, app, 1, "bot-msg-1")
+
+	// The second snooze must not schedule a duplicate.
+	for _, id := range []string{"user-msg-1", "user-msg-2"} {
+		app.HandleMessage(ctx, backend.Message{
+			ConversationID: testRoom,
+			SenderID:       "@user:example.com",
+			Text:           "snooze 1h",
+			MessageID:      id,
+			ReplyToID:      "bot-msg-1",
+		})
+	}
+
+	// Handled natively: no agent turn queued.
+	if n, _ := app.inbox.Count(ctx); n != 0 {
+		t.Errorf("inbox count = %d, want 0", n)
+	}
+
+	var (
+		fireAt    string
+		important int64
+	)
+
+	var n int
+
+	must(t, app.outbox.db.QueryRowContext(ctx, `SELECT count(*) FROM reminders`).Scan(&n))
+
+	if n != 1 {
+		t.Fatalf("reminders = %d, want 1", n)
+	}
+
+	must(t, app.outbox.db.QueryRowContext(ctx, `SELECT fire_at, important FROM reminders`).Scan(&fireAt, &important))
+
+	at, err := time.Parse(time.RFC3339, fireAt)
+	must(t, err)
+
+	if d := time.Until(at); d < 55*time.Minute || d > time.Hour {
+		t.Errorf("snoozed reminder fires in %v, want ~1h", d)
+	}
+
+	if important != 1 {
+		t.Errorf("important = %d, want 1 (carried over from the original)", important)
+	}
+
+	d, ok := app.outbox.ReminderDelivery(ctx, testRoom, "bot-msg-1")
+	if !ok || d.State != reminderSnoozed {
+		t.Errorf("delivery = %+v (found %v), want state %q", d, ok, reminderSnoozed)
+	}
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if len(mb.sentMessages) != 2 ||
+		!strings.Contains(mb.sentMessages[0].text, "Snoozed until") ||
+		mb.sentMessages[1].text != "This reminder was already snoozed." {
+		t.Errorf("sent = %v, want a snooze confirmation, then already snoozed", mb.sentMessages)
+	}
+}
+
+func TestApp_ReminderReplyFallsThrough(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, _ := newTestApp(t)
+
+	deliverTestReminder(ctx, t, app, 0, "bot-msg-1")
+
+	// A question about the reminder is not a snooze command: it must
+	// reach the agent like any other reply.
+	app.HandleMessage(ctx, backend.Message{
+		ConversationID: testRoom,
+		Text:           "which plants?",
+		MessageID:      "user-msg-1",
+		ReplyToID:      "bot-msg-1",
+	})
+
+	if n, _ := app.inbox.Count(ctx); n != 1 {
+		t.Errorf("inbox count = %d, want 1", n)
+	}
+}
+
+func TestMaintainReminderDeliveries_RefiresUntilAcknowledged(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, _ := newTestApp(t)
+	cfg := HeartbeatConfig{ReminderRefire: time.Minute, ReminderMaxRefires: 1}
+
+	deliverTestReminder(ctx, t, app, 1, "bot-msg-1")
+
+	// Pretend the delivery happened long enough ago to be due a re-fire.
+	_, err := app.outbox.db.ExecContext(ctx, `UPDATE reminder_deliveries SET delivered_at = ?`,
+		time.Now().UTC().Add(-time.Hour).Format(time.RFC3339))
+	must(t, err)
+
+	maintainReminderDeliveries(ctx, app.worker, cfg)
+
+	item, err := app.inbox.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("expected a re-fired reminder, got error: %v", err)
+	}
+
+	if item.ReminderID == 0 || !strings.Contains(item.Content, "still unacknowledged") {
+		t.Errorf("re-fired item = %+v", item)
+	}
+
+	// Deliver the re-fire too; MaxRefires=1 means it must not fire again.
+	app.outbox.RecordReminderDelivery(ctx, item.ReminderID, testRoom, "bot-msg-2")
+
+	_, err = app.outbox.db.ExecContext(ctx, `UPDATE reminder_deliveries SET delivered_at = ?`,
+		time.Now().UTC().Add(-time.Hour).Format(time.RFC3339))
+	must(t, err)
+
+	maintainReminderDeliveries(ctx, app.worker, cfg)
+
+	if n, _ := app.inbox.Count(ctx); n != 0 {
+		t.Errorf("inbox count = %d after max re-fires, want 0", n)
+	}
+
+	// Acknowledging the first message settles the whole chain.
+	app.HandleMessage(ctx, backend.Message{ConversationID: testRoom, Text: "done", ReplyToID: "bot-msg-1"})
+
+	if d, _ := app.outbox.ReminderDelivery(ctx, testRoom, "bot-msg-2"); d.State != reminderAcked {
+		t.Errorf("re-fired delivery state = %q, want %q", d.State, reminderAcked)
+	}
+}
+
+// A reminder whose reply was suppressed has no message to reply to, but
+// must still re-fire.
+func TestMaintainReminderDeliveries_RefiresWithoutMessage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	app, _ := newTestApp(t)
+
+	deliverTestReminder(ctx, t, app, 1, "")
+
+	_, err := app.outbox.db.ExecContext(ctx, `UPDATE reminder_deliveries SET delivered_at = ?`,
+		time.Now().UTC().Add(-time.Hour).Format(time.RFC3339))
+	must(t, err)
+
+	maintainReminderDeliveries(ctx, app.worker, HeartbeatConfig{ReminderRefire: time.Minute, ReminderMaxRefires: 1})
+
+	if n, _ := app.inbox.Count(ctx); n != 1 {
+		t.Errorf("inbox count = %d, want the re-fired reminder", n)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+const (
+	defaultReplyChainDepth  = 5
+	defaultReplyChainTokens = 1000
+	// replyChainTimeout bounds the whole chain walk, backend lookups of
+	// unknown messages included, so a slow homeserver can't hold up the
+	// message for long.
+	replyChainTimeout = 10 * time.Second
+	// botSpeaker labels the bot's own messages in a reply chain. The
+	// prompt is read by the agent, so its messages are "you".
+	botSpeaker = "you"
+)
+
+// ReplyConfig bounds the reply-chain context prepended to user messages.
+type ReplyConfig struct {
+	Depth  int // OPENCROW_REPLY_CHAIN_DEPTH, default 5
+	Tokens int // OPENCROW_REPLY_CHAIN_TOKENS, default 1000 (estimated)
+}
+
+// chainMessage is one message of a reply chain.
+type chainMessage struct {
+	Speaker string
+	Text    string
+	ReplyTo string // backend ID of the message it replies to, "" if none or unknown
+}
+
+// replyChain returns the messages msg replies to, oldest first: its reply
+// target, the message that one replied to, and so on, up to Depth
+// messages and Tokens estimated tokens. truncated reports that the chain
+// goes on further but was cut by a limit, an unknown message or the
+// timeout.
+func (a *App) replyChain(ctx context.Context, msg backend.Message) (chain []chainMessage, truncated bool) {
+	ctx, cancel := context.WithTimeout(ctx, replyChainTimeout)
+	defer cancel()
+
+	seen := map[string]bool{msg.MessageID: true}
+	budget := a.reply.Tokens
+
+	for next := msg.ReplyToID; next != "" && !seen[next]; {
+		if len(chain) >= a.reply.Depth {
+			truncated = true
+
+			break
+		}
+
+		seen[next] = true
+
+		var quote *backend.Quote
+		if len(chain) == 0 {
+			quote = msg.Quote
+		}
+
+		m, ok := a.lookupReplyTarget(ctx, msg.ConversationID, next, quote)
+		if !ok {
+			truncated = len(chain) > 0
+
+			break
+		}
+
+		cost := estimateTokens(m.Text)
+		if cost > budget && len(chain) > 0 {
+			truncated = true
+
+			break
+		}
+
+		if cost > budget {
+			// Always keep the direct target, shortened to the budget.
+			m.Text = truncateRunes(m.Text, budget*4)
+		}
+
+		budget -= cost
+		chain = append(chain, m)
+		next = m.ReplyTo
+	}
+
+	slices.Reverse(chain)
+
+	return chain, truncated
+}
+
+// lookupReplyTarget resolves a message by backend ID: from the message
+// history, then the quote the transport delivered with the reply, then
+// the outbox, and finally by asking the backend.
+func (a *App) lookupReplyTarget(ctx context.Context, conversationID, messageID string, quote *backend.Quote) (chainMessage, bool) {
+	if m, ok := a.history.Get(ctx, conversationID, messageID); ok {
+		speaker := botSpeaker
+		if m.Direction == historyIn {
+			speaker = cmp.Or(m.Sender, "user")
+		}
+
+		return chainMessage{Speaker: speaker, Text: m.Text, ReplyTo: m.ReplyTo}, true
+	}
+
+	if quote != nil && quote.Text != "" {
+		return quoteMessage(*quote), true
+	}
+
+	// The outbox predates the history and knows neither speaker nor parent.
+	if text := a.outbox.Get(ctx, conversationID, messageID); text != "" {
+		return chainMessage{Speaker: "earlier message", Text: text}, true
+	}
+
+	fetcher, ok := a.backend.(backend.MessageFetcher)
+	if !ok {
+		return chainMessage{}, false
+	}
+
+	q, err := fetcher.FetchMessage(ctx, conversationID, messageID)
+	if err != nil {
+		slog.Warn("failed to fetch reply target", "conversation", conversationID, "message", messageID, "error", err)
+
+		return chainMessage{}, false
+	}
+
+	return quoteMessage(q), true
+}
+
+func quoteMessage(q backend.Quote) chainMessage {
+	speaker := cmp.Or(q.SenderID, "user")
+	if q.FromBot {
+		speaker = botSpeaker
+	}
+
+	return chainMessage{Speaker: speaker, Text: q.Text, ReplyTo: q.ReplyToID}
+}
+
+// formatReplyChain renders chain as a quoted block ending in a newline,
+// ready to prepend to the user's message.
+func formatReplyChain(chain []chainMessage, truncated bool) string {
+	var sb strings.Builder
+
+	sb.WriteString("[user replied to the last message of this thread, oldest first]\n")
+
+	if truncated {
+		sb.WriteString("> …\n")
+	}
+
+	writeQuoted(&sb, chain)
+
+	return sb.String()
+}
+
+// writeQuoted writes msgs as "> speaker: text" lines.
+func writeQuoted(sb *strings.Builder, msgs []chainMessage) {
+	for _, m := range msgs {
+		for i, line := range strings.Split(strings.TrimSpace(m.Text), "\n") {
+			if i == 0 {
+				line = m.Speaker + ": " + line
+			}
+
+			sb.WriteString("> " + line + "\n")
+		}
+	}
+}
+
+// estimateTokens approximates the token count of s at four characters per
+// token, close enough for budgeting prompt context.
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+
+	return s
+}
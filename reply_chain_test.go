@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// fetchingBackend is a mockBackend that can look up messages by ID, like
+// the Matrix backend.
+type fetchingBackend struct {
+	mockBackend
+
+	messages  map[string]backend.Quote
+	deadlines []time.Time // of each FetchMessage call
+}
+
+func (f *fetchingBackend) FetchMessage(ctx context.Context, _, messageID string) (backend.Quote, error) {
+	deadline, _ := ctx.Deadline()
+	f.deadlines = append(f.deadlines, deadline)
+
+	q, ok := f.messages[messageID]
+	if !ok {
+		return backend.Quote{}, errors.New("not found")
+	}
+
+	return q, nil
+}
+
+// recordThread logs a thread of alternating user and bot messages m1, m2,
+// …, each replying to the one before.
+func recordThread(ctx context.Context, app *App, texts ...string) {
+	for i, text := range texts {
+		e := historyEntry{
+			ConversationID: testRoom,
+			Direction:      historyOut,
+			MessageID:      fmt.Sprintf("m%d", i+1),
+			Text:           text,
+		}
+
+		if i > 0 {
+			e.ReplyTo = fmt.Sprintf("m%d", i)
+		}
+
+		if i%2 == 0 {
+			e.Direction, e.Sender = historyIn, "@alice:example.com"
+		}
+
+		app.history.Record(ctx, e)
+	}
+}
+
+func TestApp_ReplyChain(t *testing.T) {
+	t.Parallel()
+
+	reply := func(to string) backend.Message {
+		return backend.Message{ConversationID: testRoom, Text: "and now?", MessageID: "new", ReplyToID: to}
+	}
+
+	tests := []struct {
+		name  string
+		reply ReplyConfig
+		msg   backend.Message
+		want  string
+	}{
+		{
+			name:  "whole thread",
+			reply: ReplyConfig{Depth: 5, Tokens: 1000},
+			msg:   reply("m3"),
+			want: "[user replied to the last message of this thread, oldest first]\n" +
+				"> @alice:example.com: check the backups\n" +
+				"> you: last run failed:\n> disk full\n" +
+				"> @alice:example.com: fixed it\n" +
+				"and now?",
+		},
+		{
+			name:  "depth limit",
+			reply: ReplyConfig{Depth: 2, Tokens: 1000},
+			msg:   reply("m3"),
+			want: "[user replied to the last message of this thread, oldest first]\n> …\n" +
+				"> you: last run failed:\n> disk full\n" +
+				"> @alice:example.com: fixed it\n" +
+				"and now?",
+		},
+		{
+			name:  "token budget shortens the direct target",
+			reply: ReplyConfig{Depth: 5, Tokens: 1},
+			msg:   reply("m3"),
+			want: "[user replied to the last message of this thread, oldest first]\n> …\n" +
+				"> @alice:example.com: fixe…\n" +
+				"and now?",
+		},
+		{
+			name:  "transport quote for unknown target",
+			reply: ReplyConfig{Depth: 5, Tokens: 1000},
+			msg: backend.Message{
+				ConversationID: testRoom, Text: "yes", ReplyToID: "gone",
+				Quote: &backend.Quote{SenderID: "+49123", Text: "lunch?"},
+			},
+			want: "[user replied to the last message of this thread, oldest first]\n> +49123: lunch?\nyes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			app, _ := newTestApp(t)
+			app.reply = tt.reply
+
+			recordThread(ctx, app, "check the backups", "last run failed:\ndisk full", "fixed it")
+
+			if got := app.buildPromptText(ctx, tt.msg); got != tt.want {
+				t.Errorf("buildPromptText =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_ReplyChain_FetchesUnknownTargets(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fb := &fetchingBackend{messages: map[string]backend.Quote{
+		"$b": {SenderID: "@bot:example.com", Text: "Deploy finished.", FromBot: true, ReplyToID: "$a"},
+		"$a": {SenderID: "@alice:example.com", Text: "deploy please"},
+	}}
+
+	app, _ := newTestAppWithBackend(t, &fb.mockBackend)
+	app.backend = fb
+
+	got := app.buildPromptText(ctx, backend.Message{ConversationID: testRoom, Text: "thanks", ReplyToID: "$b"})
+
+	want := "> @alice:example.com: deploy please\n> you: Deploy finished.\nthanks"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("buildPromptText = %q, want suffix %q", got, want)
+	}
+
+	// Both lookups share the deadline of the whole walk.
+	if len(fb.deadlines) != 2 || fb.deadlines[0].IsZero() || !fb.deadlines[0].Equal(fb.deadlines[1]) {
+		t.Errorf("fetch deadlines = %v, want one shared deadline", fb.deadlines)
+	}
+
+	// Without a fetcher an unknown target stays unavailable.
+	app.backend = &fb.mockBackend
+
+	got = app.buildPromptText(ctx, backend.Message{ConversationID: testRoom, Text: "thanks", ReplyToID: "$b"})
+	if !strings.Contains(got, "content is unavailable") {
+		t.Errorf("buildPromptText = %q, want unavailable note", got)
+	}
+}
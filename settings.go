@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// Verbosity is what a conversation sees of the agent's work besides its
+// replies.
+type Verbosity struct {
+	ToolCalls   bool // relay tool calls as they start
+	Thinking    bool // send the model's thinking before the reply
+	DebugTiming bool // append the task duration to each reply
+}
+
+// settingsStore persists per-conversation Verbosity. The worker reads it
+// on every tool event, so rows are cached; writes go through the cache.
+type settingsStore struct {
+	queries  *Queries
+	defaults Verbosity // from OPENCROW_SHOW_TOOL_CALLS and OPENCROW_DEBUG_TIMING
+
+	mu    sync.Mutex
+	cache map[string]Verbosity
+}
+
+// newSettingsStore wraps an existing database connection. The caller owns
+// the DB lifecycle.
+func newSettingsStore(db *sql.DB, defaults Verbosity) *settingsStore {
+	return &settingsStore{queries: New(db), defaults: defaults, cache: make(map[string]Verbosity)}
+}
+
+// Get returns the settings of conversationID, or the defaults if none
+// were set or they can't be read.
+func (s *settingsStore) Get(ctx context.Context, conversationID string) Verbosity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.cache[conversationID]; ok {
+		return v
+	}
+
+	row, err := s.queries.GetConversationSettings(ctx, conversationID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("failed to read conversation settings", "conversation", conversationID, "error", err)
+
+			return s.defaults
+		}
+
+		s.cache[conversationID] = s.defaults
+
+		return s.defaults
+	}
+
+	v := Verbosity{ToolCalls: row.ToolCalls != 0, Thinking: row.Thinking != 0, DebugTiming: row.DebugTiming != 0}
+	s.cache[conversationID] = v
+
+	return v
+}
+
+// Set stores v for conversationID.
+func (s *settingsStore) Set(ctx context.Context, conversationID string, v Verbosity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.queries.UpsertConversationSettings(ctx, UpsertConversationSettingsParams{
+		ConversationID: conversationID,
+		ToolCalls:      boolInt(v.ToolCalls),
+		Thinking:       boolInt(v.Thinking),
+		DebugTiming:    boolInt(v.DebugTiming),
+	}); err != nil {
+		return fmt.Errorf("saving conversation settings: %w", err)
+	}
+
+	s.cache[conversationID] = v
+
+	return nil
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func (a *App) handleVerbose(ctx context.Context, msg backend.Message, _ string) {
+	a.updateVerbosity(ctx, msg, Verbosity{ToolCalls: true, Thinking: true, DebugTiming: true})
+}
+
+func (a *App) handleQuiet(ctx context.Context, msg backend.Message, _ string) {
+	a.updateVerbosity(ctx, msg, Verbosity{})
+}
+
+func (a *App) handleTools(ctx context.Context, msg backend.Message, args string) {
+	a.toggleVerbosity(ctx, msg, "tools", args, func(v *Verbosity, on bool) { v.ToolCalls = on })
+}
+
+func (a *App) handleThinking(ctx context.Context, msg backend.Message, args string) {
+	a.toggleVerbosity(ctx, msg, "thinking", args, func(v *Verbosity, on bool) { v.Thinking = on })
+}
+
+// toggleVerbosity runs "!<command> [on|off]": it sets one setting with
+// set, or shows them all without an argument.
+func (a *App) toggleVerbosity(ctx context.Context, msg backend.Message, command, args string, set func(*Verbosity, bool)) {
+	v := a.settings.Get(ctx, msg.ConversationID)
+
+	switch strings.ToLower(args) {
+	case "on":
+		set(&v, true)
+	case "off":
+		set(&v, false)
+	case "":
+		a.backend.SendMessage(ctx, msg.ConversationID, describeVerbosity(v), "")
+
+		return
+	default:
+		a.backend.SendMessage(ctx, msg.ConversationID, "Usage: !"+command+" [on|off]", "")
+
+		return
+	}
+
+	a.updateVerbosity(ctx, msg, v)
+}
+
+// updateVerbosity saves v for the conversation and confirms it. The
+// worker reads settings per event, so a running turn picks them up
+// without restarting pi.
+func (a *App) updateVerbosity(ctx context.Context, msg backend.Message, v Verbosity) {
+	if err := a.settings.Set(ctx, msg.ConversationID, v); err != nil {
+		slog.Error("failed to save verbosity", "conversation", msg.ConversationID, "error", err)
+		a.backend.SendMessage(ctx, msg.ConversationID, fmt.Sprintf("Failed to save setting: %v", err), "")
+
+		return
+	}
+
+	a.backend.SendMessage(ctx, msg.ConversationID, describeVerbosity(v), "")
+}
+
+func describeVerbosity(v Verbosity) string {
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+
+		return "off"
+	}
+
+	return fmt.Sprintf("In this conversation: tool calls %s, thinking %s, timing %s.",
+		onOff(v.ToolCalls), onOff(v.Thinking), onOff(v.DebugTiming))
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+func TestSettingsStore_DefaultsAndPersistence(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newTestDB(ctx, t)
+	defaults := Verbosity{DebugTiming: true}
+
+	s := newSettingsStore(db, defaults)
+	if got := s.Get(ctx, "conv1"); got != defaults {
+		t.Fatalf("Get without a row = %+v, want defaults %+v", got, defaults)
+	}
+
+	want := Verbosity{ToolCalls: true, Thinking: true}
+	must(t, s.Set(ctx, "conv1", want))
+
+	// A fresh store has an empty cache, so this reads the row back.
+	reopened := newSettingsStore(db, defaults)
+	if got := reopened.Get(ctx, "conv1"); got != want {
+		t.Errorf("Get after Set = %+v, want %+v", got, want)
+	}
+
+	if got := reopened.Get(ctx, "conv2"); got != defaults {
+		t.Errorf("other conversation = %+v, want defaults %+v", got, defaults)
+	}
+}
+
+func TestApp_VerbosityCommands(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		commands []string
+		wantLast string
+		want     Verbosity
+	}{
+		{"verbose", []string{"!verbose"}, "tool calls on, thinking on, timing on", Verbosity{ToolCalls: true, Thinking: true, DebugTiming: true}},
+		{"quiet", []string{"!verbose", "!quiet"}, "tool calls off, thinking off, timing off", Verbosity{}},
+		{"tools on", []string{"!tools on"}, "tool calls on, thinking off", Verbosity{ToolCalls: true}},
+		{"tools off", []string{"!verbose", "!tools OFF"}, "tool calls off, thinking on", Verbosity{Thinking: true, DebugTiming: true}},
+		{"tools shows state", []string{"!tools"}, "tool calls off, thinking off, timing off", Verbosity{}},
+		{"tools bad arg", []string{"!tools maybe"}, "Usage: !tools [on|off]", Verbosity{}},
+		{"thinking on", []string{"!thinking on"}, "tool calls off, thinking on", Verbosity{Thinking: true}},
+		{"thinking bad arg", []string{"!thinking maybe"}, "Usage: !thinking [on|off]", Verbosity{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app, mb := newTestApp(t)
+			for _, cmd := range tc.commands {
+				sendCommand(app, cmd)
+			}
+
+			mb.mu.Lock()
+			last := mb.sentMessages[len(mb.sentMessages)-1].text
+			mb.mu.Unlock()
+
+			if !strings.Contains(last, tc.wantLast) {
+				t.Errorf("reply %q missing %q", last, tc.wantLast)
+			}
+
+			if got := app.settings.Get(context.Background(), testRoom); got != tc.want {
+				t.Errorf("settings = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// Toggling verbosity must take effect on the next turn without
+// restarting pi, and only in the conversation it was set in.
+func TestWorker_VerbosityAppliesWithoutRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+	mb := &mockBackend{}
+	w.SetBackend(mb)
+
+	app := NewApp(mb, w, w.inbox, newTestDB(ctx, t))
+	w.SetApp(app)
+
+	turn := func() []string {
+		t.Helper()
+
+		mb.mu.Lock()
+		mb.sentMessages = nil
+		mb.mu.Unlock()
+
+		w.processPrompt(ctx, Inbox{Source: sourceUser, Content: "use-tools"})
+
+		mb.mu.Lock()
+		defer mb.mu.Unlock()
+
+		var texts []string
+		for _, m := range mb.sentMessages {
+			texts = append(texts, m.text)
+		}
+
+		return texts
+	}
+
+	if got := turn(); len(got) != 1 || got[0] != "ok" {
+		t.Fatalf("quiet turn sent %q, want only the reply", got)
+	}
+
+	w.mu.Lock()
+	pi := w.pi
+	w.mu.Unlock()
+
+	must(t, app.settings.Set(ctx, "room", Verbosity{ToolCalls: true, Thinking: true, DebugTiming: true}))
+
+	// Without edits, the tool call is posted when it starts and again
+	// when it finishes (the digest interval is 0 here).
+	got := turn()
+	want := []string{"⏳ bash df -h", "✓ bash df -h", "💭 Disk first."}
+
+	if len(got) != len(want)+1 || !slices.Equal(got[:len(want)], want) {
+		t.Fatalf("verbose turn sent %q, want %q and the reply", got, want)
+	}
+
+	if reply := got[len(want)]; !strings.HasPrefix(reply, "ok") || !strings.Contains(reply, "⏱") {
+		t.Errorf("reply = %q, want timing appended", reply)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pi != pi {
+		t.Error("pi was restarted when verbosity changed")
+	}
+}
+
+// streamingBackend is a mockBackend that implements backend.Streamer,
+// like the socket backend.
+type streamingBackend struct {
+	mockBackend
+
+	thinking []string
+}
+
+func (s *streamingBackend) SendDelta(context.Context, string, string, string) {}
+
+func (s *streamingBackend) SendThinkingDelta(_ context.Context, _, _, delta string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.thinking = append(s.thinking, delta)
+}
+
+func TestWorker_ThinkingRelay(t *testing.T) {
+	t.Parallel()
+
+	t.Run("streamed", func(t *testing.T) {
+		t.Parallel()
+
+		sb := &streamingBackend{}
+		runThinkingTurn(t, sb)
+
+		sb.mu.Lock()
+		defer sb.mu.Unlock()
+
+		if got := strings.Join(sb.thinking, ""); got != "Disk first." {
+			t.Errorf("streamed thinking = %q, want %q", got, "Disk first.")
+		}
+
+		for _, m := range sb.sentMessages {
+			if strings.HasPrefix(m.text, "💭") {
+				t.Errorf("thinking sent again after streaming: %q", m.text)
+			}
+		}
+	})
+
+	t.Run("collapsed", func(t *testing.T) {
+		t.Parallel()
+
+		db := &detailsBackend{}
+		runThinkingTurn(t, db)
+
+		if db.summary != "💭 Thinking" || db.details != "Disk first." {
+			t.Errorf("details = %q / %q, want the thinking collapsed", db.summary, db.details)
+		}
+	})
+}
+
+// runThinkingTurn runs a fake-pi turn with thinking on.
+func runThinkingTurn(t *testing.T, be backend.Backend) {
+	t.Helper()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+	w.SetBackend(be)
+
+	app := NewApp(be, w, w.inbox, newTestDB(ctx, t))
+	w.SetApp(app)
+
+	must(t, app.settings.Set(ctx, "room", Verbosity{Thinking: true}))
+	w.processPrompt(ctx, Inbox{Source: sourceUser, Content: "use-tools"})
+}
+
+// Thinking follows the settings of the turn's conversation, not of the
+// room pi was started for.
+func TestWorker_ThinkingUsesTurnConversation(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+	db := &detailsBackend{}
+	w.SetBackend(db)
+
+	app := NewApp(db, w, w.inbox, newTestDB(ctx, t))
+	w.SetApp(app)
+
+	must(t, app.settings.Set(ctx, "!ops", Verbosity{Thinking: true}))
+	w.processPrompt(ctx, Inbox{Source: sourceUser, Content: "use-tools", ConversationID: "!ops"})
+
+	if db.details != "Disk first." {
+		t.Errorf("details = %q, want the thinking of the !ops turn", db.details)
+	}
+}
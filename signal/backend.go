@@ -140,7 +140,7 @@ func (b *Backend) Run(ctx context.Context) error {
 				continue
 			}
 
-			msg, ok, err := decodeReceiveNotification(n.Params, b.cfg.ConfigDir)
+			msg, ok, err := decodeReceiveNotification(n.Params, b.cfg.ConfigDir, b.cfg.Account)
 			if err != nil {
 				slog.Warn("signal: failed to parse incoming notification", "error", err)
 
@@ -155,6 +155,10 @@ func (b *Backend) Run(ctx context.Context) error {
 				continue
 			}
 
+			if msg.Quote != nil && msg.Quote.SenderID == b.cfg.Account {
+				msg.Quote.FromBot = true
+			}
+
 			if !b.isAllowed(msg.SenderID) {
 				slog.Debug("signal: dropping message from non-allowed sender", "sender", msg.SenderID)
 
@@ -207,6 +211,49 @@ func (b *Backend) SendMessage(ctx context.Context, conversationID string, text s
 	return timestamp
 }
 
+// EditMessage edits a message the bot sent earlier, identified by the
+// timestamp SendMessage returned.
+func (b *Backend) EditMessage(ctx context.Context, conversationID, messageID, text string) error {
+	ts, err := strconv.ParseInt(messageID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("signal edit: invalid message timestamp %q", messageID)
+	}
+
+	params := map[string]any{
+		"message":       text,
+		"editTimestamp": ts,
+	}
+	addRecipientParams(params, conversationID)
+
+	var result sendResult
+	if err := b.rpcCall(ctx, "send", params, &result); err != nil {
+		return fmt.Errorf("signal edit: %w", err)
+	}
+
+	return nil
+}
+
+// RedactMessage deletes an earlier message for everyone. Signal identifies
+// messages by their timestamp.
+func (b *Backend) RedactMessage(ctx context.Context, conversationID, messageID string) error {
+	ts, err := strconv.ParseInt(messageID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("signal remote delete: invalid message timestamp %q", messageID)
+	}
+
+	params := map[string]any{
+		"targetTimestamp": ts,
+	}
+	addRecipientParams(params, conversationID)
+
+	var result sendResult
+	if err := b.rpcCall(ctx, "remoteDelete", params, &result); err != nil {
+		return fmt.Errorf("signal remote delete: %w", err)
+	}
+
+	return nil
+}
+
 // SendFile sends a file attachment via Signal.
 func (b *Backend) SendFile(ctx context.Context, conversationID string, filePath string) error {
 	params := map[string]any{
@@ -431,7 +431,7 @@ func handleTestNotification(ctx context.Context, b *Backend, n rpcNotification)
 		return
 	}
 
-	msg, ok, err := decodeReceiveNotification(n.Params, b.cfg.ConfigDir)
+	msg, ok, err := decodeReceiveNotification(n.Params, b.cfg.ConfigDir, b.cfg.Account)
 	if err != nil || !ok {
 		return
 	}
@@ -802,6 +802,41 @@ func TestSendFile(t *testing.T) {
 	}
 }
 
+func TestEditMessage(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeSignalDaemon(t)
+	sends := &sendRecorder{}
+	b := newTestBackend(t, fake, nil, func(_ context.Context, _ backend.Message) {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go fake.autoRespond(ctx, sends)
+
+	if err := b.EditMessage(ctx, "+49222", "1700000000999", "edited"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.EditMessage(ctx, "+49222", "not-a-timestamp", "edited"); err == nil {
+		t.Error("expected error for non-numeric message ID")
+	}
+
+	calls := sends.get()
+	if len(calls) != 1 {
+		t.Fatalf("got %d calls, want 1", len(calls))
+	}
+
+	p := marshalParams(t, calls[0])
+	if p["message"] != "edited" {
+		t.Errorf("message = %v", p["message"])
+	}
+
+	if ts, ok := p["editTimestamp"].(float64); !ok || int64(ts) != 1700000000999 {
+		t.Errorf("editTimestamp = %v", p["editTimestamp"])
+	}
+}
+
 func TestSendMessage_EmptyTextNoop(t *testing.T) {
 	t.Parallel()
 
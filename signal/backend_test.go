@@ -10,7 +10,7 @@ func TestDecodeReceiveMessage_DirectText(t *testing.T) {
 
 	payload := []byte(`{"envelope":{"source":"+4911111","sourceNumber":"+4911111","timestamp":1700000000123,"dataMessage":{"timestamp":1700000000123,"message":"hello"}}}`)
 
-	msg, ok, err := decodeReceiveMessage(payload, "")
+	msg, ok, err := decodeReceiveMessage(payload, "", "")
 	if err != nil {
 		t.Fatalf("decodeReceiveMessage: %v", err)
 	}
@@ -39,9 +39,9 @@ func TestDecodeReceiveMessage_DirectText(t *testing.T) {
 func TestDecodeReceiveNotification_WrappedSubscription(t *testing.T) {
 	t.Parallel()
 
-	payload := []byte(`{"subscription":0,"result":{"envelope":{"sourceNumber":"+4911111","timestamp":1700000000999,"dataMessage":{"timestamp":1700000001000,"message":"see file","quote":{"id":1699999999000},"groupInfo":{"groupId":"ABCD123="},"attachments":[{"filename":"attachments/abc.png","caption":"diagram"}]}}}}`)
+	payload := []byte(`{"subscription":0,"result":{"envelope":{"sourceNumber":"+4911111","sourceName":"Alice","timestamp":1700000000999,"dataMessage":{"timestamp":1700000001000,"message":"see file","quote":{"id":1699999999000},"groupInfo":{"groupId":"ABCD123=","groupName":"Family"},"attachments":[{"filename":"attachments/abc.png","caption":"diagram"}]}}}}`)
 
-	msg, ok, err := decodeReceiveNotification(json.RawMessage(payload), "/var/lib/signal-cli")
+	msg, ok, err := decodeReceiveNotification(json.RawMessage(payload), "/var/lib/signal-cli", "")
 	if err != nil {
 		t.Fatalf("decodeReceiveNotification: %v", err)
 	}
@@ -58,6 +58,14 @@ func TestDecodeReceiveNotification_WrappedSubscription(t *testing.T) {
 		t.Fatalf("ReplyToID = %q, want 1699999999000", msg.ReplyToID)
 	}
 
+	if !msg.Group {
+		t.Fatal("Group = false for a group message")
+	}
+
+	if msg.SenderName != "Alice" || msg.ConversationName != "Family" {
+		t.Fatalf("SenderName/ConversationName = %q/%q, want Alice/Family", msg.SenderName, msg.ConversationName)
+	}
+
 	wantText := "see file\n[User sent a file (diagram): /var/lib/signal-cli/attachments/abc.png]\nUse the read tool to view it."
 	if msg.Text != wantText {
 		t.Fatalf("Text = %q, want %q", msg.Text, wantText)
@@ -69,7 +77,7 @@ func TestDecodeReceiveMessage_IgnoresNonDataMessages(t *testing.T) {
 
 	payload := []byte(`{"envelope":{"sourceNumber":"+4911111","timestamp":1700000000123}}`)
 
-	msg, ok, err := decodeReceiveMessage(payload, "")
+	msg, ok, err := decodeReceiveMessage(payload, "", "")
 	if err != nil {
 		t.Fatalf("decodeReceiveMessage: %v", err)
 	}
@@ -78,3 +86,49 @@ func TestDecodeReceiveMessage_IgnoresNonDataMessages(t *testing.T) {
 		t.Fatalf("expected ignored message, got %+v", msg)
 	}
 }
+
+func TestDecodeReceiveMessage_QuoteText(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte(`{"envelope":{"sourceNumber":"+4911111","timestamp":1700000000500,"dataMessage":{"timestamp":1700000000500,"message":"and then?","quote":{"id":1699999999000,"author":"+4922222","authorNumber":"+4922222","text":"step one done"}}}}`)
+
+	msg, ok, err := decodeReceiveMessage(payload, "", "")
+	if err != nil {
+		t.Fatalf("decodeReceiveMessage: %v", err)
+	}
+
+	if !ok || msg == nil {
+		t.Fatal("msg is nil")
+	}
+
+	if msg.Quote == nil {
+		t.Fatal("Quote is nil")
+	}
+
+	if msg.Quote.SenderID != "+4922222" || msg.Quote.Text != "step one done" {
+		t.Fatalf("Quote = %+v", msg.Quote)
+	}
+}
+
+func TestDecodeReceiveMessage_Mentions(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte(`{"envelope":{"sourceNumber":"+4911111","timestamp":1700000000600,"dataMessage":{"timestamp":1700000000600,"message":"\ufffc ask \ufffc","mentions":[{"name":"Crow","number":"+4900000","start":0,"length":1},{"name":"Bob","number":"+4922222","start":6,"length":1}],"groupInfo":{"groupId":"ABCD123="}}}}`)
+
+	msg, ok, err := decodeReceiveMessage(payload, "", "+4900000")
+	if err != nil {
+		t.Fatalf("decodeReceiveMessage: %v", err)
+	}
+
+	if !ok || msg == nil {
+		t.Fatal("msg is nil")
+	}
+
+	if msg.Text != "@Crow ask @Bob" {
+		t.Fatalf("Text = %q, want %q", msg.Text, "@Crow ask @Bob")
+	}
+
+	if !msg.MentionsBot {
+		t.Fatal("MentionsBot = false, want true")
+	}
+}
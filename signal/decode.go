@@ -43,6 +43,7 @@ type receiveEnvelope struct {
 	Source       string          `json:"source"`
 	SourceNumber string          `json:"sourceNumber"` //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
 	SourceUUID   string          `json:"sourceUuid"`   //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
+	SourceName   string          `json:"sourceName"`   //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
 	Timestamp    int64           `json:"timestamp"`
 	DataMessage  *receiveDataMsg `json:"dataMessage"` //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
 }
@@ -53,14 +54,28 @@ type receiveDataMsg struct {
 	Quote       *receiveQuote       `json:"quote"`
 	GroupInfo   *receiveGroupInfo   `json:"groupInfo"` //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
 	Attachments []receiveAttachment `json:"attachments"`
+	Mentions    []receiveMention    `json:"mentions"`
+}
+
+// receiveMention is an @-mention. signal-cli leaves an U+FFFC placeholder
+// in the message text for each one, in order.
+type receiveMention struct {
+	Name   string `json:"name"`
+	Number string `json:"number"`
+	UUID   string `json:"uuid"`
 }
 
 type receiveQuote struct {
-	ID int64 `json:"id"`
+	ID           int64  `json:"id"`
+	Author       string `json:"author"`
+	AuthorNumber string `json:"authorNumber"` //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
+	AuthorUUID   string `json:"authorUuid"`   //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
+	Text         string `json:"text"`
 }
 
 type receiveGroupInfo struct {
-	GroupID string `json:"groupId"` //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
+	GroupID   string `json:"groupId"`   //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
+	GroupName string `json:"groupName"` //nolint:tagliatelle // signal-cli JSON uses camelCase keys.
 }
 
 type receiveAttachment struct {
@@ -70,8 +85,13 @@ type receiveAttachment struct {
 	ID          string `json:"id"`
 }
 
-func decodeReceiveNotification(params json.RawMessage, configDir string) (*backend.Message, bool, error) {
-	msg, ok, err := decodeReceiveMessage(params, configDir)
+// mentionPlaceholder stands in for each mention in a message's text.
+const mentionPlaceholder = "\uFFFC"
+
+// decodeReceiveNotification decodes a receive notification. account is
+// the bot's own account, used to detect mentions of it.
+func decodeReceiveNotification(params json.RawMessage, configDir, account string) (*backend.Message, bool, error) {
+	msg, ok, err := decodeReceiveMessage(params, configDir, account)
 	if err != nil {
 		return nil, false, err
 	}
@@ -94,11 +114,11 @@ func decodeReceiveNotification(params json.RawMessage, configDir string) (*backe
 		return nil, false, fmt.Errorf("encoding wrapped receive payload: %w", err)
 	}
 
-	return decodeReceiveMessage(inner, configDir)
+	return decodeReceiveMessage(inner, configDir, account)
 }
 
 //nolint:cyclop // straightforward mapping from signal-cli JSON to backend message.
-func decodeReceiveMessage(payload []byte, configDir string) (*backend.Message, bool, error) {
+func decodeReceiveMessage(payload []byte, configDir, account string) (*backend.Message, bool, error) {
 	var line receiveLine
 	if err := json.Unmarshal(payload, &line); err != nil {
 		return nil, false, fmt.Errorf("decoding signal receive payload: %w", err)
@@ -114,12 +134,13 @@ func decodeReceiveMessage(payload []byte, configDir string) (*backend.Message, b
 		return nil, false, nil
 	}
 
-	conversationID := sender
-	if env.DataMessage.GroupInfo != nil && env.DataMessage.GroupInfo.GroupID != "" {
-		conversationID = groupConversationPrefix + env.DataMessage.GroupInfo.GroupID
+	conversationID, conversationName := sender, ""
+	if g := env.DataMessage.GroupInfo; g != nil && g.GroupID != "" {
+		conversationID, conversationName = groupConversationPrefix+g.GroupID, g.GroupName
 	}
 
-	text := strings.TrimSpace(env.DataMessage.Message)
+	text, mentionsBot := resolveMentions(env.DataMessage.Message, env.DataMessage.Mentions, account)
+	text = strings.TrimSpace(text)
 	if attachmentText := formatAttachmentText(env.DataMessage.Attachments, configDir); attachmentText != "" {
 		if text != "" {
 			text += "\n"
@@ -137,20 +158,54 @@ func decodeReceiveMessage(payload []byte, configDir string) (*backend.Message, b
 		messageID = strconv.FormatInt(ts, 10)
 	}
 
-	var replyTo string
-	if env.DataMessage.Quote != nil && env.DataMessage.Quote.ID != 0 {
-		replyTo = strconv.FormatInt(env.DataMessage.Quote.ID, 10)
+	var (
+		replyTo string
+		quote   *backend.Quote
+	)
+
+	if q := env.DataMessage.Quote; q != nil && q.ID != 0 {
+		replyTo = strconv.FormatInt(q.ID, 10)
+
+		// signal-cli carries the quoted text in the envelope, so a reply to
+		// a message sent before opencrow started still has context.
+		if text := strings.TrimSpace(q.Text); text != "" {
+			quote = &backend.Quote{
+				SenderID: firstNonEmpty(q.AuthorNumber, q.AuthorUUID, q.Author),
+				Text:     text,
+			}
+		}
 	}
 
 	return &backend.Message{
-		ConversationID: conversationID,
-		SenderID:       sender,
-		Text:           text,
-		MessageID:      messageID,
-		ReplyToID:      replyTo,
+		ConversationID:   conversationID,
+		ConversationName: conversationName,
+		SenderID:         sender,
+		SenderName:       strings.TrimSpace(env.SourceName),
+		Text:             text,
+		MessageID:        messageID,
+		ReplyToID:        replyTo,
+		Quote:            quote,
+		Group:            env.DataMessage.GroupInfo != nil && env.DataMessage.GroupInfo.GroupID != "",
+		MentionsBot:      mentionsBot,
 	}, true, nil
 }
 
+// resolveMentions replaces the mention placeholders in text with
+// "@name" and reports whether account is among the mentioned.
+func resolveMentions(text string, mentions []receiveMention, account string) (string, bool) {
+	var mentionsBot bool
+
+	for _, m := range mentions {
+		if account != "" && (m.Number == account || m.UUID == account) {
+			mentionsBot = true
+		}
+
+		text = strings.Replace(text, mentionPlaceholder, "@"+firstNonEmpty(m.Name, m.Number, m.UUID), 1)
+	}
+
+	return text, mentionsBot
+}
+
 func formatAttachmentText(attachments []receiveAttachment, configDir string) string {
 	if len(attachments) == 0 {
 		return ""
@@ -234,6 +234,41 @@ func (b *Backend) SendDelta(_ context.Context, _ string, messageID string, delta
 	})
 }
 
+// SendThinkingDelta sends an incremental fragment of the model's
+// thinking. It has its own kind so clients can keep it apart from the
+// reply.
+func (b *Backend) SendThinkingDelta(_ context.Context, _ string, messageID string, delta string) {
+	b.push(event{
+		Kind:      "thinking_delta",
+		Streaming: true,
+		Target:    messageID,
+		Text:      delta,
+	})
+}
+
+// EditMessage replaces the text of an earlier message on connected clients.
+func (b *Backend) EditMessage(_ context.Context, _ string, messageID string, text string) error {
+	b.push(event{
+		Kind:      "edit",
+		Streaming: true,
+		Target:    messageID,
+		Text:      text,
+	})
+
+	return nil
+}
+
+// RedactMessage removes an earlier message on connected clients.
+func (b *Backend) RedactMessage(_ context.Context, _ string, messageID string) error {
+	b.push(event{
+		Kind:      "delete",
+		Streaming: true,
+		Target:    messageID,
+	})
+
+	return nil
+}
+
 // SendFile sends a file path reference to connected clients.
 func (b *Backend) SendFile(_ context.Context, _ string, filePath string) error {
 	id := b.nextID()
@@ -368,6 +368,71 @@ func TestSendDelta_StreamsToClients(t *testing.T) {
 	}
 }
 
+func TestSendThinkingDelta_HasOwnKind(t *testing.T) {
+	t.Parallel()
+
+	b, sockPath, cancel := startBackend(t, func(_ context.Context, _ backend.Message) {})
+	defer cancel()
+
+	conn := dial(t, sockPath)
+	defer conn.Close()
+
+	cs := newConnScanner(conn)
+
+	b.SendThinkingDelta(context.Background(), "local", "thinking-1", "Hmm")
+
+	ev := cs.readEvent(t, conn)
+	if ev.Kind != "thinking_delta" {
+		t.Fatalf("Kind = %q, want thinking_delta", ev.Kind)
+	}
+
+	if ev.Target != "thinking-1" || ev.Text != "Hmm" {
+		t.Errorf("event = %+v, want thinking-1/Hmm", ev)
+	}
+}
+
+func TestEditMessage_PushesEditEvent(t *testing.T) {
+	t.Parallel()
+
+	b, sockPath, cancel := startBackend(t, func(_ context.Context, _ backend.Message) {})
+	defer cancel()
+
+	conn := dial(t, sockPath)
+	defer conn.Close()
+
+	if err := b.EditMessage(context.Background(), "local", "msg-1", "edited"); err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+
+	ev := readEvent(t, conn)
+	if ev.Kind != "edit" {
+		t.Fatalf("Kind = %q, want edit", ev.Kind)
+	}
+
+	if ev.Target != "msg-1" || ev.Text != "edited" {
+		t.Errorf("Target, Text = %q, %q; want msg-1, edited", ev.Target, ev.Text)
+	}
+}
+
+func TestRedactMessage_PushesDeleteEvent(t *testing.T) {
+	t.Parallel()
+
+	b, sockPath, cancel := startBackend(t, func(_ context.Context, _ backend.Message) {})
+	defer cancel()
+
+	conn := dial(t, sockPath)
+	defer conn.Close()
+
+	if err := b.RedactMessage(context.Background(), "local", "msg-1"); err != nil {
+		t.Fatalf("RedactMessage: %v", err)
+	}
+
+	ev := readEvent(t, conn)
+	if ev.Kind != "delete" || ev.Target != "msg-1" {
+		t.Errorf("Kind, Target = %q, %q; want delete, msg-1", ev.Kind, ev.Target)
+	}
+}
+
 func TestBackend_ImplementsStreamer(t *testing.T) {
 	t.Parallel()
 
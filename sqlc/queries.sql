@@ -20,20 +20,32 @@ WHERE rowid IN (
 );
 
 -- name: EnqueueInbox :exec
-INSERT INTO inbox (priority, source, content, reply_to)
-VALUES (?, ?, ?, ?);
+INSERT INTO inbox (priority, source, content, reply_to, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: EnqueueInboxUnlessQueued :one
+-- Skips the insert (no row returned) if an item with the same non-empty
+-- dedup_key is queued.
+INSERT INTO inbox (priority, source, content, reply_to, label, dedup_key, silent, conversation_id, waiter_id)
+SELECT sqlc.arg(priority), sqlc.arg(source), sqlc.arg(content), sqlc.arg(reply_to), sqlc.arg(label),
+       sqlc.arg(dedup_key), sqlc.arg(silent), sqlc.arg(conversation_id), sqlc.arg(waiter_id)
+WHERE NOT EXISTS (SELECT 1 FROM inbox WHERE dedup_key != '' AND dedup_key = sqlc.arg(dedup_key))
+RETURNING id;
 
 -- name: DequeueInbox :one
+-- Mergeable triggers (see DequeueMergeableTriggers) created after the
+-- given time are held back, so a burst can land in one turn.
 DELETE FROM inbox
 WHERE id = (
     SELECT id FROM inbox
+    WHERE NOT (source = 'trigger' AND reminder_id = 0 AND waiter_id = '' AND created_at > ?)
     ORDER BY priority ASC, id ASC
     LIMIT 1
 )
-RETURNING id, priority, source, content, reply_to, created_at;
+RETURNING id, priority, source, content, reply_to, created_at, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name;
 
 -- name: PeekInbox :one
-SELECT id, priority, source, content, reply_to, created_at
+SELECT id, priority, source, content, reply_to, created_at, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name
 FROM inbox
 ORDER BY priority ASC, id ASC
 LIMIT 1;
@@ -47,7 +59,21 @@ DELETE FROM inbox WHERE source IN ('heartbeat', 'compact');
 -- name: DequeueUserItems :many
 DELETE FROM inbox
 WHERE source = 'user'
-RETURNING id, priority, source, content, reply_to, created_at;
+RETURNING id, priority, source, content, reply_to, created_at, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name;
+
+-- name: DequeueMergeableTriggers :many
+-- Triggers without their own reply lifecycle (reminder delivery or
+-- synchronous caller) from the same source, bound for the same
+-- conversation.
+DELETE FROM inbox
+WHERE source = 'trigger' AND reminder_id = 0 AND waiter_id = '' AND conversation_id = ? AND label = ?
+RETURNING id, priority, source, content, reply_to, created_at, reminder_id, label, dedup_key, silent, conversation_id, waiter_id, sender, conversation_name;
+
+-- name: OldestMergeableTrigger :one
+SELECT created_at FROM inbox
+WHERE source = 'trigger' AND reminder_id = 0 AND waiter_id = ''
+ORDER BY created_at ASC
+LIMIT 1;
 
 -- name: CountInbox :one
 SELECT count(*) FROM inbox;
@@ -62,7 +88,122 @@ WHERE NOT EXISTS (SELECT 1 FROM inbox WHERE source = 'heartbeat');
 -- lexicographic comparison doesn't break on agent-formatted timestamps.
 DELETE FROM reminders
 WHERE datetime(fire_at) <= datetime(?)
-RETURNING id, fire_at, prompt;
+RETURNING id, fire_at, prompt, important;
 
 -- name: InsertReminder :exec
-INSERT INTO reminders (fire_at, prompt) VALUES (?, ?);
+INSERT INTO reminders (fire_at, prompt, important) VALUES (?, ?, ?);
+
+-- name: InsertReminderDelivery :one
+INSERT INTO reminder_deliveries (fire_at, prompt, important, refires)
+VALUES (?, ?, ?, ?)
+RETURNING id;
+
+-- name: MarkReminderDelivered :exec
+UPDATE reminder_deliveries
+SET state = 'delivered', conversation_id = ?, message_id = ?, delivered_at = ?
+WHERE id = ? AND state = 'pending';
+
+-- name: GetReminderDelivery :one
+SELECT id, fire_at, prompt, important, refires, state, conversation_id, message_id, delivered_at
+FROM reminder_deliveries
+WHERE conversation_id = ? AND message_id = ?;
+
+-- name: SettleReminderDeliveries :execrows
+-- Settles every delivery of one reminder (the original plus any re-fires)
+-- so acknowledging an older message also stops later re-fires. Settled
+-- deliveries are left alone, so a second reply changes nothing.
+UPDATE reminder_deliveries SET state = ?
+WHERE fire_at = ? AND prompt = ? AND state NOT IN ('acked', 'snoozed');
+
+-- name: RefireReminderDeliveries :many
+UPDATE reminder_deliveries SET state = 'refired'
+WHERE state = 'delivered' AND important = 1 AND refires < ?
+  AND datetime(delivered_at) <= datetime(?)
+RETURNING id, fire_at, prompt, important, refires, state, conversation_id, message_id, delivered_at;
+
+-- name: PruneReminderDeliveries :exec
+DELETE FROM reminder_deliveries WHERE datetime(fire_at) < datetime(?);
+
+-- name: DueScheduledMessages :many
+DELETE FROM scheduled_messages
+WHERE datetime(send_at) <= datetime(?)
+RETURNING id, send_at, text, files, conversation_id;
+
+-- name: InsertScheduledMessage :exec
+INSERT INTO scheduled_messages (send_at, text, files, conversation_id)
+VALUES (?, ?, ?, ?);
+
+-- name: ListCronRuns :many
+SELECT name, last_run FROM cron_runs;
+
+-- name: UpsertCronRun :exec
+INSERT INTO cron_runs (name, last_run) VALUES (?, ?)
+ON CONFLICT(name) DO UPDATE SET last_run = excluded.last_run;
+
+-- name: TriggerSeenSince :one
+SELECT count(*) FROM trigger_history
+WHERE dedup_key = ? AND datetime(seen_at) >= datetime(?);
+
+-- name: RecordTriggerSeen :exec
+INSERT INTO trigger_history (dedup_key, seen_at) VALUES (?, ?)
+ON CONFLICT(dedup_key) DO UPDATE SET seen_at = excluded.seen_at;
+
+-- name: PruneTriggerHistory :exec
+DELETE FROM trigger_history WHERE datetime(seen_at) < datetime(?);
+
+-- name: ListWatchedFiles :many
+SELECT rule, path, mod_time, size FROM watched_files WHERE rule = ?;
+
+-- name: UpsertWatchedFile :exec
+INSERT INTO watched_files (rule, path, mod_time, size) VALUES (?, ?, ?, ?)
+ON CONFLICT(rule, path) DO UPDATE SET mod_time = excluded.mod_time, size = excluded.size;
+
+-- name: WatchRuleSeeded :one
+SELECT count(*) FROM watch_rules WHERE rule = ?;
+
+-- name: MarkWatchRuleSeeded :exec
+INSERT INTO watch_rules (rule, seeded_at) VALUES (?, ?)
+ON CONFLICT(rule) DO NOTHING;
+
+-- name: ListFeedEntries :many
+SELECT guid FROM feed_entries WHERE feed = ?;
+
+-- name: UpsertFeedEntry :exec
+INSERT INTO feed_entries (feed, guid, seen_at) VALUES (?, ?, ?)
+ON CONFLICT(feed, guid) DO UPDATE SET seen_at = excluded.seen_at;
+
+-- name: PruneFeedEntries :exec
+DELETE FROM feed_entries WHERE datetime(seen_at) < datetime(?);
+
+-- name: CalendarEventFired :one
+SELECT count(*) FROM calendar_fired WHERE event_key = ?;
+
+-- name: RecordCalendarFired :exec
+INSERT OR IGNORE INTO calendar_fired (event_key, fired_at) VALUES (?, ?);
+
+-- name: PruneCalendarFired :exec
+DELETE FROM calendar_fired WHERE datetime(fired_at) < datetime(?);
+
+-- name: InsertMessage :exec
+INSERT INTO messages (conversation_id, direction, sender, message_id, reply_to, source, text, attachments, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetMessage :one
+SELECT id, conversation_id, direction, sender, message_id, reply_to, source, text, attachments, created_at
+FROM messages
+WHERE conversation_id = ? AND message_id = ?
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: GetConversationSettings :one
+SELECT conversation_id, tool_calls, thinking, debug_timing
+FROM conversation_settings
+WHERE conversation_id = ?;
+
+-- name: UpsertConversationSettings :exec
+INSERT INTO conversation_settings (conversation_id, tool_calls, thinking, debug_timing)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(conversation_id) DO UPDATE SET
+    tool_calls = excluded.tool_calls,
+    thinking = excluded.thinking,
+    debug_timing = excluded.debug_timing;
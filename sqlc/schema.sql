@@ -6,18 +6,152 @@ CREATE TABLE IF NOT EXISTS sent_messages (
 );
 
 CREATE TABLE IF NOT EXISTS reminders (
-    id       INTEGER PRIMARY KEY AUTOINCREMENT,
-    fire_at  TEXT NOT NULL,  -- ISO 8601 UTC
-    prompt   TEXT NOT NULL
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    fire_at   TEXT    NOT NULL,  -- ISO 8601 UTC
+    prompt    TEXT    NOT NULL,
+    important INTEGER NOT NULL DEFAULT 0  -- re-fire until acknowledged
     -- no index on fire_at: DueReminders wraps it in datetime() so an index
     -- would be unused, and rows are deleted on fire so the table stays tiny
 );
 
+-- Fired reminders, kept so a reply to the delivered message can snooze or
+-- acknowledge it natively. message_id is filled in once the agent's reply
+-- has been sent; until then the row is 'pending'.
+CREATE TABLE IF NOT EXISTS reminder_deliveries (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    fire_at         TEXT    NOT NULL,             -- original schedule, ISO 8601 UTC
+    prompt          TEXT    NOT NULL,
+    important       INTEGER NOT NULL DEFAULT 0,
+    refires         INTEGER NOT NULL DEFAULT 0,   -- how often this reminder re-fired unacknowledged
+    state           TEXT    NOT NULL DEFAULT 'pending',  -- pending, delivered, acked, snoozed, refired
+    conversation_id TEXT    NOT NULL DEFAULT '',
+    message_id      TEXT    NOT NULL DEFAULT '',
+    delivered_at    TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS reminder_deliveries_message
+    ON reminder_deliveries (conversation_id, message_id);
+
+-- Verbatim messages posted at send_at without an agent turn. files holds
+-- newline-separated absolute paths; an empty conversation_id means the
+-- current room.
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    send_at         TEXT    NOT NULL,  -- ISO 8601 UTC
+    text            TEXT    NOT NULL DEFAULT '',
+    files           TEXT    NOT NULL DEFAULT '',
+    conversation_id TEXT    NOT NULL DEFAULT ''
+);
+
+-- Last run of each config-defined cron job, keyed by job name, so a run
+-- missed while opencrow was down is caught up once on start.
+CREATE TABLE IF NOT EXISTS cron_runs (
+    name     TEXT PRIMARY KEY,
+    last_run TEXT NOT NULL  -- ISO 8601 UTC
+);
+
+-- Dedup keys of recently accepted triggers, for OPENCROW_TRIGGER_DEDUP_WINDOW.
+-- Rows older than the window are pruned on insert.
+CREATE TABLE IF NOT EXISTS trigger_history (
+    dedup_key TEXT PRIMARY KEY,
+    seen_at   TEXT NOT NULL  -- ISO 8601 UTC
+);
+
+-- Last seen state of files matched by OPENCROW_WATCH_FILE rules, so a
+-- restart only reports files that changed while opencrow was down.
+CREATE TABLE IF NOT EXISTS watched_files (
+    rule     TEXT    NOT NULL,
+    path     TEXT    NOT NULL,
+    mod_time TEXT    NOT NULL,  -- RFC 3339 with nanoseconds
+    size     INTEGER NOT NULL,
+    PRIMARY KEY (rule, path)
+);
+
+-- OPENCROW_WATCH_FILE rules whose directory has been seeded. Only the
+-- first scan of a rule records existing files without reporting them;
+-- later starts report whatever appeared meanwhile, even if the rule has
+-- no watched_files rows.
+CREATE TABLE IF NOT EXISTS watch_rules (
+    rule      TEXT PRIMARY KEY,
+    seeded_at TEXT NOT NULL  -- RFC 3339
+);
+
+-- Entry IDs already seen per OPENCROW_FEEDS_FILE feed. seen_at is
+-- refreshed while an entry is still in the feed, so pruning only forgets
+-- entries that have dropped out.
+CREATE TABLE IF NOT EXISTS feed_entries (
+    feed    TEXT NOT NULL,
+    guid    TEXT NOT NULL,
+    seen_at TEXT NOT NULL,  -- ISO 8601 UTC
+    PRIMARY KEY (feed, guid)
+);
+
+-- Calendar event instances (UID@start) that already fired a pre-event
+-- trigger. The start is part of the key, so a moved event fires again.
+CREATE TABLE IF NOT EXISTS calendar_fired (
+    event_key TEXT PRIMARY KEY,
+    fired_at  TEXT NOT NULL  -- ISO 8601 UTC
+);
+
+-- Full conversation log, both directions, for !search and the history
+-- extension. Unlike sent_messages it is not bounded. attachments holds
+-- newline-separated file paths.
+CREATE TABLE IF NOT EXISTS messages (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    conversation_id TEXT NOT NULL,
+    direction       TEXT NOT NULL,             -- "in" or "out"
+    sender          TEXT NOT NULL DEFAULT '',  -- backend user ID, '' for the bot
+    message_id      TEXT NOT NULL DEFAULT '',  -- backend message ID, '' if unknown
+    reply_to        TEXT NOT NULL DEFAULT '',  -- backend message ID this one replies to
+    source          TEXT NOT NULL DEFAULT '',  -- inbox source the reply answered, "user" for inbound
+    text            TEXT NOT NULL DEFAULT '',
+    attachments     TEXT NOT NULL DEFAULT '',
+    created_at      TEXT NOT NULL              -- ISO 8601 UTC
+);
+
+CREATE INDEX IF NOT EXISTS messages_conversation
+    ON messages (conversation_id, created_at);
+
+CREATE INDEX IF NOT EXISTS messages_message_id
+    ON messages (conversation_id, message_id);
+
+-- External-content FTS5 index over messages.text, kept in sync by triggers.
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts
+    USING fts5(text, content='messages', content_rowid='id');
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+    INSERT INTO messages_fts (rowid, text) VALUES (new.id, new.text);
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+    INSERT INTO messages_fts (messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+END;
+
+-- Per-conversation verbosity set with !verbose, !quiet and !tools. A
+-- conversation without a row uses OPENCROW_SHOW_TOOL_CALLS and
+-- OPENCROW_DEBUG_TIMING.
+CREATE TABLE IF NOT EXISTS conversation_settings (
+    conversation_id TEXT    PRIMARY KEY,
+    tool_calls      INTEGER NOT NULL DEFAULT 0,  -- relay tool calls to chat
+    thinking        INTEGER NOT NULL DEFAULT 0,  -- relay the model's thinking
+    debug_timing    INTEGER NOT NULL DEFAULT 0   -- append task duration to replies
+);
+
 CREATE TABLE IF NOT EXISTS inbox (
-    id         INTEGER PRIMARY KEY AUTOINCREMENT,
-    priority   INTEGER NOT NULL DEFAULT 2,  -- 0=user, 1=trigger, 2=heartbeat
-    source     TEXT    NOT NULL,             -- "user", "trigger", "heartbeat"
-    content    TEXT    NOT NULL DEFAULT '',
-    reply_to   TEXT    NOT NULL DEFAULT '',  -- backend message ID to reply to
-    created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    priority    INTEGER NOT NULL DEFAULT 2,  -- 0=user, 1=trigger, 2=heartbeat
+    source      TEXT    NOT NULL,             -- "user", "trigger", "heartbeat"
+    content     TEXT    NOT NULL DEFAULT '',
+    reply_to    TEXT    NOT NULL DEFAULT '',  -- backend message ID to reply to
+    created_at  TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    reminder_id INTEGER NOT NULL DEFAULT 0,   -- reminder_deliveries.id for fired reminders
+    -- Optional trigger metadata (structured trigger-pipe lines).
+    label           TEXT    NOT NULL DEFAULT '',  -- who sent the trigger, shown to the agent
+    dedup_key       TEXT    NOT NULL DEFAULT '',  -- at most one queued item per non-empty key
+    silent          INTEGER NOT NULL DEFAULT 0,   -- reply only if something needs attention
+    conversation_id TEXT    NOT NULL DEFAULT '',  -- target conversation, '' = current room
+    waiter_id       TEXT    NOT NULL DEFAULT '',  -- synchronous caller awaiting the reply (trigger.sock)
+    -- Who sent a user message, for the prompt envelope.
+    sender            TEXT NOT NULL DEFAULT '',  -- display name, else backend user ID
+    conversation_name TEXT NOT NULL DEFAULT ''   -- room or group name, '' if unknown
 );
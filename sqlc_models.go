@@ -4,19 +4,88 @@
 
 package main
 
+type CalendarFired struct {
+	EventKey string
+	FiredAt  string
+}
+
+type ConversationSettings struct {
+	ConversationID string
+	ToolCalls      int64
+	Thinking       int64
+	DebugTiming    int64
+}
+
+type CronRuns struct {
+	Name    string
+	LastRun string
+}
+
+type FeedEntries struct {
+	Feed   string
+	Guid   string
+	SeenAt string
+}
+
 type Inbox struct {
-	ID        int64
-	Priority  int64
-	Source    string
-	Content   string
-	ReplyTo   string
-	CreatedAt string
+	ID               int64
+	Priority         int64
+	Source           string
+	Content          string
+	ReplyTo          string
+	CreatedAt        string
+	ReminderID       int64
+	Label            string
+	DedupKey         string
+	Silent           int64
+	ConversationID   string
+	WaiterID         string
+	Sender           string
+	ConversationName string
+}
+
+type Messages struct {
+	ID             int64
+	ConversationID string
+	Direction      string
+	Sender         string
+	MessageID      string
+	ReplyTo        string
+	Source         string
+	Text           string
+	Attachments    string
+	CreatedAt      string
+}
+
+type MessagesFts struct {
+	Text string
+}
+
+type ReminderDeliveries struct {
+	ID             int64
+	FireAt         string
+	Prompt         string
+	Important      int64
+	Refires        int64
+	State          string
+	ConversationID string
+	MessageID      string
+	DeliveredAt    string
 }
 
 type Reminders struct {
-	ID     int64
-	FireAt string
-	Prompt string
+	ID        int64
+	FireAt    string
+	Prompt    string
+	Important int64
+}
+
+type ScheduledMessages struct {
+	ID             int64
+	SendAt         string
+	Text           string
+	Files          string
+	ConversationID string
 }
 
 type SentMessages struct {
@@ -24,3 +93,20 @@ type SentMessages struct {
 	MessageID      string
 	Text           string
 }
+
+type TriggerHistory struct {
+	DedupKey string
+	SeenAt   string
+}
+
+type WatchedFiles struct {
+	Rule    string
+	Path    string
+	ModTime string
+	Size    int64
+}
+
+type WatchRules struct {
+	Rule     string
+	SeededAt string
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// Values of OPENCROW_TOOL_RESULTS.
+const (
+	toolResultsOff      = "off"
+	toolResultsFailures = "failures"
+	toolResultsAll      = "all"
+)
+
+const (
+	defaultToolOutputTail     = 10
+	defaultToolDigestInterval = 30 * time.Second
+	// maxToolOutputChars bounds the failure tail however long its lines.
+	maxToolOutputChars = 1500
+	// statusEditInterval spaces out edits of the status message, so a
+	// burst of quick tools doesn't run into the server's rate limits.
+	statusEditInterval = time.Second
+	// maxStatusTools bounds the tools listed in a status message; older
+	// ones are only counted.
+	maxStatusTools = 15
+)
+
+// ToolsConfig controls how a turn's tool calls are shown in chat.
+type ToolsConfig struct {
+	Results          string        // OPENCROW_TOOL_RESULTS: off, failures (default) or all
+	OutputTail       int           // OPENCROW_TOOL_OUTPUT_TAIL, output lines shown for failures, default 10
+	DigestInterval   time.Duration // OPENCROW_TOOL_DIGEST_INTERVAL, default 30s; backends without edits
+	Summary          bool          // OPENCROW_TOOL_SUMMARY, summarize the turn's tools below the reply
+	Templates        toolTemplates // OPENCROW_TOOL_TEMPLATES_FILE over defaultToolTemplates
+	ProgressInterval time.Duration // OPENCROW_PROGRESS_INTERVAL, default 5m; 0 disables updates during long turns
+}
+
+// exitCodeRe finds the exit status pi's bash tool appends to the output
+// of a failed command ("Command exited with code 2").
+var exitCodeRe = regexp.MustCompile(`(?i)exit(?:ed with)? code:?\s*(-?\d+)`)
+
+// toolRun is one tool call of a turn.
+type toolRun struct {
+	call     ToolCallEvent
+	started  time.Time
+	elapsed  time.Duration
+	finished bool
+	result   ToolResultEvent
+	reported bool // listed as finished in a digest
+}
+
+// toolNotifier shows the tool calls of one turn. Backends with edits get
+// a single status message, edited as tools start and finish and
+// finalized before the reply; others get a digest at most every
+// DigestInterval. Events arrive on the goroutine running the turn, but an
+// edit held back by statusEditInterval is made from a timer, so it locks.
+type toolNotifier struct {
+	be             Backend
+	editor         backend.MessageEditor   // nil if the backend can't edit
+	redactor       backend.MessageRedactor // nil if the backend can't redact
+	flavor         backend.MarkdownFlavor
+	cfg            ToolsConfig
+	conversationID string
+	start          time.Time
+
+	mu       sync.Mutex
+	runs     []*toolRun
+	byID     map[string]*toolRun // running tools by tool call ID
+	statusID string              // the status message, "" until posted
+	lastPost time.Time           // last post or edit
+	flush    *time.Timer         // held-back edit, nil if none is due
+	closed   bool
+}
+
+func newToolNotifier(be Backend, cfg ToolsConfig, conversationID string) *toolNotifier {
+	n := &toolNotifier{
+		be:             be,
+		flavor:         be.MarkdownFlavor(),
+		cfg:            cfg,
+		conversationID: conversationID,
+		start:          time.Now(),
+		byID:           make(map[string]*toolRun),
+	}
+
+	if editor, ok := be.(backend.MessageEditor); ok {
+		n.editor = editor
+	}
+
+	if redactor, ok := be.(backend.MessageRedactor); ok {
+		n.redactor = redactor
+	}
+
+	return n
+}
+
+// started records a tool call and shows it.
+func (n *toolNotifier) started(ctx context.Context, evt ToolCallEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed || n.cfg.Templates.lookup(evt.ToolName).Hide {
+		return
+	}
+
+	r := &toolRun{call: evt, started: time.Now()}
+	n.runs = append(n.runs, r)
+
+	if evt.ID != "" {
+		n.byID[evt.ID] = r
+	}
+
+	n.update(ctx)
+}
+
+// finished records how a shown tool call ended.
+func (n *toolNotifier) finished(ctx context.Context, evt ToolResultEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	r, ok := n.byID[evt.ID]
+	if !ok || n.closed {
+		return
+	}
+
+	delete(n.byID, evt.ID)
+
+	r.finished = true
+	r.result = evt
+	r.elapsed = time.Since(r.started)
+
+	n.update(ctx)
+}
+
+// done finalizes the turn's status before the reply is sent. Later
+// events are ignored.
+func (n *toolNotifier) done(ctx context.Context) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return
+	}
+
+	n.closed = true
+	n.stopFlush()
+
+	if len(n.runs) == 0 {
+		return
+	}
+
+	if n.editor != nil && n.statusID != "" {
+		n.edit(ctx, n.renderStatus(true))
+
+		return
+	}
+
+	n.postDigest(ctx, true)
+}
+
+// discard closes the turn's status when it ends without a reply, so
+// nothing is left behind: the status message is redacted where the
+// backend supports it and no final digest is posted. Digests already
+// posted during the turn stay.
+func (n *toolNotifier) discard(ctx context.Context) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return
+	}
+
+	n.closed = true
+	n.stopFlush()
+
+	if n.statusID == "" || n.redactor == nil {
+		return
+	}
+
+	if err := n.redactor.RedactMessage(ctx, n.conversationID, n.statusID); err != nil {
+		slog.Warn("failed to redact tool status message", "conversation", n.conversationID, "error", err)
+	}
+}
+
+// update edits the status message or posts a digest. An edit too soon
+// after the last one is made once statusEditInterval has passed, so the
+// message still ends up showing the latest state.
+func (n *toolNotifier) update(ctx context.Context) {
+	if n.editor == nil {
+		if n.lastPost.IsZero() || time.Since(n.lastPost) >= n.cfg.DigestInterval {
+			n.postDigest(ctx, false)
+		}
+
+		return
+	}
+
+	if n.statusID == "" {
+		n.statusID = n.be.SendMessage(ctx, n.conversationID, n.renderStatus(false), "")
+		n.lastPost = time.Now()
+
+		if n.statusID == "" {
+			// Nothing to edit: the rest of the turn goes out as digests.
+			n.editor = nil
+			n.markReported()
+		}
+
+		return
+	}
+
+	wait := statusEditInterval - time.Since(n.lastPost)
+	if wait <= 0 {
+		n.edit(ctx, n.renderStatus(false))
+
+		return
+	}
+
+	if n.flush == nil {
+		n.flush = time.AfterFunc(wait, func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+
+			if n.flush == nil || n.closed {
+				return
+			}
+
+			n.edit(context.Background(), n.renderStatus(false))
+		})
+	}
+}
+
+func (n *toolNotifier) edit(ctx context.Context, text string) {
+	n.stopFlush()
+	n.lastPost = time.Now()
+
+	if err := n.editor.EditMessage(ctx, n.conversationID, n.statusID, text); err != nil {
+		slog.Warn("failed to edit tool status message", "conversation", n.conversationID, "error", err)
+	}
+}
+
+// stopFlush cancels a held-back edit.
+func (n *toolNotifier) stopFlush() {
+	if n.flush != nil {
+		n.flush.Stop()
+		n.flush = nil
+	}
+}
+
+// postDigest sends the tools not yet reported as finished. Running ones
+// are listed again once they finish.
+func (n *toolNotifier) postDigest(ctx context.Context, final bool) {
+	var pending []*toolRun
+
+	for _, r := range n.runs {
+		if !r.reported {
+			pending = append(pending, r)
+		}
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+
+	if final {
+		sb.WriteString(n.summary() + "\n")
+	}
+
+	for _, r := range pending {
+		sb.WriteString(n.runLine(r, final) + "\n")
+	}
+
+	sb.WriteString(n.failureTail(pending))
+
+	n.markReported()
+	n.lastPost = time.Now()
+	n.be.SendMessage(ctx, n.conversationID, strings.TrimRight(sb.String(), "\n"), "")
+}
+
+func (n *toolNotifier) markReported() {
+	for _, r := range n.runs {
+		r.reported = r.finished
+	}
+}
+
+// renderStatus renders the status message: the tools so far, the
+// current step and the elapsed time; or, once final, a summary line
+// above the tools.
+func (n *toolNotifier) renderStatus(final bool) string {
+	var sb strings.Builder
+
+	if final {
+		sb.WriteString(n.summary() + "\n")
+	}
+
+	runs := n.runs
+	if len(runs) > maxStatusTools {
+		fmt.Fprintf(&sb, "… %d earlier\n", len(runs)-maxStatusTools)
+		runs = runs[len(runs)-maxStatusTools:]
+	}
+
+	var current *toolRun
+
+	if !final {
+		for _, r := range runs {
+			if !r.finished {
+				current = r
+			}
+		}
+	}
+
+	for _, r := range runs {
+		if r != current {
+			sb.WriteString(n.runLine(r, final) + "\n")
+		}
+	}
+
+	if current != nil {
+		sb.WriteString(formatToolCall(current.call, n.cfg.Templates.lookup(current.call.ToolName), n.flavor) + "\n")
+	}
+
+	if !final {
+		sb.WriteString("⏱ " + formatElapsed(time.Since(n.start)) + "\n")
+	}
+
+	sb.WriteString(n.failureTail(runs))
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// summary counts the turn's tools: "🔧 6 tools · 1 failed · 2m13s".
+func (n *toolNotifier) summary() string {
+	failed := 0
+
+	for _, r := range n.runs {
+		if r.result.IsError {
+			failed++
+		}
+	}
+
+	s := "🔧 1 tool"
+	if len(n.runs) != 1 {
+		s = fmt.Sprintf("🔧 %d tools", len(n.runs))
+	}
+
+	if failed > 0 {
+		s += fmt.Sprintf(" · %d failed", failed)
+	}
+
+	return s + " · " + formatElapsed(time.Since(n.start))
+}
+
+// runLine renders one tool with its state. Results decides how much is
+// said about how it ended.
+func (n *toolNotifier) runLine(r *toolRun, final bool) string {
+	label := toolLabel(r.call, n.cfg.Templates.lookup(r.call.ToolName), n.flavor)
+
+	switch {
+	case !r.finished && final:
+		return "⏹ " + label
+	case !r.finished:
+		return "⏳ " + label
+	case r.result.IsError && n.cfg.Results != toolResultsOff:
+		return fmt.Sprintf("✗ %s — %s · %s", label, toolStatus(r.result), formatElapsed(r.elapsed))
+	case r.result.IsError:
+		return "✗ " + label
+	case n.cfg.Results == toolResultsAll:
+		return fmt.Sprintf("✓ %s · %s", label, formatElapsed(r.elapsed))
+	default:
+		return "✓ " + label
+	}
+}
+
+// failureTail renders the output tail of the last failed tool in runs.
+func (n *toolNotifier) failureTail(runs []*toolRun) string {
+	if n.cfg.Results == toolResultsOff {
+		return ""
+	}
+
+	for i := len(runs) - 1; i >= 0; i-- {
+		if !runs[i].result.IsError {
+			continue
+		}
+
+		output := outputTail(runs[i].result.Output, n.cfg.OutputTail)
+		if output == "" {
+			return ""
+		}
+
+		if n.flavor == backend.MarkdownNone {
+			return output
+		}
+
+		return "```\n" + output + "\n```"
+	}
+
+	return ""
+}
+
+// toolStatus says how a failed tool ended: its exit code if known.
+func toolStatus(evt ToolResultEvent) string {
+	if m := exitCodeRe.FindAllStringSubmatch(evt.Output, -1); m != nil {
+		return "exited with code " + m[len(m)-1][1]
+	}
+
+	return "failed"
+}
+
+// outputTail returns the last n lines of output, at most
+// maxToolOutputChars of them.
+func outputTail(output string, n int) string {
+	output = strings.Trim(output, "\n")
+	if n <= 0 || strings.TrimSpace(output) == "" {
+		return ""
+	}
+
+	lines := strings.Split(output, "\n")
+	cut := len(lines) > n
+
+	if cut {
+		lines = lines[len(lines)-n:]
+	}
+
+	output = strings.Join(lines, "\n")
+	if r := []rune(output); len(r) > maxToolOutputChars {
+		output = string(r[len(r)-maxToolOutputChars:])
+		cut = true
+	}
+
+	if cut {
+		output = "…\n" + output
+	}
+
+	return output
+}
+
+// formatElapsed renders d at a precision that suits its length: "850ms",
+// "3.4s", "2m13s".
+func formatElapsed(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}
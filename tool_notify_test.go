@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// editingBackend is a mockBackend that returns message IDs and supports
+// edits and redactions, like Matrix and Signal.
+type editingBackend struct {
+	mockBackend
+
+	edits    []editCall
+	redacted []string
+}
+
+type editCall struct {
+	messageID string
+	text      string
+}
+
+func (e *editingBackend) SendMessage(ctx context.Context, conversationID string, text string, replyToID string) string {
+	e.mockBackend.SendMessage(ctx, conversationID, text, replyToID)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return fmt.Sprintf("msg-%d", len(e.sentMessages))
+}
+
+func (e *editingBackend) EditMessage(_ context.Context, _, messageID, text string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.edits = append(e.edits, editCall{messageID, text})
+
+	return nil
+}
+
+func (e *editingBackend) RedactMessage(_ context.Context, _, messageID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.redacted = append(e.redacted, messageID)
+
+	return nil
+}
+
+func TestToolLabel(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		evt    ToolCallEvent
+		flavor backend.MarkdownFlavor
+		want   string
+	}{
+		{ToolCallEvent{ToolName: "bash", Args: map[string]any{"command": "make test"}}, backend.MarkdownFull, "bash `make test`"},
+		{ToolCallEvent{ToolName: "bash", Args: map[string]any{"command": "cd x\nmake"}}, backend.MarkdownNone, "bash cd x …"},
+		{ToolCallEvent{ToolName: "read", Args: map[string]any{"path": "/etc/hosts"}}, backend.MarkdownBasic, "reading `/etc/hosts`"},
+		{ToolCallEvent{ToolName: "grep", Args: map[string]any{"pattern": "TODO"}}, backend.MarkdownFull, "searching for `TODO`"},
+		{ToolCallEvent{ToolName: "mcp_thing", Args: map[string]any{"query": "x"}}, backend.MarkdownFull, "mcp_thing"},
+	}
+
+	for _, tc := range cases {
+		if got := toolLabel(tc.evt, defaultToolTemplates.lookup(tc.evt.ToolName), tc.flavor); got != tc.want {
+			t.Errorf("toolLabel(%v) = %q, want %q", tc.evt.Args, got, tc.want)
+		}
+	}
+}
+
+func TestOutputTail(t *testing.T) {
+	t.Parallel()
+
+	if got, want := outputTail("a\nb\nc\nd\n", 2), "…\nc\nd"; got != want {
+		t.Errorf("outputTail = %q, want %q", got, want)
+	}
+
+	if got := outputTail("a\nb", 0); got != "" {
+		t.Errorf("outputTail with n=0 = %q, want empty", got)
+	}
+
+	if got := toolStatus(ToolResultEvent{Output: "FAIL\n\nCommand exited with code 2"}); got != "exited with code 2" {
+		t.Errorf("toolStatus = %q", got)
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	t.Parallel()
+
+	for d, want := range map[time.Duration]string{
+		850 * time.Millisecond:                 "850ms",
+		3449 * time.Millisecond:                "3.4s",
+		2*time.Minute + 13400*time.Millisecond: "2m13s",
+	} {
+		if got := formatElapsed(d); got != want {
+			t.Errorf("formatElapsed(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+// runTurn feeds a turn of two bash calls, the second failing, to a
+// notifier and finalizes it.
+func runTurn(be Backend, cfg ToolsConfig) {
+	ctx := context.Background()
+	n := newToolNotifier(be, cfg, testRoom)
+
+	n.started(ctx, ToolCallEvent{ID: "1", ToolName: "bash", Args: map[string]any{"command": "go vet"}})
+	n.finished(ctx, ToolResultEvent{ID: "1", ToolName: "bash"})
+	n.started(ctx, ToolCallEvent{ID: "2", ToolName: "bash", Args: map[string]any{"command": "make test"}})
+	n.finished(ctx, ToolResultEvent{ID: "2", ToolName: "bash", IsError: true, Output: "FAIL x\nCommand exited with code 2"})
+	n.done(ctx)
+	n.started(ctx, ToolCallEvent{ID: "3", ToolName: "bash"}) // after the reply: ignored
+}
+
+func TestToolNotifier_StatusMessage(t *testing.T) {
+	t.Parallel()
+
+	eb := &editingBackend{mockBackend: mockBackend{markdownFlavor: backend.MarkdownFull}}
+	runTurn(eb, ToolsConfig{Results: toolResultsFailures, OutputTail: 5})
+
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if len(eb.sentMessages) != 1 {
+		t.Fatalf("sent %d messages, want 1 status message", len(eb.sentMessages))
+	}
+
+	if first := eb.sentMessages[0].text; !strings.Contains(first, "```sh\ngo vet\n```") || !strings.Contains(first, "⏱") {
+		t.Errorf("first status = %q, want the current step and elapsed time", first)
+	}
+
+	if len(eb.edits) == 0 {
+		t.Fatal("status message never edited")
+	}
+
+	final := eb.edits[len(eb.edits)-1]
+	if final.messageID != "msg-1" {
+		t.Errorf("edited %q, want msg-1", final.messageID)
+	}
+
+	for _, want := range []string{
+		"🔧 2 tools · 1 failed · ",
+		"\n✓ bash `go vet`\n",
+		"\n✗ bash `make test` — exited with code 2 · ",
+		"```\nFAIL x\nCommand exited with code 2\n```",
+	} {
+		if !strings.Contains(final.text, want) {
+			t.Errorf("final status %q missing %q", final.text, want)
+		}
+	}
+
+	if strings.Contains(final.text, "⏱") {
+		t.Errorf("final status %q still shows the running clock", final.text)
+	}
+}
+
+// An edit held back by statusEditInterval is still made, so the status
+// doesn't stay at "⏳" while the model thinks after a quick tool.
+func TestToolNotifier_FlushesHeldBackEdit(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	eb := &editingBackend{}
+	n := newToolNotifier(eb, ToolsConfig{}, testRoom)
+
+	n.started(ctx, ToolCallEvent{ID: "1", ToolName: "bash", Args: map[string]any{"command": "go vet"}})
+	n.finished(ctx, ToolResultEvent{ID: "1", ToolName: "bash"})
+
+	edited := func() string {
+		eb.mu.Lock()
+		defer eb.mu.Unlock()
+
+		if len(eb.edits) == 0 {
+			return ""
+		}
+
+		return eb.edits[len(eb.edits)-1].text
+	}
+
+	for deadline := time.Now().Add(3 * statusEditInterval); edited() == "" && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := edited(); !strings.Contains(got, "✓ bash go vet") || !strings.Contains(got, "⏱") {
+		t.Errorf("status after the held-back edit = %q, want the finished tool and elapsed time", got)
+	}
+
+	n.done(ctx)
+}
+
+// A heartbeat turn whose reply is suppressed takes its status message
+// back instead of finalizing it.
+func TestWorker_SuppressedReplyRedactsStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+	eb := &editingBackend{}
+	w.SetBackend(eb)
+
+	app := NewApp(eb, w, w.inbox, newTestDB(ctx, t))
+	w.SetApp(app)
+
+	must(t, app.settings.Set(ctx, "room", Verbosity{ToolCalls: true}))
+	must(t, os.WriteFile(filepath.Join(w.piCfg.WorkingDir, "HEARTBEAT.md"), []byte("- use-tools heartbeat-ok\n"), 0o600))
+
+	w.processPrompt(ctx, Inbox{Source: sourceHeartbeat})
+
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if len(eb.sentMessages) != 1 {
+		t.Fatalf("sent %v, want only the status message", eb.sentMessages)
+	}
+
+	if !slices.Equal(eb.redacted, []string{"msg-1"}) {
+		t.Errorf("redacted %q, want the status message msg-1", eb.redacted)
+	}
+
+	for _, e := range eb.edits {
+		if strings.HasPrefix(e.text, "🔧") {
+			t.Errorf("status finalized as %q although the reply was suppressed", e.text)
+		}
+	}
+}
+
+func TestToolNotifier_Digest(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		cfg      ToolsConfig
+		want     []string
+		wantLast string
+	}{
+		{
+			// Every event goes out, and the final flush has nothing left.
+			name: "no rate limit",
+			cfg:  ToolsConfig{Results: toolResultsAll},
+			want: []string{
+				"⏳ bash go vet",
+				"✓ bash go vet · ",
+				"⏳ bash make test",
+				"✗ bash make test — exited with code 2 · ",
+			},
+		},
+		{
+			// Only the first event goes out at once; the rest waits
+			// for the end of the turn.
+			name:     "rate limited",
+			cfg:      ToolsConfig{Results: toolResultsOff, DigestInterval: time.Hour},
+			want:     []string{"⏳ bash go vet", ""},
+			wantLast: "🔧 2 tools · 1 failed · .*\n✓ bash go vet\n✗ bash make test$",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mb := &mockBackend{}
+			runTurn(mb, tc.cfg)
+
+			mb.mu.Lock()
+			defer mb.mu.Unlock()
+
+			if len(mb.sentMessages) != len(tc.want) {
+				t.Fatalf("sent %d digests, want %d: %v", len(mb.sentMessages), len(tc.want), mb.sentMessages)
+			}
+
+			for i, want := range tc.want {
+				if !strings.HasPrefix(mb.sentMessages[i].text, want) {
+					t.Errorf("digest %d = %q, want it to start with %q", i, mb.sentMessages[i].text, want)
+				}
+			}
+
+			if tc.wantLast != "" {
+				last := mb.sentMessages[len(mb.sentMessages)-1].text
+				if !regexp.MustCompile(tc.wantLast).MatchString(last) {
+					t.Errorf("final digest = %q, want match for %q", last, tc.wantLast)
+				}
+			}
+		})
+	}
+}
+
+// A silent turn also takes back its progress updates, which quote the
+// model's text, instead of leaving a "done" line behind.
+func TestWorker_SuppressedReplyRedactsProgress(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+	w.toolsCfg.ProgressInterval = 20 * time.Millisecond
+	eb := &editingBackend{}
+	w.SetBackend(eb)
+
+	app := NewApp(eb, w, w.inbox, newTestDB(ctx, t))
+	w.SetApp(app)
+
+	must(t, os.WriteFile(filepath.Join(w.piCfg.WorkingDir, "HEARTBEAT.md"), []byte("- slow-turn heartbeat-ok\n"), 0o600))
+
+	w.processPrompt(ctx, Inbox{Source: sourceHeartbeat})
+
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if len(eb.sentMessages) != 1 {
+		t.Fatalf("sent %v, want only the progress message", eb.sentMessages)
+	}
+
+	if !slices.Equal(eb.redacted, []string{"msg-1"}) {
+		t.Errorf("redacted %q, want the progress message msg-1", eb.redacted)
+	}
+
+	for _, e := range eb.edits {
+		if strings.HasPrefix(e.text, "✓ done") {
+			t.Errorf("progress finished as %q although the reply was suppressed", e.text)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// maxSummaryFiles bounds the changed files named in a tool summary.
+const maxSummaryFiles = 5
+
+// toolActivity collects the tool calls of one turn for the summary sent
+// with the reply (OPENCROW_TOOL_SUMMARY). It records every call, whether
+// or not tool calls are shown live.
+type toolActivity struct {
+	start   time.Time
+	calls   int
+	counts  map[string]int
+	names   []string          // tool names in first-use order
+	editing map[string]string // paths of running edit and write calls by ID
+	files   []string          // changed paths in first-change order
+}
+
+func newToolActivity() *toolActivity {
+	return &toolActivity{
+		start:   time.Now(),
+		counts:  make(map[string]int),
+		editing: make(map[string]string),
+	}
+}
+
+func (a *toolActivity) started(evt ToolCallEvent) {
+	if a.counts[evt.ToolName] == 0 {
+		a.names = append(a.names, evt.ToolName)
+	}
+
+	a.calls++
+	a.counts[evt.ToolName]++
+
+	if evt.ToolName != "edit" && evt.ToolName != "write" {
+		return
+	}
+
+	if path, ok := evt.Args["path"].(string); ok && path != "" {
+		a.editing[evt.ID] = path
+	}
+}
+
+func (a *toolActivity) finished(evt ToolResultEvent) {
+	path, ok := a.editing[evt.ID]
+	if !ok {
+		return
+	}
+
+	delete(a.editing, evt.ID)
+
+	if !evt.IsError && !slices.Contains(a.files, path) {
+		a.files = append(a.files, path)
+	}
+}
+
+// replyFooter is shown below a reply but kept out of the history.
+type replyFooter struct {
+	Summary string // always shown
+	Details string // collapsed below Summary where the backend supports it
+	Line    string // both in one line, for backends that can't collapse
+}
+
+// footer summarizes the turn: "used 14 tools: 6 bash, 5 read, 3 edit ·
+// files changed: a.go, b.go · 2m13s". Paths under workingDir are shown
+// relative to it. Empty if no tools were used.
+func (a *toolActivity) footer(workingDir string, flavor backend.MarkdownFlavor) replyFooter {
+	if a.calls == 0 {
+		return replyFooter{}
+	}
+
+	used := "used 1 tool"
+	if a.calls != 1 {
+		used = fmt.Sprintf("used %d tools", a.calls)
+	}
+
+	names := slices.Clone(a.names)
+	slices.SortStableFunc(names, func(x, y string) int { return cmp.Compare(a.counts[y], a.counts[x]) })
+
+	counts := make([]string, len(names))
+	for i, name := range names {
+		counts[i] = fmt.Sprintf("%d %s", a.counts[name], name)
+	}
+
+	elapsed := formatElapsed(time.Since(a.start))
+
+	details := []string{strings.Join(counts, ", ")}
+	if files := a.changedFiles(workingDir, flavor); files != "" {
+		details = append(details, "files changed: "+files)
+	}
+
+	return replyFooter{
+		Summary: "🔧 " + used + " · " + elapsed,
+		Details: strings.Join(details, "\n\n"),
+		Line:    "🔧 " + used + ": " + strings.Join(details, " · ") + " · " + elapsed,
+	}
+}
+
+func (a *toolActivity) changedFiles(workingDir string, flavor backend.MarkdownFlavor) string {
+	names := make([]string, 0, maxSummaryFiles)
+
+	for _, path := range a.files[:min(len(a.files), maxSummaryFiles)] {
+		if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+
+		if flavor != backend.MarkdownNone {
+			path = "`" + path + "`"
+		}
+
+		names = append(names, path)
+	}
+
+	s := strings.Join(names, ", ")
+	if more := len(a.files) - maxSummaryFiles; more > 0 {
+		s += fmt.Sprintf(" and %d more", more)
+	}
+
+	return s
+}
+
+// sendWithFooter sends text with footer below it: collapsed where the
+// backend supports it, else as a last line.
+func (a *App) sendWithFooter(ctx context.Context, conversationID, text, replyToID string, footer replyFooter) string {
+	if footer.Summary == "" {
+		return a.backend.SendMessage(ctx, conversationID, text, replyToID)
+	}
+
+	if ds, ok := a.backend.(backend.DetailsSender); ok {
+		return ds.SendMessageWithDetails(ctx, conversationID, text, footer.Summary, footer.Details, replyToID)
+	}
+
+	return a.backend.SendMessage(ctx, conversationID, text+"\n\n"+footer.Line, replyToID)
+}
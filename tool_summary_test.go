@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// detailsBackend is a mockBackend that can collapse details, like Matrix.
+type detailsBackend struct {
+	mockBackend
+
+	summary, details string
+}
+
+func (d *detailsBackend) SendMessageWithDetails(ctx context.Context, conversationID, text, summary, details, replyToID string) string {
+	d.summary, d.details = summary, details
+
+	return d.SendMessage(ctx, conversationID, text, replyToID)
+}
+
+func TestToolActivity_Footer(t *testing.T) {
+	t.Parallel()
+
+	a := newToolActivity()
+
+	call := func(id, tool, key, arg string, failed bool) {
+		a.started(ToolCallEvent{ID: id, ToolName: tool, Args: map[string]any{key: arg}})
+		a.finished(ToolResultEvent{ID: id, ToolName: tool, IsError: failed})
+	}
+
+	call("1", "read", "path", "/work/a.go", false)
+	call("2", "bash", "command", "go test", false)
+	call("3", "edit", "path", "/work/a.go", false)
+	call("4", "bash", "command", "go vet", false)
+	call("5", "write", "path", "/etc/motd", false)
+	call("6", "edit", "path", "/work/b.go", true) // failed: not changed
+	call("7", "edit", "path", "/work/a.go", false)
+
+	cases := []struct {
+		flavor backend.MarkdownFlavor
+		want   string
+	}{
+		{backend.MarkdownNone, `^🔧 used 7 tools: 3 edit, 2 bash, 1 read, 1 write · files changed: a.go, /etc/motd · \S+$`},
+		{backend.MarkdownFull, "^🔧 used 7 tools: .* · files changed: `a.go`, `/etc/motd` · \\S+$"},
+	}
+
+	for _, tc := range cases {
+		if got := a.footer("/work", tc.flavor).Line; !regexp.MustCompile(tc.want).MatchString(got) {
+			t.Errorf("footer(%d) = %q, want match for %q", tc.flavor, got, tc.want)
+		}
+	}
+
+	f := a.footer("/work", backend.MarkdownFull)
+	if !regexp.MustCompile(`^🔧 used 7 tools · \S+$`).MatchString(f.Summary) {
+		t.Errorf("Summary = %q", f.Summary)
+	}
+
+	if want := "3 edit, 2 bash, 1 read, 1 write\n\nfiles changed: `a.go`, `/etc/motd`"; f.Details != want {
+		t.Errorf("Details = %q, want %q", f.Details, want)
+	}
+
+	if f := newToolActivity().footer("/work", backend.MarkdownFull); f != (replyFooter{}) {
+		t.Errorf("footer without tools = %+v, want empty", f)
+	}
+}
+
+func TestApp_SendWithFooter(t *testing.T) {
+	t.Parallel()
+
+	footer := replyFooter{Summary: "🔧 used 1 tool · 2s", Details: "1 bash", Line: "🔧 used 1 tool: 1 bash · 2s"}
+
+	app, mb := newTestApp(t)
+	app.sendReplyWithFiles(context.Background(), testRoom, "Done.", "", sourceUser, footer)
+
+	if got, want := mb.sentMessages[0].text, "Done.\n\n🔧 used 1 tool: 1 bash · 2s"; got != want {
+		t.Errorf("inline footer: sent %q, want %q", got, want)
+	}
+
+	db := &detailsBackend{}
+	app, _ = newTestAppWithBackend(t, &db.mockBackend)
+	app.backend = db
+	app.sendReplyWithFiles(context.Background(), testRoom, "Done.", "", sourceUser, footer)
+
+	if got := db.sentMessages[0].text; got != "Done." {
+		t.Errorf("collapsed footer: text %q, want the bare reply", got)
+	}
+
+	if db.summary != footer.Summary || db.details != footer.Details {
+		t.Errorf("details = %q / %q, want %q / %q", db.summary, db.details, footer.Summary, footer.Details)
+	}
+
+	// The history keeps the reply without the footer.
+	hits, err := app.history.Search(context.Background(), testRoom, "tool", 10)
+	if err != nil || len(hits) != 0 {
+		t.Errorf("history search for footer text = %v, %v; want no hits", hits, err)
+	}
+}
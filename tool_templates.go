@@ -0,0 +1,179 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+// defaultToolArgMax bounds each argument shown in a tool call's line.
+const defaultToolArgMax = 60
+
+// ToolTemplate says how calls of one tool are shown in chat.
+type ToolTemplate struct {
+	Emoji string   `json:"emoji,omitempty"` // default 🔧
+	Verb  string   `json:"verb,omitempty"`  // e.g. "reading", default the tool name
+	Args  []string `json:"args,omitempty"`  // arguments shown after the verb, if present
+	Max   int      `json:"max,omitempty"`   // runes per argument, default 60
+	Block bool     `json:"block,omitempty"` // show the first argument as a code block
+	Lang  string   `json:"lang,omitempty"`  // language hint of the block
+	Hide  bool     `json:"hide,omitempty"`  // don't show calls of this tool at all
+}
+
+// toolTemplates maps tool names to templates; "*" applies to tools
+// without one of their own.
+type toolTemplates map[string]ToolTemplate
+
+// defaultToolTemplates covers pi's built-in tools and opencrow's
+// extensions.
+var defaultToolTemplates = toolTemplates{
+	"bash":             {Args: []string{"command"}, Block: true, Lang: "sh"},
+	"read":             {Emoji: "📄", Verb: "reading", Args: []string{"path"}},
+	"edit":             {Emoji: "✏️", Verb: "editing", Args: []string{"path"}},
+	"write":            {Emoji: "📝", Verb: "writing", Args: []string{"path"}},
+	"grep":             {Emoji: "🔍", Verb: "searching for", Args: []string{"pattern", "path"}},
+	"find":             {Emoji: "🔍", Verb: "finding", Args: []string{"pattern", "path"}},
+	"ls":               {Emoji: "📂", Verb: "listing", Args: []string{"path"}},
+	"fetch":            {Emoji: "🌐", Verb: "fetching", Args: []string{"url"}},
+	"web_fetch":        {Emoji: "🌐", Verb: "fetching", Args: []string{"url"}},
+	"web_search":       {Emoji: "🌐", Verb: "searching the web for", Args: []string{"query"}},
+	"task":             {Emoji: "🤖", Verb: "delegating", Args: []string{"description"}},
+	"history_search":   {Emoji: "🗂", Verb: "searching history for", Args: []string{"query"}},
+	"memory_search":    {Emoji: "🧠", Verb: "recalling", Args: []string{"query"}},
+	"remind_at":        {Emoji: "⏰", Verb: "setting a reminder for", Args: []string{"when"}},
+	"remind_list":      {Emoji: "⏰", Verb: "listing reminders"},
+	"remind_cancel":    {Emoji: "⏰", Verb: "cancelling reminder", Args: []string{"id"}},
+	"schedule_message": {Emoji: "📅", Verb: "scheduling a message for", Args: []string{"when"}},
+	"scheduled_list":   {Emoji: "📅", Verb: "listing scheduled messages"},
+	"scheduled_cancel": {Emoji: "📅", Verb: "cancelling scheduled message", Args: []string{"id"}},
+}
+
+// loadToolTemplates reads a JSON object of templates by tool name. Each
+// entry overrides only the fields it sets, so {"bash": {"hide": true}}
+// keeps the rest of the built-in bash template.
+func loadToolTemplates(path string) (toolTemplates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OPENCROW_TOOL_TEMPLATES_FILE: %w", err)
+	}
+
+	var overrides map[string]json.RawMessage
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parsing OPENCROW_TOOL_TEMPLATES_FILE: %w", err)
+	}
+
+	templates := maps.Clone(defaultToolTemplates)
+
+	var errs error
+
+	for name, raw := range overrides {
+		t := templates[name]
+		if err := json.Unmarshal(raw, &t); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("tool %q: %w", name, err))
+
+			continue
+		}
+
+		if t.Max < 0 {
+			errs = errors.Join(errs, fmt.Errorf("tool %q: max must not be negative", name))
+		}
+
+		templates[name] = t
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return templates, nil
+}
+
+// lookup returns the template for a tool, with defaults filled in. A nil
+// table means the built-in one.
+func (t toolTemplates) lookup(name string) ToolTemplate {
+	if t == nil {
+		t = defaultToolTemplates
+	}
+
+	tmpl, ok := t[name]
+	if !ok {
+		tmpl = t["*"]
+	}
+
+	tmpl.Emoji = cmp.Or(tmpl.Emoji, "🔧")
+	tmpl.Verb = cmp.Or(tmpl.Verb, name)
+	tmpl.Max = cmp.Or(tmpl.Max, defaultToolArgMax)
+
+	return tmpl
+}
+
+// formatToolCall renders a tool call as its status message shows the
+// current step. The Markdown flavor controls whether arguments are
+// wrapped in fenced code blocks / inline backticks (and whether fences
+// carry a language hint) so backends that do not render Markdown do not
+// leak raw syntax.
+func formatToolCall(evt ToolCallEvent, tmpl ToolTemplate, flavor backend.MarkdownFlavor) string {
+	if !tmpl.Block || len(tmpl.Args) == 0 {
+		return tmpl.Emoji + " " + toolLabel(evt, tmpl, flavor)
+	}
+
+	arg, ok := evt.Args[tmpl.Args[0]].(string)
+	if !ok || strings.TrimSpace(arg) == "" {
+		return tmpl.Emoji + " " + tmpl.Verb
+	}
+
+	switch flavor {
+	case backend.MarkdownFull:
+		return fmt.Sprintf("%s\n```%s\n%s\n```", tmpl.Emoji, tmpl.Lang, arg)
+	case backend.MarkdownBasic:
+		// No language hint: some clients (e.g. Nostr/0xchat)
+		// render the hint literally instead of hiding it.
+		return fmt.Sprintf("%s\n```\n%s\n```", tmpl.Emoji, arg)
+	case backend.MarkdownNone:
+		return tmpl.Emoji + " " + arg
+	default:
+		return tmpl.Emoji + " " + arg
+	}
+}
+
+// toolLabel names a tool call in one line: the verb and the template's
+// arguments, each cut to its first line and tmpl.Max runes. For
+// templates without a verb, that's "bash `make test`".
+func toolLabel(evt ToolCallEvent, tmpl ToolTemplate, flavor backend.MarkdownFlavor) string {
+	parts := []string{tmpl.Verb}
+
+	for _, key := range tmpl.Args {
+		var arg string
+
+		switch v := evt.Args[key].(type) {
+		case string:
+			arg = v
+		case float64, bool:
+			arg = fmt.Sprint(v)
+		}
+
+		arg, _, multiline := strings.Cut(strings.TrimSpace(arg), "\n")
+		if arg == "" {
+			continue
+		}
+
+		arg = truncateRunes(arg, tmpl.Max)
+		if multiline && !strings.HasSuffix(arg, "…") {
+			arg += " …"
+		}
+
+		if flavor != backend.MarkdownNone {
+			arg = "`" + arg + "`"
+		}
+
+		parts = append(parts, arg)
+	}
+
+	return strings.Join(parts, " ")
+}
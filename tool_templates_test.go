@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pinpox/opencrow/backend"
+)
+
+func TestFormatToolCall(t *testing.T) {
+	t.Parallel()
+
+	bash := ToolCallEvent{ToolName: "bash", Args: map[string]any{"command": "ls -la"}}
+	read := ToolCallEvent{ToolName: "read", Args: map[string]any{"path": "/etc/hosts"}}
+
+	check := func(evt ToolCallEvent, flavor backend.MarkdownFlavor, want string) {
+		t.Helper()
+
+		if got := formatToolCall(evt, defaultToolTemplates.lookup(evt.ToolName), flavor); got != want {
+			t.Errorf("formatToolCall(%s, %d) = %q, want %q", evt.ToolName, flavor, got, want)
+		}
+	}
+
+	check(bash, backend.MarkdownFull, "🔧\n```sh\nls -la\n```")
+	check(bash, backend.MarkdownBasic, "🔧\n```\nls -la\n```")
+	check(bash, backend.MarkdownNone, "🔧 ls -la")
+	check(read, backend.MarkdownBasic, "📄 reading `/etc/hosts`")
+	check(read, backend.MarkdownNone, "📄 reading /etc/hosts")
+	check(ToolCallEvent{ToolName: "bash"}, backend.MarkdownFull, "🔧 bash")
+}
+
+func TestLoadToolTemplates(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		json    string
+		wantErr string
+		check   func(t *testing.T, tt toolTemplates)
+	}{
+		{
+			name: "overrides merge into defaults",
+			json: `{"read": {"emoji": "👀", "max": 10}, "ls": {"hide": true}, "*": {"emoji": "⚙️"}}`,
+			check: func(t *testing.T, tt toolTemplates) {
+				t.Helper()
+
+				read := tt.lookup("read")
+				evt := ToolCallEvent{ToolName: "read", Args: map[string]any{"path": "/a/very/long/path/to/file"}}
+
+				if got, want := formatToolCall(evt, read, backend.MarkdownNone), "👀 reading /a/very/lo…"; got != want {
+					t.Errorf("read = %q, want %q", got, want)
+				}
+
+				if !tt.lookup("ls").Hide {
+					t.Error("ls not hidden")
+				}
+
+				if got := tt.lookup("mcp_thing"); got.Emoji != "⚙️" || got.Verb != "mcp_thing" {
+					t.Errorf("unknown tool = %+v, want the \"*\" emoji and its name", got)
+				}
+
+				if got := tt.lookup("bash"); !got.Block {
+					t.Error("bash lost its built-in template")
+				}
+			},
+		},
+		{name: "bad json", json: `{"read": [1]}`, wantErr: `tool "read"`},
+		{name: "negative max", json: `{"bash": {"max": -1}}`, wantErr: "max must not be negative"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "tools.json")
+			must(t, os.WriteFile(path, []byte(tc.json), 0o600))
+
+			tt, err := loadToolTemplates(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+
+				return
+			}
+
+			must(t, err)
+			tc.check(t, tt)
+		})
+	}
+}
+
+func TestToolNotifier_HiddenTool(t *testing.T) {
+	t.Parallel()
+
+	mb := &mockBackend{}
+	runTurn(mb, ToolsConfig{Templates: toolTemplates{"bash": {Hide: true}}})
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if len(mb.sentMessages) != 0 {
+		t.Errorf("hidden tool was shown: %v", mb.sentMessages)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// maxTriggerCLILine bounds the line `opencrow trigger` writes, newline
+// included: writes up to PIPE_BUF are atomic, so concurrent senders can't
+// interleave.
+const maxTriggerCLILine = 4096
+
+// runTriggerCLI implements `opencrow trigger [flags] [text...]`: it writes
+// one structured trigger line to the trigger pipe of a running instance.
+// The text is read from stdin when no arguments are given.
+func runTriggerCLI(args []string, stdin io.Reader, stderr io.Writer) int {
+	t, sessionDir, err := parseTriggerCLI(args, stdin, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "opencrow trigger:", err)
+
+		return 2
+	}
+
+	line, err := json.Marshal(t)
+	if err != nil {
+		fmt.Fprintln(stderr, "opencrow trigger:", err)
+
+		return 1
+	}
+
+	if len(line)+1 > maxTriggerCLILine {
+		fmt.Fprintf(stderr, "opencrow trigger: trigger too long (over %d bytes); shorten it, e.g. with tail\n", maxTriggerCLILine)
+
+		return 2
+	}
+
+	if err := writeTriggerPipe(TriggerPipePath(sessionDir), append(line, '\n')); err != nil {
+		fmt.Fprintln(stderr, "opencrow trigger:", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+func parseTriggerCLI(args []string, stdin io.Reader, stderr io.Writer) (triggerLine, string, error) {
+	var t triggerLine
+
+	fs := flag.NewFlagSet("opencrow trigger", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&t.Source, "source", "", "trigger source label, selects a named source from OPENCROW_TRIGGER_SOURCES_FILE")
+	fs.StringVar(&t.Priority, "priority", "", "high, normal or low")
+	fs.StringVar(&t.DedupKey, "dedup-key", "", "drop the trigger if one with this key is still queued")
+	fs.StringVar(&t.ReplyTo, "reply-to", "", "backend message ID the reply should quote")
+	fs.StringVar(&t.Conversation, "conversation", "", "send the reply to this conversation")
+	fs.BoolVar(&t.Silent, "silent", false, "only reply if something needs attention")
+	sessionDir := fs.String("session-dir", cmp.Or(os.Getenv("OPENCROW_PI_SESSION_DIR"), defaultSessionDir), "opencrow session directory")
+
+	if err := fs.Parse(args); err != nil {
+		return t, "", err //nolint:wrapcheck // flag already printed the error
+	}
+
+	t.Content = strings.Join(fs.Args(), " ")
+	if t.Content == "" {
+		// Anything longer is rejected by the caller anyway.
+		data, err := io.ReadAll(io.LimitReader(stdin, maxTriggerCLILine+1))
+		if err != nil {
+			return t, "", fmt.Errorf("reading stdin: %w", err)
+		}
+
+		t.Content = strings.TrimSpace(string(data))
+	}
+
+	return t, *sessionDir, t.validate()
+}
+
+// writeTriggerPipe writes line to the FIFO at path without blocking when
+// no opencrow instance has it open for reading.
+func writeTriggerPipe(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return fmt.Errorf("no opencrow instance is reading %s", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("opening trigger pipe: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("writing trigger pipe: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// inboxTimeLayout is the created_at format written by the inbox schema.
+const inboxTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// enqueueTrigger applies the item's named source and the configured
+// dedup policy, then enqueues item and wakes the worker.
+// Returns the inbox ID, or 0 if the trigger was dropped as a duplicate.
+// All trigger inputs (pipe, socket, webhook) go through here.
+func (w *Worker) enqueueTrigger(ctx context.Context, item Inbox) (int64, error) {
+	cfg := w.triggerCfg
+
+	if src := cfg.source(item.Label); src != nil {
+		item = src.apply(item)
+	}
+
+	if item.Priority == priorityUnset {
+		item.Priority = PriorityTrigger
+	}
+
+	switch cfg.Dedup {
+	case triggerDedupOff:
+		item.DedupKey = ""
+	case triggerDedupExact:
+		if item.DedupKey == "" {
+			sum := sha256.Sum256([]byte(item.Label + "\x00" + item.Content))
+			item.DedupKey = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	}
+
+	if item.DedupKey != "" && cfg.DedupWindow > 0 {
+		if w.triggerSeenRecently(ctx, item.DedupKey, cfg.DedupWindow) {
+			slog.Info("trigger: duplicate within dedup window dropped", "dedup_key", item.DedupKey)
+
+			return 0, nil
+		}
+	}
+
+	id, err := w.inbox.EnqueueTrigger(ctx, item)
+	if err != nil || id == 0 {
+		return id, err
+	}
+
+	if item.DedupKey != "" && cfg.DedupWindow > 0 {
+		if err := w.inbox.queries.RecordTriggerSeen(ctx, RecordTriggerSeenParams{
+			DedupKey: item.DedupKey,
+			SeenAt:   time.Now().UTC().Format(time.RFC3339),
+		}); err != nil {
+			slog.Warn("trigger: failed to record dedup key", "error", err)
+		}
+	}
+
+	w.Notify(item.Priority)
+
+	return id, nil
+}
+
+// triggerSeenRecently reports whether key was accepted within window,
+// pruning history older than that first. Lookup errors let the trigger
+// through: a duplicate turn is better than a lost alert.
+func (w *Worker) triggerSeenRecently(ctx context.Context, key string, window time.Duration) bool {
+	since := time.Now().UTC().Add(-window).Format(time.RFC3339)
+
+	if err := w.inbox.queries.PruneTriggerHistory(ctx, since); err != nil {
+		slog.Warn("trigger: failed to prune dedup history", "error", err)
+	}
+
+	n, err := w.inbox.queries.TriggerSeenSince(ctx, TriggerSeenSinceParams{DedupKey: key, Datetime: since})
+	if err != nil {
+		slog.Warn("trigger: failed to check dedup history", "error", err)
+
+		return false
+	}
+
+	return n > 0
+}
+
+// mergeTriggerItems folds other queued triggers from the same source for
+// the same conversation into item, like mergeUserItems does for user
+// messages. Only one source is merged at a time so the result keeps that
+// source's template, reply policy and conversation. Triggers with their
+// own reply lifecycle (reminders, synchronous callers) are never merged.
+func (w *Worker) mergeTriggerItems(ctx context.Context, item Inbox) Inbox {
+	if !w.triggerCfg.Merge || item.ReminderID != 0 || item.WaiterID != "" {
+		return item
+	}
+
+	extra, err := w.inbox.DequeueMergeableTriggers(ctx, item.ConversationID, item.Label)
+	if err != nil {
+		slog.Error("worker: failed to dequeue trigger batch", "error", err)
+
+		return item
+	}
+
+	if len(extra) == 0 {
+		return item
+	}
+
+	slog.Info("worker: merging triggers", "count", 1+len(extra))
+
+	return mergeTriggers(append([]Inbox{item}, extra...))
+}
+
+// mergeHold is how long mergeable triggers stay queued before the worker
+// takes them, so a burst lands in one turn. Held triggers don't block
+// anything else: user messages and older items are taken around them.
+func (w *Worker) mergeHold() time.Duration {
+	if !w.triggerCfg.Merge {
+		return 0
+	}
+
+	return max(w.triggerCfg.MergeWindow, 0)
+}
+
+// armMergeTimer wakes the worker once the oldest held trigger is due.
+// Called when drainOnce found nothing to take.
+func (w *Worker) armMergeTimer(ctx context.Context) {
+	hold := w.mergeHold()
+	if hold == 0 {
+		return
+	}
+
+	created, ok := w.inbox.OldestMergeableTrigger(ctx)
+	if !ok {
+		return
+	}
+
+	if w.mergeTimer != nil {
+		w.mergeTimer.Stop()
+	}
+
+	wait := max(time.Until(created.Add(hold)), 10*time.Millisecond)
+
+	slog.Info("worker: holding trigger for merge window", "wait", wait.Round(time.Millisecond))
+
+	w.mergeTimer = time.AfterFunc(wait, func() { w.Notify(PriorityTrigger) })
+}
+
+// mergeTriggers combines items (oldest first) into one trigger whose
+// content marks each original's boundary and source. The result keeps the
+// highest priority, and is silent only if every item was.
+func mergeTriggers(items []Inbox) Inbox {
+	merged := items[0]
+
+	var sb strings.Builder
+
+	for i, it := range items {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+
+		fmt.Fprintf(&sb, "[trigger %d of %d", i+1, len(items))
+
+		if it.Label != "" {
+			sb.WriteString(", from " + it.Label)
+		}
+
+		sb.WriteString("]\n")
+		sb.WriteString(it.Content)
+
+		merged.Priority = min(merged.Priority, it.Priority)
+		merged.Silent = min(merged.Silent, it.Silent)
+	}
+
+	merged.Content = sb.String()
+	merged.ReplyTo = items[len(items)-1].ReplyTo
+	merged.DedupKey = ""
+
+	return merged
+}
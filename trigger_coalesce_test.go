@@ -0,0 +1,272 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeTriggerItems(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	inbox := newTestInbox(ctx, t)
+
+	w := NewWorker(inbox, PiConfig{}, "", "")
+	w.triggerCfg = TriggerConfig{Merge: true}
+
+	for _, item := range []Inbox{
+		{Priority: PriorityTrigger, Content: "disk 91%", Label: "monitor", Silent: 1},
+		{Priority: PriorityUser, Content: "disk 95%", Label: "monitor", Silent: 1, ReplyTo: "msg-2"},
+		{Priority: PriorityTrigger, Content: "other room", ConversationID: "!ops:example.com"},
+		{Priority: PriorityTrigger, Content: "backup ok", Label: "backup"},
+		{Priority: PriorityTrigger, Content: "waiting caller", WaiterID: "w1"},
+	} {
+		_, err := w.enqueueTrigger(ctx, item)
+		must(t, err)
+	}
+
+	head, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	merged := w.mergeTriggerItems(ctx, head)
+
+	want := "[trigger 1 of 2, from monitor]\ndisk 95%\n\n[trigger 2 of 2, from monitor]\ndisk 91%"
+	if merged.Content != want {
+		t.Errorf("Content = %q, want %q", merged.Content, want)
+	}
+
+	if merged.Label != "monitor" || merged.Silent != 1 || merged.Priority != PriorityUser {
+		t.Errorf("merged = %+v, want label monitor, silent, user priority", merged)
+	}
+
+	// The other conversation, the other source and the synchronous
+	// trigger stay queued.
+	waitForInboxCount(ctx, t, inbox, 3)
+}
+
+// Merged triggers are numbered, and take their reply target, in
+// insertion order whatever order the database returns them in.
+func TestMergeTriggerItems_InsertionOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	db := newTestDB(ctx, t)
+	inbox := newTestInboxWithDB(ctx, t, db)
+
+	w := NewWorker(inbox, PiConfig{}, "", "")
+	w.triggerCfg = TriggerConfig{Merge: true}
+
+	for _, stmt := range []string{
+		"INSERT INTO inbox (id, priority, source, content, reply_to) VALUES (9, 1, 'trigger', 'third', 'msg-9')",
+		"INSERT INTO inbox (id, priority, source, content, reply_to) VALUES (5, 1, 'trigger', 'second', 'msg-5')",
+		"INSERT INTO inbox (id, priority, source, content) VALUES (1, 1, 'trigger', 'first')",
+	} {
+		_, err := db.ExecContext(ctx, stmt)
+		must(t, err)
+	}
+
+	head, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	merged := w.mergeTriggerItems(ctx, head)
+
+	want := "[trigger 1 of 3]\nfirst\n\n[trigger 2 of 3]\nsecond\n\n[trigger 3 of 3]\nthird"
+	if merged.Content != want || merged.ReplyTo != "msg-9" {
+		t.Errorf("merged = %q, reply to %q; want %q, reply to msg-9", merged.Content, merged.ReplyTo, want)
+	}
+}
+
+func TestMergeTriggers_SilentAndReplyTo(t *testing.T) {
+	t.Parallel()
+
+	merged := mergeTriggers([]Inbox{
+		{Content: "a", Label: "ci", Silent: 1, ReplyTo: "m1"},
+		{Content: "b", Label: "ci", ReplyTo: "m2"},
+	})
+
+	if merged.Label != "ci" {
+		t.Errorf("Label = %q, want ci", merged.Label)
+	}
+
+	if merged.Silent != 0 {
+		t.Error("merged trigger is silent although one item was not")
+	}
+
+	if merged.ReplyTo != "m2" {
+		t.Errorf("ReplyTo = %q, want m2", merged.ReplyTo)
+	}
+
+	if !strings.Contains(merged.Content, "[trigger 2 of 2, from ci]\nb") {
+		t.Errorf("Content = %q, missing boundary for second item", merged.Content)
+	}
+}
+
+func TestEnqueueTrigger_Dedup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  TriggerConfig
+		// second is enqueued after the first has been dequeued.
+		first, second Inbox
+		wantQueued    bool
+	}{
+		{
+			name:       "key policy ignores unkeyed repeats",
+			cfg:        TriggerConfig{Dedup: triggerDedupKey, DedupWindow: time.Hour},
+			first:      Inbox{Content: "ping"},
+			second:     Inbox{Content: "ping"},
+			wantQueued: true,
+		},
+		{
+			name:       "key policy drops repeat within window",
+			cfg:        TriggerConfig{Dedup: triggerDedupKey, DedupWindow: time.Hour},
+			first:      Inbox{Content: "backup failed", DedupKey: "backup"},
+			second:     Inbox{Content: "backup failed again", DedupKey: "backup"},
+			wantQueued: false,
+		},
+		{
+			name:       "exact policy drops identical content",
+			cfg:        TriggerConfig{Dedup: triggerDedupExact, DedupWindow: time.Hour},
+			first:      Inbox{Content: "ping", Label: "cron"},
+			second:     Inbox{Content: "ping", Label: "cron"},
+			wantQueued: false,
+		},
+		{
+			name:       "exact policy keeps different labels apart",
+			cfg:        TriggerConfig{Dedup: triggerDedupExact, DedupWindow: time.Hour},
+			first:      Inbox{Content: "ping", Label: "a"},
+			second:     Inbox{Content: "ping", Label: "b"},
+			wantQueued: true,
+		},
+		{
+			name:       "off policy ignores keys",
+			cfg:        TriggerConfig{Dedup: triggerDedupOff, DedupWindow: time.Hour},
+			first:      Inbox{Content: "x", DedupKey: "k"},
+			second:     Inbox{Content: "x", DedupKey: "k"},
+			wantQueued: true,
+		},
+		{
+			name:       "no window only dedups against the queue",
+			cfg:        TriggerConfig{Dedup: triggerDedupKey},
+			first:      Inbox{Content: "x", DedupKey: "k"},
+			second:     Inbox{Content: "x", DedupKey: "k"},
+			wantQueued: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			inbox := newTestInbox(ctx, t)
+
+			w := NewWorker(inbox, PiConfig{}, "", "")
+			w.triggerCfg = tt.cfg
+
+			_, err := w.enqueueTrigger(ctx, tt.first)
+			must(t, err)
+
+			_, err = inbox.Dequeue(ctx)
+			must(t, err)
+
+			id, err := w.enqueueTrigger(ctx, tt.second)
+			must(t, err)
+
+			if got := id != 0; got != tt.wantQueued {
+				t.Errorf("second trigger queued = %v, want %v", got, tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestDequeueHolding_LeavesYoungTriggersQueued(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	inbox := newTestInbox(ctx, t)
+
+	_, err := inbox.EnqueueTrigger(ctx, Inbox{Priority: PriorityTrigger, Content: "burst"})
+	must(t, err)
+	must(t, inbox.EnqueueReminder(ctx, "Reminder: stretch", 1))
+	must(t, inbox.EnqueueUser(ctx, Inbox{Content: "hi"}))
+
+	// The user message and the reminder are taken around the held trigger.
+	for _, want := range []string{"hi", "Reminder: stretch"} {
+		item, err := inbox.DequeueHolding(ctx, time.Hour)
+		must(t, err)
+
+		if item.Content != want {
+			t.Errorf("dequeued %q, want %q", item.Content, want)
+		}
+	}
+
+	if _, err := inbox.DequeueHolding(ctx, time.Hour); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("err = %v, want sql.ErrNoRows while the trigger is held", err)
+	}
+
+	if created, ok := inbox.OldestMergeableTrigger(ctx); !ok || time.Since(created) > time.Minute {
+		t.Errorf("OldestMergeableTrigger = %v, %v, want the held trigger", created, ok)
+	}
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	if item.Content != "burst" {
+		t.Errorf("dequeued %q without hold, want the trigger", item.Content)
+	}
+}
+
+// The worker must come back for a held trigger on its own.
+func TestWorker_MergeWindowWakesWorker(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+	w.triggerCfg = TriggerConfig{Merge: true, MergeWindow: 50 * time.Millisecond}
+
+	mb := &mockBackend{}
+	w.SetBackend(mb)
+	w.SetApp(NewApp(mb, w, w.inbox, newTestDB(ctx, t)))
+
+	go w.Run(ctx)
+
+	for _, content := range []string{"a", "b"} {
+		_, err := w.enqueueTrigger(ctx, Inbox{Priority: PriorityTrigger, Content: content})
+		must(t, err)
+		w.Notify(PriorityTrigger)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		n, err := w.inbox.Count(ctx)
+		must(t, err)
+
+		mb.mu.Lock()
+		sent := len(mb.sentMessages)
+		mb.mu.Unlock()
+
+		if n == 0 && sent > 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("inbox count = %d after the merge window, want 0", n)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Both triggers were held long enough to land in one turn.
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if len(mb.sentMessages) != 1 {
+		t.Errorf("sent %v, want one reply for the merged triggers", mb.sentMessages)
+	}
+}
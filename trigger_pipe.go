@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -12,6 +14,10 @@ import (
 	"syscall"
 )
 
+// maxTriggerPipeLine bounds a line on the trigger pipe; longer lines are
+// dropped.
+const maxTriggerPipeLine = 1 << 20
+
 // startTriggerPipe reads lines from a named pipe (FIFO) and enqueues
 // them into the inbox. Runs until ctx is cancelled.
 func startTriggerPipe(ctx context.Context, w *Worker, sessionDir string) {
@@ -42,6 +48,8 @@ func triggerReadLoop(ctx context.Context, w *Worker, pipePath string) {
 	defer stop()
 
 	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 4096), maxTriggerPipeLine+1)
+	scanner.Split(skipLongLines(maxTriggerPipeLine))
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -55,13 +63,11 @@ func triggerReadLoop(ctx context.Context, w *Worker, pipePath string) {
 
 		slog.Info("trigger: received", "content", line)
 
-		if err := w.inbox.Enqueue(ctx, PriorityTrigger, sourceTrigger, line, ""); err != nil {
-			slog.Error("trigger: failed to enqueue", "error", err)
+		item := parseTriggerLine(line)
 
-			continue
+		if _, err := w.enqueueTrigger(ctx, item); err != nil {
+			slog.Error("trigger: failed to enqueue", "error", err)
 		}
-
-		w.Notify(PriorityTrigger)
 	}
 
 	if err := scanner.Err(); err != nil && ctx.Err() == nil {
@@ -69,6 +75,130 @@ func triggerReadLoop(ctx context.Context, w *Worker, pipePath string) {
 	}
 }
 
+// skipLongLines is bufio.ScanLines, except that lines longer than maxLen
+// are dropped with a warning instead of ending the scan. The scanner's
+// buffer must hold more than maxLen bytes.
+func skipLongLines(maxLen int) bufio.SplitFunc {
+	skipping := false
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		i := bytes.IndexByte(data, '\n')
+
+		switch {
+		case skipping && i < 0:
+			return len(data), nil, nil
+		case skipping:
+			skipping = false
+
+			return i + 1, nil, nil
+		case i > maxLen || (i < 0 && len(data) > maxLen):
+			slog.Warn("trigger: dropping line over the size limit", "limit", maxLen)
+
+			if i < 0 {
+				skipping = true
+
+				return len(data), nil, nil
+			}
+
+			return i + 1, nil, nil
+		}
+
+		return bufio.ScanLines(data, atEOF)
+	}
+}
+
+// triggerLine is the structured form of a trigger-pipe line: a JSON object
+// instead of plain text. Only content is required.
+type triggerLine struct {
+	Content string `json:"content"`
+	// Priority is "high" (ahead of other triggers, preempting a running
+	// trigger), "normal" (default) or "low" (behind other triggers).
+	Priority string `json:"priority"`
+	// Source labels the sender ("backup", "ci", …) for the agent.
+	Source string `json:"source"`
+	// DedupKey drops the trigger if one with the same key is still queued.
+	DedupKey string `json:"dedup_key"` //nolint:tagliatelle // snake_case is the documented wire format.
+	ReplyTo  string `json:"reply_to"`  //nolint:tagliatelle // snake_case is the documented wire format.
+	// Silent suppresses the reply unless something needs attention.
+	Silent bool `json:"silent"`
+	// Conversation overrides the room the reply goes to.
+	Conversation string `json:"conversation"`
+}
+
+// priorityUnset marks a trigger that names no priority, so the default of
+// its source applies. enqueueTrigger resolves it before the item is queued.
+const priorityUnset int64 = -1
+
+var triggerPriorities = map[string]int64{
+	"":       priorityUnset,
+	"high":   PriorityUser,
+	"normal": PriorityTrigger,
+	"low":    PriorityHeartbeat,
+}
+
+// parseTriggerLine turns a pipe line into an inbox item. Lines starting
+// with "{" are decoded as a triggerLine; anything else, including JSON
+// that fails to decode, is enqueued as plain text so no trigger is lost.
+func parseTriggerLine(line string) Inbox {
+	plain := Inbox{Priority: PriorityTrigger, Source: sourceTrigger, Content: line}
+
+	if !strings.HasPrefix(line, "{") {
+		return plain
+	}
+
+	var t triggerLine
+	if err := errors.Join(decodeTriggerJSON(line, &t), t.validate()); err != nil {
+		slog.Warn("trigger: invalid JSON line, treating as plain text", "error", err)
+
+		return plain
+	}
+
+	return t.item()
+}
+
+// decodeTriggerJSON strictly decodes a JSON trigger into v, rejecting
+// unknown fields so typos don't silently fall back to defaults.
+func decodeTriggerJSON(line string, v any) error {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("decoding trigger: %w", err)
+	}
+
+	return nil
+}
+
+func (t triggerLine) validate() error {
+	if strings.TrimSpace(t.Content) == "" {
+		return errors.New("trigger has no content")
+	}
+
+	if _, ok := triggerPriorities[t.Priority]; !ok {
+		return fmt.Errorf("unknown priority %q (valid: high, normal, low)", t.Priority)
+	}
+
+	return nil
+}
+
+func (t triggerLine) item() Inbox {
+	var silent int64
+	if t.Silent {
+		silent = 1
+	}
+
+	return Inbox{
+		Priority:       triggerPriorities[t.Priority],
+		Source:         sourceTrigger,
+		Content:        t.Content,
+		ReplyTo:        t.ReplyTo,
+		Label:          t.Source,
+		DedupKey:       t.DedupKey,
+		Silent:         silent,
+		ConversationID: t.Conversation,
+	}
+}
+
 // TriggerPipePath returns the path to the trigger FIFO.
 func TriggerPipePath(sessionDir string) string {
 	return filepath.Join(sessionDir, "trigger.pipe")
@@ -99,12 +229,30 @@ func ensureFIFO(path string) error {
 	return nil
 }
 
-func buildTriggerPrompt(basePrompt, content string) string {
+// silentTriggerOK is the sentinel a silent trigger's reply may consist of
+// when nothing needs the user's attention.
+const silentTriggerOK = "TRIGGER_OK"
+
+// silentTriggerInstruction tells the agent about silentTriggerOK.
+const silentTriggerInstruction = "This trigger is silent: if nothing needs the user's attention, reply with exactly: " + silentTriggerOK
+
+func buildTriggerPrompt(basePrompt string, item Inbox) string {
 	var prompt strings.Builder
 
 	prompt.WriteString(basePrompt)
-	prompt.WriteString("\n\n--- External trigger ---\n")
-	prompt.WriteString(content)
+
+	if item.Silent != 0 {
+		prompt.WriteString("\n" + silentTriggerInstruction)
+	}
+
+	prompt.WriteString("\n\n--- External trigger")
+
+	if item.Label != "" {
+		prompt.WriteString(" from " + item.Label)
+	}
+
+	prompt.WriteString(" ---\n")
+	prompt.WriteString(item.Content)
 	prompt.WriteString("\n--- end trigger ---")
 
 	return prompt.String()
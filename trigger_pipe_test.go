@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -53,6 +56,101 @@ func TestTriggerPipeReader_EnqueuesLines(t *testing.T) {
 	}
 }
 
+func TestTriggerPipeReader_StructuredLines(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	db := newTestDBAt(ctx, t, t.TempDir()+"/test.db")
+	inbox := newTestInboxWithDB(ctx, t, db)
+
+	dir := t.TempDir()
+
+	worker := NewWorker(inbox, PiConfig{SessionDir: dir}, "", "")
+	startTriggerPipe(ctx, worker, dir)
+
+	pipePath := TriggerPipePath(dir)
+	waitForFIFO(t, pipePath)
+	writeToPipe(t, pipePath,
+		`{"content": "backup ok", "source": "backup", "silent": true, "dedup_key": "backup", "conversation": "!ops:example.com"}`+"\n"+
+			`{"content": "backup ok again", "dedup_key": "backup"}`+"\n"+
+			`{"content": "disk full", "priority": "high", "reply_to": "msg-1"}`+"\n"+
+			"{not json}\n")
+	waitForInboxCount(ctx, t, inbox, 3)
+
+	// The high-priority item jumps the queue.
+	urgent, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	if urgent.Content != "disk full" || urgent.Priority != PriorityUser || urgent.ReplyTo != "msg-1" {
+		t.Errorf("urgent = %+v", urgent)
+	}
+
+	// The second backup line was a duplicate of a queued key.
+	backup, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	want := Inbox{Label: "backup", DedupKey: "backup", Silent: 1, ConversationID: "!ops:example.com", Content: "backup ok"}
+	if backup.Content != want.Content || backup.Label != want.Label || backup.Silent != want.Silent ||
+		backup.ConversationID != want.ConversationID {
+		t.Errorf("backup = %+v, want %+v", backup, want)
+	}
+
+	// Invalid JSON still arrives, verbatim.
+	plain, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	if plain.Content != "{not json}" || plain.Priority != PriorityTrigger {
+		t.Errorf("plain = %+v", plain)
+	}
+}
+
+func TestSkipLongLines(t *testing.T) {
+	t.Parallel()
+
+	const maxLen = 8
+
+	input := "ok\n" + strings.Repeat("x", 20) + "\nfits\n" + strings.Repeat("y", maxLen+1) + "\nafter"
+
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Buffer(make([]byte, 0, 4), maxLen+1)
+	scanner.Split(skipLongLines(maxLen))
+
+	var got []string
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+
+	must(t, scanner.Err())
+
+	if want := []string{"ok", "fits", "after"}; !slices.Equal(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestShouldSuppressReply_SilentTrigger(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		reply string
+		item  Inbox
+		want  bool
+	}{
+		{"TRIGGER_OK", Inbox{Source: sourceTrigger, Silent: 1}, true},
+		{" TRIGGER_OK\n", Inbox{Source: sourceTrigger, Silent: 1}, true},
+		{"TRIGGER_OK", Inbox{Source: sourceTrigger}, false},
+		{"Backup failed: TRIGGER_OK not applicable", Inbox{Source: sourceTrigger, Silent: 1}, false},
+		{"HEARTBEAT_OK", Inbox{Source: sourceTrigger, Silent: 1}, false},
+		{"HEARTBEAT_OK", Inbox{Source: sourceHeartbeat}, true},
+		{"", Inbox{Source: sourceTrigger}, true},
+	}
+
+	for _, tc := range cases {
+		if got := shouldSuppressReply(tc.reply, tc.item); got != tc.want {
+			t.Errorf("shouldSuppressReply(%q, %+v) = %v, want %v", tc.reply, tc.item, got, tc.want)
+		}
+	}
+}
+
 func waitForFIFO(t *testing.T, path string) {
 	t.Helper()
 
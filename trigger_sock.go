@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSyncTriggerLine bounds a request on trigger.sock.
+	maxSyncTriggerLine = 1 << 20
+	// syncTriggerReadTimeout bounds how long a client may take to send
+	// its request line, so an idle connection doesn't hold a goroutine.
+	syncTriggerReadTimeout = 10 * time.Second
+	// defaultSyncTriggerTimeout bounds how long a caller waits for the
+	// reply; long agent turns can take a while.
+	defaultSyncTriggerTimeout = 30 * time.Minute
+)
+
+// errNoConversation fails a synchronous trigger that arrives before the
+// bot knows a room to run it in.
+var errNoConversation = errors.New("no conversation yet: message the bot first, or set conversation_id")
+
+// syncTriggerRequest is a trigger.sock request: a structured trigger line
+// plus whether the reply should also be posted to chat.
+type syncTriggerRequest struct {
+	triggerLine
+
+	Post bool `json:"post"`
+}
+
+// triggerResult is the single JSON line written back to a trigger.sock
+// caller once the worker has processed its item.
+type triggerResult struct {
+	ID    int64    `json:"id,omitempty"`
+	Reply string   `json:"reply"`
+	Files []string `json:"files,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// triggerWaiter is a caller blocked on trigger.sock. done is buffered so
+// the worker never blocks on a caller that went away.
+type triggerWaiter struct {
+	post bool
+	done chan triggerResult
+}
+
+// TriggerSocketPath returns the path to the synchronous trigger socket.
+func TriggerSocketPath(sessionDir string) string {
+	return filepath.Join(sessionDir, "trigger.sock")
+}
+
+// startTriggerSocket serves synchronous triggers next to trigger.pipe:
+// each connection sends one line (plain text or a JSON trigger) and
+// receives one JSON line with the agent's reply. Runs until ctx is done.
+func startTriggerSocket(ctx context.Context, w *Worker, sessionDir string) {
+	path := TriggerSocketPath(sessionDir)
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("trigger: failed to remove stale socket", "path", path, "error", err)
+
+		return
+	}
+
+	var lc net.ListenConfig
+
+	ln, err := lc.Listen(ctx, "unix", path)
+	if err != nil {
+		slog.Error("trigger: failed to listen on socket", "path", path, "error", err)
+
+		return
+	}
+
+	// Same access model as trigger.pipe: owner and group may submit.
+	if err := os.Chmod(path, 0o660); err != nil {
+		slog.Error("trigger: failed to set socket permissions", "path", path, "error", err)
+		ln.Close()
+
+		return
+	}
+
+	context.AfterFunc(ctx, func() { ln.Close() })
+
+	slog.Info("trigger socket started", "path", path)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Warn("trigger: socket accept failed", "error", err)
+				}
+
+				return
+			}
+
+			go handleTriggerConn(ctx, w, conn)
+		}
+	}()
+}
+
+func handleTriggerConn(ctx context.Context, w *Worker, conn net.Conn) {
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+
+	req, err := readSyncTrigger(conn, syncTriggerReadTimeout)
+	if err != nil {
+		_ = enc.Encode(triggerResult{Error: err.Error()})
+
+		return
+	}
+
+	slog.Info("trigger: received on socket", "content", req.Content, "post", req.Post)
+
+	_ = enc.Encode(w.runSyncTrigger(ctx, req))
+}
+
+// readSyncTrigger reads the request line, failing if it doesn't arrive
+// within timeout.
+func readSyncTrigger(conn net.Conn, timeout time.Duration) (syncTriggerRequest, error) {
+	var req syncTriggerRequest
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return req, fmt.Errorf("setting read deadline: %w", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxSyncTriggerLine)
+
+	if !scanner.Scan() {
+		return req, fmt.Errorf("reading trigger: %w", cmp.Or(scanner.Err(), errors.New("no input")))
+	}
+
+	line := strings.TrimSpace(scanner.Text())
+	if !strings.HasPrefix(line, "{") {
+		req.Content = line
+	} else if err := decodeTriggerJSON(line, &req); err != nil {
+		return req, err
+	}
+
+	return req, req.validate()
+}
+
+// runSyncTrigger enqueues req linked to a waiter and blocks until the
+// worker answers it, the trigger socket's timeout passes or ctx is done.
+// A caller that stops waiting leaves its waiter behind, so the item is
+// still answered according to req.Post instead of posted like an ordinary
+// trigger; the worker removes it then.
+func (w *Worker) runSyncTrigger(ctx context.Context, req syncTriggerRequest) triggerResult {
+	waiterID, waiter := w.addWaiter(req.Post)
+
+	item := req.item()
+	item.WaiterID = waiterID
+
+	id, err := w.enqueueTrigger(ctx, item)
+	if err != nil {
+		w.removeWaiter(waiterID)
+
+		return triggerResult{Error: err.Error()}
+	}
+
+	if id == 0 {
+		w.removeWaiter(waiterID)
+
+		return triggerResult{Error: "duplicate of a queued trigger (dedup_key " + req.DedupKey + ")"}
+	}
+
+	timeout := cmp.Or(w.triggerCfg.SyncTimeout, defaultSyncTriggerTimeout)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-waiter.done:
+		res.ID = id
+
+		return res
+	case <-timer.C:
+		return triggerResult{ID: id, Error: fmt.Sprintf("no reply within %s; the trigger is still processed", timeout)}
+	case <-ctx.Done():
+		return triggerResult{ID: id, Error: "opencrow is shutting down; the trigger stays queued"}
+	}
+}
+
+func (w *Worker) addWaiter(post bool) (string, *triggerWaiter) {
+	id := rand.Text()
+	waiter := &triggerWaiter{post: post, done: make(chan triggerResult, 1)}
+
+	w.waitersMu.Lock()
+	w.waiters[id] = waiter
+	w.waitersMu.Unlock()
+
+	return id, waiter
+}
+
+func (w *Worker) removeWaiter(id string) {
+	w.waitersMu.Lock()
+	delete(w.waiters, id)
+	w.waitersMu.Unlock()
+}
+
+// takeWaiter removes and returns the caller waiting on item, if any. Items
+// whose caller is gone (e.g. after a restart) are handled like ordinary
+// triggers.
+func (w *Worker) takeWaiter(item Inbox) *triggerWaiter {
+	if item.WaiterID == "" {
+		return nil
+	}
+
+	w.waitersMu.Lock()
+	defer w.waitersMu.Unlock()
+
+	waiter := w.waiters[item.WaiterID]
+	delete(w.waiters, item.WaiterID)
+
+	return waiter
+}
+
+// answerWaiter hands the reply to a synchronous caller waiting on item.
+// Returns whether the reply should still be posted to chat.
+func (w *Worker) answerWaiter(item Inbox, reply string) bool {
+	waiter := w.takeWaiter(item)
+	if waiter == nil {
+		return true
+	}
+
+	text, files := extractSendFiles(reply)
+	waiter.done <- triggerResult{Reply: text, Files: files}
+
+	return waiter.post
+}
+
+// failWaiter reports a terminal processing error to a synchronous caller.
+func (w *Worker) failWaiter(item Inbox, err error) {
+	if waiter := w.takeWaiter(item); waiter != nil {
+		waiter.done <- triggerResult{Error: err.Error()}
+	}
+}
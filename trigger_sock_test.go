@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// syncTrigger submits line on the trigger socket and decodes the answer.
+func syncTrigger(t *testing.T, path, line string) triggerResult {
+	t.Helper()
+
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	must(t, err)
+
+	defer conn.Close()
+
+	must(t, conn.SetDeadline(time.Now().Add(10*time.Second)))
+
+	_, err = conn.Write([]byte(line + "\n"))
+	must(t, err)
+
+	var res triggerResult
+
+	must(t, json.NewDecoder(bufio.NewReader(conn)).Decode(&res))
+
+	return res
+}
+
+func TestTriggerSocket_ReturnsReply(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+
+	startTriggerSocket(ctx, w, w.piCfg.SessionDir)
+
+	go w.Run(ctx)
+
+	path := TriggerSocketPath(w.piCfg.SessionDir)
+
+	res := syncTrigger(t, path, `{"content": "ping", "source": "ci"}`)
+	if res.Error != "" || res.Reply != "ok" || res.ID == 0 {
+		t.Errorf("result = %+v, want reply %q with an id", res, "ok")
+	}
+
+	res = syncTrigger(t, path, `{"content": "ping", "bogus": 1}`)
+	if res.Error == "" {
+		t.Errorf("result = %+v, want an error for an unknown field", res)
+	}
+
+	// Answered items must not leave their waiter behind.
+	w.waitersMu.Lock()
+	defer w.waitersMu.Unlock()
+
+	if len(w.waiters) != 0 {
+		t.Errorf("waiters = %d, want 0", len(w.waiters))
+	}
+}
+
+func TestTriggerSocket_NoRoomFailsCaller(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	w := newFakePiWorker(t)
+	w.SetRoomID("")
+
+	startTriggerSocket(ctx, w, w.piCfg.SessionDir)
+
+	go w.Run(ctx)
+
+	res := syncTrigger(t, TriggerSocketPath(w.piCfg.SessionDir), `{"content": "ping"}`)
+	if res.Error != errNoConversation.Error() {
+		t.Errorf("result = %+v, want %q", res, errNoConversation)
+	}
+
+	// The caller has its answer; the item must not run later.
+	if n, _ := w.inbox.Count(ctx); n != 0 {
+		t.Errorf("inbox count = %d, want 0", n)
+	}
+}
+
+func TestRunSyncTrigger_Timeout(t *testing.T) {
+	t.Parallel()
+
+	w := newFakePiWorker(t)
+	w.triggerCfg.SyncTimeout = 10 * time.Millisecond
+
+	// No worker loop: the item is never answered.
+	res := w.runSyncTrigger(t.Context(), syncTriggerRequest{triggerLine: triggerLine{Content: "ping"}})
+	if res.ID == 0 || res.Error == "" {
+		t.Fatalf("result = %+v, want a timeout error with the id", res)
+	}
+
+	// The waiter stays so the item is still answered per its post flag.
+	w.waitersMu.Lock()
+	defer w.waitersMu.Unlock()
+
+	if len(w.waiters) != 1 {
+		t.Errorf("waiters = %d, want 1", len(w.waiters))
+	}
+}
+
+func TestReadSyncTrigger_IdleClientTimesOut(t *testing.T) {
+	t.Parallel()
+
+	server, client := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// The client connects but never sends a line.
+	if _, err := readSyncTrigger(server, 20*time.Millisecond); err == nil {
+		t.Fatal("readSyncTrigger returned no error for an idle client")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Reply policies of a TriggerSource.
+const (
+	triggerReplyAlways  = "always"   // post every reply (default)
+	triggerReplyOnIssue = "on-issue" // silent trigger: post unless the agent answers TRIGGER_OK
+	triggerReplyNever   = "never"    // run the turn, never post the reply
+)
+
+// TriggerSource is one entry of OPENCROW_TRIGGER_SOURCES_FILE. A trigger
+// whose source label matches Name (the "source" field of a pipe line, a
+// webhook route's source, or `opencrow trigger -source`) is rendered with
+// Template instead of the built-in trigger prompt, and picks up the
+// source's defaults for whatever the trigger itself leaves unset.
+type TriggerSource struct {
+	Name string `json:"name"`
+	// Template is a text/template rendered with triggerPromptData; the
+	// result is the whole prompt for the turn.
+	Template     string `json:"template"`
+	Priority     string `json:"priority"`
+	Reply        string `json:"reply"`
+	Conversation string `json:"conversation"`
+
+	tmpl *template.Template
+}
+
+// triggerPromptData is what a source template can reference. JSON is the
+// decoded trigger content, or nil if the content is not JSON.
+type triggerPromptData struct {
+	Source  string
+	Content string
+	JSON    any
+	Silent  bool
+	Now     time.Time
+}
+
+// loadTriggerSources reads and validates the JSON source list at path.
+// All errors are reported at once.
+func loadTriggerSources(path string) ([]TriggerSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OPENCROW_TRIGGER_SOURCES_FILE: %w", err)
+	}
+
+	var sources []TriggerSource
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("parsing OPENCROW_TRIGGER_SOURCES_FILE: %w", err)
+	}
+
+	seen := make(map[string]bool, len(sources))
+
+	var errs error
+
+	for i := range sources {
+		if err := sources[i].load(); err != nil {
+			errs = errors.Join(errs, err)
+
+			continue
+		}
+
+		if seen[sources[i].Name] {
+			errs = errors.Join(errs, fmt.Errorf("trigger source %q: duplicate name", sources[i].Name))
+		}
+
+		seen[sources[i].Name] = true
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return sources, nil
+}
+
+func (s *TriggerSource) load() error {
+	if s.Name == "" {
+		return errors.New("trigger source: name is required")
+	}
+
+	if _, ok := triggerPriorities[s.Priority]; !ok {
+		return fmt.Errorf("trigger source %q: unknown priority %q (valid: high, normal, low)", s.Name, s.Priority)
+	}
+
+	switch s.Reply {
+	case "":
+		s.Reply = triggerReplyAlways
+	case triggerReplyAlways, triggerReplyOnIssue, triggerReplyNever:
+	default:
+		return fmt.Errorf("trigger source %q: unknown reply policy %q (valid: always, on-issue, never)", s.Name, s.Reply)
+	}
+
+	if strings.TrimSpace(s.Template) == "" {
+		return fmt.Errorf("trigger source %q: template is required", s.Name)
+	}
+
+	var err error
+	if s.tmpl, err = template.New(s.Name).Funcs(webhookFuncs).Parse(s.Template); err != nil {
+		return fmt.Errorf("trigger source %q: template: %w", s.Name, err)
+	}
+
+	return nil
+}
+
+// source returns the named trigger source, or nil.
+func (c TriggerConfig) source(name string) *TriggerSource {
+	if name == "" {
+		return nil
+	}
+
+	for i := range c.Sources {
+		if c.Sources[i].Name == name {
+			return &c.Sources[i]
+		}
+	}
+
+	return nil
+}
+
+// apply fills in the source's defaults for fields item leaves unset.
+func (s *TriggerSource) apply(item Inbox) Inbox {
+	if item.Priority == priorityUnset {
+		item.Priority = triggerPriorities[s.Priority]
+	}
+
+	if item.ConversationID == "" {
+		item.ConversationID = s.Conversation
+	}
+
+	if s.Reply == triggerReplyOnIssue {
+		item.Silent = 1
+	}
+
+	return item
+}
+
+// render builds the prompt for item from the source template. The silent
+// instruction is appended as for built-in trigger prompts, so on-issue
+// sources can still answer TRIGGER_OK.
+func (s *TriggerSource) render(item Inbox) (string, error) {
+	data := triggerPromptData{
+		Source:  s.Name,
+		Content: item.Content,
+		Silent:  item.Silent != 0,
+		Now:     time.Now(),
+	}
+
+	if err := json.Unmarshal([]byte(item.Content), &data.JSON); err != nil {
+		data.JSON = nil
+	}
+
+	var prompt strings.Builder
+	if err := s.tmpl.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("rendering trigger source %q: %w", s.Name, err)
+	}
+
+	if data.Silent {
+		prompt.WriteString("\n\n" + silentTriggerInstruction)
+	}
+
+	return prompt.String(), nil
+}
+
+// buildTriggerItemPrompt builds the prompt for a trigger item: the
+// template of its named source if there is one, the built-in wrapper
+// otherwise.
+func (w *Worker) buildTriggerItemPrompt(item Inbox) string {
+	src := w.triggerCfg.source(item.Label)
+	if src == nil {
+		return buildTriggerPrompt(w.triggerPrompt, item)
+	}
+
+	prompt, err := src.render(item)
+	if err != nil {
+		slog.Warn("trigger: source template failed, using default prompt", "error", err)
+
+		return buildTriggerPrompt(w.triggerPrompt, item)
+	}
+
+	return prompt
+}
+
+// replyMuted reports whether item's source never posts replies.
+func (w *Worker) replyMuted(item Inbox) bool {
+	if item.Source != sourceTrigger {
+		return false
+	}
+
+	src := w.triggerCfg.source(item.Label)
+
+	return src != nil && src.Reply == triggerReplyNever
+}
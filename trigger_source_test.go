@@ -0,0 +1,172 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testTriggerSources = `[
+	{
+		"name": "alertmanager",
+		"priority": "high",
+		"template": "Summarize these alerts:{{range .JSON.alerts}}\n- {{.labels.alertname}} ({{.status}}){{end}}"
+	},
+	{
+		"name": "backup",
+		"priority": "low",
+		"reply": "on-issue",
+		"conversation": "!ops:example.com",
+		"template": "Backup report:\n{{.Content}}"
+	},
+	{"name": "metrics", "reply": "never", "template": "Record: {{.Content}}"}
+]`
+
+func TestLoadTriggerSources_ReportsAllErrors(t *testing.T) {
+	t.Parallel()
+
+	path := writeTestFile(t, "sources.json", `[
+		{"name": "ok", "template": "x"},
+		{"name": "ok", "template": "dup"},
+		{"name": "bad-reply", "reply": "sometimes", "template": "x"},
+		{"name": "bad-priority", "priority": "urgent", "template": "x"},
+		{"name": "no-template"},
+		{"name": "bad-template", "template": "{{.Nope"}
+	]`)
+
+	_, err := loadTriggerSources(path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"duplicate name", "bad-reply", "bad-priority", "no-template", "bad-template"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+}
+
+func TestTriggerSource_AppliedOnEnqueue(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	sources, err := loadTriggerSources(writeTestFile(t, "sources.json", testTriggerSources))
+	must(t, err)
+
+	inbox := newTestInbox(ctx, t)
+	w := NewWorker(inbox, PiConfig{}, "", defaultTriggerPrompt)
+	w.triggerCfg = TriggerConfig{Sources: sources}
+
+	_, err = w.enqueueTrigger(ctx, parseTriggerLine(`{"content": "nightly: 0 errors", "source": "backup"}`))
+	must(t, err)
+
+	item, err := inbox.Dequeue(ctx)
+	must(t, err)
+
+	if item.Priority != PriorityHeartbeat || item.Silent != 1 || item.ConversationID != "!ops:example.com" {
+		t.Errorf("item = %+v, want low priority, silent, ops conversation", item)
+	}
+
+	prompt := w.buildTriggerItemPrompt(item)
+	if !strings.HasPrefix(prompt, "Backup report:\nnightly: 0 errors") || !strings.Contains(prompt, silentTriggerOK) {
+		t.Errorf("prompt = %q, want source template with silent instruction", prompt)
+	}
+
+	// The trigger's own conversation wins over the source default.
+	_, err = w.enqueueTrigger(ctx, parseTriggerLine(`{"content": "x", "source": "backup", "conversation": "!other"}`))
+	must(t, err)
+
+	item, err = inbox.Dequeue(ctx)
+	must(t, err)
+
+	if item.ConversationID != "!other" {
+		t.Errorf("ConversationID = %q, want !other", item.ConversationID)
+	}
+
+	// So does an explicit priority, even the one a trigger gets by default.
+	_, err = w.enqueueTrigger(ctx, parseTriggerLine(`{"content": "x", "source": "backup", "priority": "normal"}`))
+	must(t, err)
+
+	item, err = inbox.Dequeue(ctx)
+	must(t, err)
+
+	if item.Priority != PriorityTrigger {
+		t.Errorf("Priority = %d, want %d for an explicit normal priority", item.Priority, PriorityTrigger)
+	}
+}
+
+func TestTriggerSource_TemplateAndReplyPolicy(t *testing.T) {
+	t.Parallel()
+
+	sources, err := loadTriggerSources(writeTestFile(t, "sources.json", testTriggerSources))
+	must(t, err)
+
+	w := NewWorker(nil, PiConfig{}, "", defaultTriggerPrompt)
+	w.triggerCfg = TriggerConfig{Sources: sources}
+
+	alerts := Inbox{
+		Source:  sourceTrigger,
+		Label:   "alertmanager",
+		Content: `{"alerts": [{"status": "firing", "labels": {"alertname": "DiskFull"}}]}`,
+	}
+
+	want := "Summarize these alerts:\n- DiskFull (firing)"
+	if got := w.buildTriggerItemPrompt(alerts); got != want {
+		t.Errorf("prompt = %q, want %q", got, want)
+	}
+
+	// Unknown labels keep the built-in wrapper.
+	if got := w.buildTriggerItemPrompt(Inbox{Source: sourceTrigger, Label: "ci", Content: "x"}); !strings.Contains(got, "--- External trigger from ci ---") {
+		t.Errorf("prompt = %q, want default trigger wrapper", got)
+	}
+
+	if !w.replyMuted(Inbox{Source: sourceTrigger, Label: "metrics"}) {
+		t.Error("reply of a never-reply source not muted")
+	}
+
+	if w.replyMuted(alerts) {
+		t.Error("reply of an always-reply source muted")
+	}
+}
+
+func TestParseTriggerCLI(t *testing.T) {
+	t.Parallel()
+
+	var stderr strings.Builder
+
+	got, dir, err := parseTriggerCLI(
+		[]string{"-source", "backup", "-priority", "high", "-silent", "-session-dir", "/tmp/s", "backup", "done"},
+		strings.NewReader("ignored"), &stderr)
+	must(t, err)
+
+	want := triggerLine{Content: "backup done", Source: "backup", Priority: "high", Silent: true}
+	if got != want || dir != "/tmp/s" {
+		t.Errorf("got %+v in %q, want %+v in /tmp/s", got, dir, want)
+	}
+
+	got, _, err = parseTriggerCLI(nil, strings.NewReader("from stdin\n"), &stderr)
+	must(t, err)
+
+	if got.Content != "from stdin" {
+		t.Errorf("Content = %q, want stdin text", got.Content)
+	}
+
+	if _, _, err := parseTriggerCLI([]string{"-priority", "urgent", "x"}, nil, &stderr); err == nil {
+		t.Error("expected an error for an unknown priority")
+	}
+}
+
+func TestRunTriggerCLI_RejectsLongTrigger(t *testing.T) {
+	t.Parallel()
+
+	var stderr strings.Builder
+
+	stdin := strings.NewReader(strings.Repeat("log line\n", maxTriggerCLILine))
+	if code := runTriggerCLI([]string{"-session-dir", t.TempDir()}, stdin, &stderr); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+
+	if !strings.Contains(stderr.String(), "too long") {
+		t.Errorf("stderr = %q, want a size error", stderr.String())
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// maxWebhookBody bounds request bodies; webhook payloads are small and the
+// rendered result ends up in a prompt anyway.
+const maxWebhookBody = 1 << 20
+
+const defaultWebhookTemplate = `Webhook {{.Route}}:
+{{.Body}}`
+
+const defaultSignatureHeader = "X-Hub-Signature-256"
+
+// WebhookConfig enables the HTTP trigger endpoint.
+type WebhookConfig struct {
+	// Listen is a TCP address ("127.0.0.1:8787") or a unix socket path
+	// (absolute, or prefixed with "unix:") — OPENCROW_WEBHOOK_LISTEN.
+	Listen string
+	Routes []WebhookRoute // OPENCROW_WEBHOOK_FILE (JSON list)
+}
+
+// WebhookRoute is one entry of OPENCROW_WEBHOOK_FILE, served at
+// POST /hooks/<name>. Each route must be authenticated by a bearer token,
+// an HMAC-SHA256 body signature, or both.
+type WebhookRoute struct {
+	Name string `json:"name"`
+	// TokenFile holds the expected "Authorization: Bearer" token.
+	TokenFile string `json:"token_file"` //nolint:tagliatelle // snake_case is the documented file format.
+	// HMACSecretFile holds the key for the signature in SignatureHeader,
+	// given as hex with or without a "sha256=" prefix (GitHub, Gitea).
+	HMACSecretFile  string `json:"hmac_secret_file"` //nolint:tagliatelle // snake_case is the documented file format.
+	SignatureHeader string `json:"signature_header"` //nolint:tagliatelle // snake_case is the documented file format.
+	// Template is a text/template rendered with webhookData; the result
+	// becomes the trigger content.
+	Template string `json:"template"`
+	// Source names a TriggerSource that renders the prompt; the route
+	// name is the source label otherwise. With a source and no template,
+	// the raw body is forwarded.
+	Source       string `json:"source"`
+	Priority     string `json:"priority"`
+	Silent       bool   `json:"silent"`
+	Conversation string `json:"conversation"`
+
+	token      []byte
+	hmacSecret []byte
+	tmpl       *template.Template
+}
+
+// webhookData is what a route template can reference. JSON is the decoded
+// body, or nil if the body is not JSON.
+type webhookData struct {
+	Route   string
+	Body    string
+	JSON    any
+	Headers http.Header
+	Query   map[string][]string
+}
+
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+
+		return string(b), err
+	},
+}
+
+// loadWebhookRoutes reads and validates the JSON route list at path,
+// loading secrets from their files. All errors are reported at once.
+func loadWebhookRoutes(path string) ([]WebhookRoute, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OPENCROW_WEBHOOK_FILE: %w", err)
+	}
+
+	var routes []WebhookRoute
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("parsing OPENCROW_WEBHOOK_FILE: %w", err)
+	}
+
+	seen := make(map[string]bool, len(routes))
+
+	var errs error
+
+	for i := range routes {
+		if err := routes[i].load(); err != nil {
+			errs = errors.Join(errs, err)
+
+			continue
+		}
+
+		if seen[routes[i].Name] {
+			errs = errors.Join(errs, fmt.Errorf("webhook route %q: duplicate name", routes[i].Name))
+		}
+
+		seen[routes[i].Name] = true
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return routes, nil
+}
+
+func (r *WebhookRoute) load() error {
+	if r.Name == "" || strings.ContainsAny(r.Name, "/?#") {
+		return fmt.Errorf("webhook route %q: name must be non-empty and contain no /, ? or #", r.Name)
+	}
+
+	if r.TokenFile == "" && r.HMACSecretFile == "" {
+		return fmt.Errorf("webhook route %q: token_file or hmac_secret_file is required", r.Name)
+	}
+
+	if _, ok := triggerPriorities[r.Priority]; !ok {
+		return fmt.Errorf("webhook route %q: unknown priority %q (valid: high, normal, low)", r.Name, r.Priority)
+	}
+
+	var err error
+
+	if r.TokenFile != "" {
+		if r.token, err = readSecretFile(r.TokenFile); err != nil {
+			return fmt.Errorf("webhook route %q: %w", r.Name, err)
+		}
+	}
+
+	if r.HMACSecretFile != "" {
+		if r.hmacSecret, err = readSecretFile(r.HMACSecretFile); err != nil {
+			return fmt.Errorf("webhook route %q: %w", r.Name, err)
+		}
+	}
+
+	if r.SignatureHeader == "" {
+		r.SignatureHeader = defaultSignatureHeader
+	}
+
+	tmpl := r.Template
+
+	switch {
+	case tmpl != "":
+	case r.Source != "":
+		tmpl = "{{.Body}}"
+	default:
+		tmpl = defaultWebhookTemplate
+	}
+
+	if r.tmpl, err = template.New(r.Name).Funcs(webhookFuncs).Parse(tmpl); err != nil {
+		return fmt.Errorf("webhook route %q: template: %w", r.Name, err)
+	}
+
+	return nil
+}
+
+func readSecretFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading secret: %w", err)
+	}
+
+	secret := bytes.TrimSpace(data)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret file %s is empty", path)
+	}
+
+	return secret, nil
+}
+
+// authorize checks the bearer token and/or HMAC signature configured for
+// the route. When both are configured, both must match.
+func (r *WebhookRoute) authorize(req *http.Request, body []byte) bool {
+	if r.token != nil {
+		got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), r.token) != 1 {
+			return false
+		}
+	}
+
+	if r.hmacSecret != nil {
+		sig := strings.TrimPrefix(req.Header.Get(r.SignatureHeader), "sha256=")
+
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, r.hmacSecret)
+		mac.Write(body)
+
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// webhookHandler serves POST /hooks/<route>, rendering the payload through
+// the route's template and enqueueing it as a trigger.
+func webhookHandler(w *Worker, routes []WebhookRoute) http.Handler {
+	byName := make(map[string]*WebhookRoute, len(routes))
+	for i := range routes {
+		byName[routes[i].Name] = &routes[i]
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /hooks/{route}", func(rw http.ResponseWriter, req *http.Request) {
+		route, ok := byName[req.PathValue("route")]
+		if !ok {
+			http.NotFound(rw, req)
+
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(rw, req.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(rw, "request body too large or unreadable", http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
+		if !route.authorize(req, body) {
+			slog.Warn("webhook: unauthorized request", "route", route.Name, "remote", req.RemoteAddr)
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		data := webhookData{
+			Route:   route.Name,
+			Body:    string(body),
+			Headers: req.Header,
+			Query:   req.URL.Query(),
+		}
+
+		if err := json.Unmarshal(body, &data.JSON); err != nil {
+			data.JSON = nil
+		}
+
+		var content strings.Builder
+		if err := route.tmpl.Execute(&content, data); err != nil {
+			slog.Error("webhook: template failed", "route", route.Name, "error", err)
+			http.Error(rw, "template error: "+err.Error(), http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		item := route.item(content.String())
+
+		id, err := w.enqueueTrigger(req.Context(), item)
+		if err != nil {
+			slog.Error("webhook: failed to enqueue", "route", route.Name, "error", err)
+			http.Error(rw, "failed to enqueue", http.StatusInternalServerError)
+
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		if id == 0 {
+			// Dropped by dedup: nothing new was queued.
+			rw.WriteHeader(http.StatusOK)
+			fmt.Fprint(rw, "{\"duplicate\":true}\n")
+
+			return
+		}
+
+		rw.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(rw, "{\"id\":%d}\n", id)
+	})
+
+	return mux
+}
+
+func (r *WebhookRoute) item(content string) Inbox {
+	var silent int64
+	if r.Silent {
+		silent = 1
+	}
+
+	return Inbox{
+		Priority:       triggerPriorities[r.Priority],
+		Content:        content,
+		Label:          cmp.Or(r.Source, r.Name),
+		Silent:         silent,
+		ConversationID: r.Conversation,
+	}
+}
+
+// startWebhookServer serves cfg.Routes on cfg.Listen until ctx is done.
+func startWebhookServer(ctx context.Context, w *Worker, cfg WebhookConfig) error {
+	ln, err := listenWebhook(cfg.Listen)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Handler:           webhookHandler(w, cfg.Routes),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	slog.Info("webhook server started", "listen", cfg.Listen, "routes", len(cfg.Routes))
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("webhook server failed", "error", err)
+		}
+	}()
+
+	context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx) //nolint:contextcheck // parent ctx is already cancelled
+	})
+
+	return nil
+}
+
+// listenWebhook opens a unix socket for paths (replacing a stale socket
+// file from a previous run) and a TCP listener otherwise.
+func listenWebhook(addr string) (net.Listener, error) {
+	path, isUnix := strings.CutPrefix(addr, "unix:")
+	if !isUnix && !filepath.IsAbs(addr) {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("listening on %s: %w", addr, err)
+		}
+
+		return ln, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating webhook socket dir: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("removing stale webhook socket: %w", err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", path, err)
+	}
+
+	// Same access model as trigger.pipe: owner and group may connect.
+	if err := os.Chmod(path, 0o660); err != nil {
+		ln.Close()
+
+		return nil, fmt.Errorf("setting webhook socket permissions: %w", err)
+	}
+
+	return ln, nil
+}
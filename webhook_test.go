@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	must(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func newTestWebhook(t *testing.T) (*InboxStore, http.Handler) {
+	t.Helper()
+
+	ctx := context.Background()
+	inbox := newTestInboxWithDB(ctx, t, newTestDB(ctx, t))
+	worker := NewWorker(inbox, PiConfig{SessionDir: t.TempDir()}, "", "")
+
+	routes, err := loadWebhookRoutes(writeTestFile(t, "webhooks.json", `[
+		{"name": "gitea", "hmac_secret_file": "`+writeTestFile(t, "hmac", "s3cret\n")+`",
+		 "signature_header": "X-Gitea-Signature",
+		 "template": "Push to {{.JSON.repository.full_name}} by {{.JSON.pusher.login}}"},
+		{"name": "alertmanager", "token_file": "`+writeTestFile(t, "token", "tok")+`", "silent": true}
+	]`))
+	must(t, err)
+
+	return inbox, webhookHandler(worker, routes)
+}
+
+func TestWebhook_HMACRoute(t *testing.T) {
+	t.Parallel()
+
+	inbox, h := newTestWebhook(t)
+
+	body := `{"repository": {"full_name": "crow/opencrow"}, "pusher": {"login": "alice"}}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks/gitea", strings.NewReader(body))
+	req.Header.Set("X-Gitea-Signature", hex.EncodeToString(mac.Sum(nil)))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"id":`) {
+		t.Fatalf("response = %d %q, want 202 with an id", rec.Code, rec.Body.String())
+	}
+
+	item, err := inbox.Dequeue(context.Background())
+	must(t, err)
+
+	if item.Content != "Push to crow/opencrow by alice" || item.Label != "gitea" || item.Source != sourceTrigger {
+		t.Errorf("item = %+v", item)
+	}
+}
+
+func TestWebhook_Rejects(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"bad signature", "/hooks/gitea", "X-Gitea-Signature", "sha256=00", http.StatusUnauthorized},
+		{"missing token", "/hooks/alertmanager", "", "", http.StatusUnauthorized},
+		{"wrong token", "/hooks/alertmanager", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"unknown route", "/hooks/nope", "", "", http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inbox, h := newTestWebhook(t)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader("{}"))
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d", rec.Code, tc.want)
+			}
+
+			if n, _ := inbox.Count(context.Background()); n != 0 {
+				t.Errorf("inbox count = %d, want 0", n)
+			}
+		})
+	}
+}
+
+func TestWebhook_BearerRouteDefaultTemplate(t *testing.T) {
+	t.Parallel()
+
+	inbox, h := newTestWebhook(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/hooks/alertmanager", strings.NewReader(`{"status":"firing"}`))
+	req.Header.Set("Authorization", "Bearer tok")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.Code)
+	}
+
+	item, err := inbox.Dequeue(context.Background())
+	must(t, err)
+
+	if item.Content != "Webhook alertmanager:\n{\"status\":\"firing\"}" || item.Silent != 1 {
+		t.Errorf("item = %+v", item)
+	}
+}
+
+func TestLoadWebhookRoutes_RequiresAuth(t *testing.T) {
+	t.Parallel()
+
+	_, err := loadWebhookRoutes(writeTestFile(t, "webhooks.json", `[{"name": "open"}]`))
+	if err == nil || !strings.Contains(err.Error(), "token_file or hmac_secret_file") {
+		t.Errorf("err = %v, want missing-auth error", err)
+	}
+}
+
+func TestWebhook_DuplicateReported(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	inbox := newTestInboxWithDB(ctx, t, newTestDB(ctx, t))
+	worker := NewWorker(inbox, PiConfig{SessionDir: t.TempDir()}, "", "")
+	worker.triggerCfg.Dedup = triggerDedupExact
+
+	routes, err := loadWebhookRoutes(writeTestFile(t, "webhooks.json",
+		`[{"name": "ci", "token_file": "`+writeTestFile(t, "token", "tok")+`"}]`))
+	must(t, err)
+
+	h := webhookHandler(worker, routes)
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/hooks/ci", strings.NewReader(`{"build":1}`))
+		req.Header.Set("Authorization", "Bearer tok")
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	if rec := post(); rec.Code != http.StatusAccepted {
+		t.Fatalf("first request = %d %q, want 202", rec.Code, rec.Body.String())
+	}
+
+	if rec := post(); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"duplicate":true`) {
+		t.Errorf("duplicate request = %d %q, want 200 with duplicate", rec.Code, rec.Body.String())
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -38,6 +39,9 @@ type Worker struct {
 	// config
 	hbPrompt      string
 	triggerPrompt string
+	triggerCfg    TriggerConfig
+	promptCfg     PromptConfig
+	toolsCfg      ToolsConfig
 
 	// mu protects pi, lastUse, compactResult, currentPriority, currentCancel, freshStart.
 	mu              sync.Mutex
@@ -51,8 +55,40 @@ type Worker struct {
 	// wake is signalled (non-blocking) on every Notify call so the
 	// worker can poll the DB for the highest-priority item.
 	wake chan struct{}
+
+	// waiters maps inbox waiter_id to a synchronous trigger caller.
+	waitersMu sync.Mutex
+	waiters   map[string]*triggerWaiter
+
+	// thinking collects the current turn's thinking deltas for relay.
+	// pi callbacks run on the goroutine running the turn, so it needs
+	// no lock.
+	thinking strings.Builder
+	// streamThinking relays thinking deltas as they come on streaming
+	// backends; nil on others.
+	streamThinking func(string)
+	// tools shows the current turn's tool calls and activity collects
+	// them for the summary, under the same rule.
+	tools    *toolNotifier
+	activity *toolActivity
+	// progress posts updates during long turns; nil when off. It runs on
+	// its own goroutine and locks.
+	progress *progressReporter
+
+	// mergeTimer wakes the worker when a trigger held for the merge
+	// window is due. Only the Run goroutine touches it.
+	mergeTimer *time.Timer
 }
 
+const (
+	// maxThinkingSummary bounds the relayed thinking sent before a reply
+	// on backends that can't collapse it.
+	maxThinkingSummary = 500
+	// maxThinkingDetails bounds the collapsed thinking on backends that
+	// can.
+	maxThinkingDetails = 16000
+)
+
 // compactOutcome carries the result of a compact operation back to the caller.
 type compactOutcome struct {
 	result *CompactResult
@@ -77,6 +113,7 @@ func NewWorker(inbox *InboxStore, piCfg PiConfig, hbPrompt, triggerPrompt string
 		lastUse:         time.Now(),
 		currentPriority: -1,
 		wake:            make(chan struct{}, 1),
+		waiters:         make(map[string]*triggerWaiter),
 	}
 }
 
@@ -253,8 +290,10 @@ func (w *Worker) drainOnce(ctx context.Context) {
 			return
 		}
 
-		item, err := w.inbox.Dequeue(ctx)
+		item, err := w.inbox.DequeueHolding(ctx, w.mergeHold())
 		if errors.Is(err, sql.ErrNoRows) {
+			w.armMergeTimer(ctx)
+
 			return
 		}
 
@@ -270,6 +309,10 @@ func (w *Worker) drainOnce(ctx context.Context) {
 			item = w.mergeUserItems(ctx, item)
 		}
 
+		if item.Source == sourceTrigger {
+			item = w.mergeTriggerItems(ctx, item)
+		}
+
 		if w.processItem(ctx, item) {
 			return
 		}
@@ -292,19 +335,57 @@ func (w *Worker) mergeUserItems(ctx context.Context, item Inbox) Inbox {
 
 	slog.Info("worker: merging user messages", "count", 1+len(extra))
 
+	return mergeUserMessages(append([]Inbox{item}, extra...), w.promptCfg.location())
+}
+
+// mergeUserMessages combines user items (oldest first) into one prompt
+// that keeps them apart: each message gets a header with when it was sent
+// and whether it is a reply or carries files, followed by its content
+// (which already holds the reply context and attachment markers). The
+// reply threads to the newest message that replied to something, since
+// that is the conversation the user most recently pointed at.
+func mergeUserMessages(items []Inbox, loc *time.Location) Inbox {
+	merged := items[0]
+	merged.ReplyTo = ""
+
+	// The envelope names one sender; in a group batch each message
+	// names its own instead.
+	mixed := slices.ContainsFunc(items, func(it Inbox) bool { return it.Sender != merged.Sender })
+	if mixed {
+		merged.Sender = ""
+	}
+
 	var sb strings.Builder
 
-	sb.WriteString(item.Content)
+	fmt.Fprintf(&sb, "[%d messages sent while you were busy, oldest first]", len(items))
+
+	for i, it := range items {
+		fmt.Fprintf(&sb, "\n\n[message %d of %d", i+1, len(items))
 
-	for _, e := range extra {
-		sb.WriteByte('\n')
-		sb.WriteString(e.Content)
+		if mixed && it.Sender != "" {
+			sb.WriteString(", from " + it.Sender)
+		}
+
+		if created, err := time.Parse(inboxTimeLayout, it.CreatedAt); err == nil {
+			sb.WriteString(", sent " + created.In(loc).Format("2006-01-02 15:04:05 MST"))
+		}
+
+		if it.ReplyTo != "" {
+			sb.WriteString(", a reply")
+			merged.ReplyTo = it.ReplyTo
+		}
+
+		if n := len(inboundAttachments(it.Content)); n > 0 {
+			fmt.Fprintf(&sb, ", %d file(s) attached", n)
+		}
+
+		sb.WriteString("]\n")
+		sb.WriteString(it.Content)
 	}
 
-	item.Content = sb.String()
-	item.ReplyTo = extra[len(extra)-1].ReplyTo
+	merged.Content = sb.String()
 
-	return item
+	return merged
 }
 
 // processItem handles one inbox item. Returns true if drainOnce should
@@ -345,7 +426,7 @@ func (w *Worker) processPrompt(ctx context.Context, item Inbox) bool {
 		return false
 	}
 
-	convID := w.resolveRoomID()
+	convID := cmp.Or(item.ConversationID, w.resolveRoomID())
 	if convID == "" {
 		return w.handleNoRoomID(item) //nolint:contextcheck // requeue uses context.Background intentionally
 	}
@@ -353,17 +434,41 @@ func (w *Worker) processPrompt(ctx context.Context, item Inbox) bool {
 	w.be.SetTyping(ctx, convID, true)
 	defer w.be.SetTyping(context.Background(), convID, false) //nolint:contextcheck // must clear typing even after preemption
 
+	if err := setTurnConversation(w.piCfg.SessionDir, convID); err != nil {
+		slog.Warn("worker: failed to record turn conversation", "error", err)
+	}
+	defer setTurnConversation(w.piCfg.SessionDir, "") //nolint:errcheck // a stale file is replaced by the next turn
+
 	taskStart := time.Now()
 
+	w.thinking.Reset()
+	w.streamThinking = nil
+
+	w.tools = newToolNotifier(w.be, w.toolsCfg, convID)
+	w.activity = newToolActivity()
+	defer w.tools.done(context.Background()) //nolint:contextcheck // only finalizes a turn that failed; replies finalize and suppressed replies discard it first
+
+	stopProgress := w.startProgress(ctx, convID)
+	defer stopProgress(false)
+
 	// Stream text deltas to the client if the backend supports it.
 	var onDelta func(string)
 
+	if p := w.progress; p != nil {
+		onDelta = p.textDelta
+	}
+
 	if streamer, ok := w.be.(backend.Streamer); ok {
 		streamID := fmt.Sprintf("stream-%d", time.Now().UnixNano())
 
 		onDelta = func(delta string) {
 			streamer.SendDelta(ctx, convID, streamID, delta)
 		}
+
+		thinkingID := fmt.Sprintf("thinking-%d", time.Now().UnixNano())
+		w.streamThinking = func(delta string) {
+			streamer.SendThinkingDelta(ctx, convID, thinkingID, delta)
+		}
 	}
 
 	pi, reply, err := w.sendWithRetry(ctx, prompt, onDelta)
@@ -381,41 +486,150 @@ func (w *Worker) processPrompt(ctx context.Context, item Inbox) bool {
 		reply = w.retryEmptyResponse(ctx, pi)
 	}
 
-	if shouldSuppressReply(reply, item.Source) {
+	if !w.answerWaiter(item, reply) {
+		w.tools.discard(ctx)
+		stopProgress(true)
+
+		return false
+	}
+
+	if w.replyMuted(item) {
+		slog.Info("trigger: source reply policy is never, not posting", "label", item.Label)
+		w.tools.discard(ctx)
+		stopProgress(true)
+
+		return false
+	}
+
+	if shouldSuppressReply(reply, item) {
+		w.tools.discard(ctx)
+		stopProgress(true)
+
+		// Nothing to reply to, but an important reminder must still
+		// re-fire if it goes unacknowledged.
+		w.app.outbox.RecordReminderDelivery(ctx, item.ReminderID, convID, "")
+
 		return false
 	}
 
-	if w.piCfg.DebugTiming {
+	stopProgress(false)
+	w.tools.done(ctx)
+
+	verbosity := w.verbosity(ctx, convID)
+
+	if verbosity.Thinking {
+		w.relayThinking(ctx, convID)
+	}
+
+	if verbosity.DebugTiming {
 		reply += fmt.Sprintf("\n\n⏱ %s", time.Since(taskStart).Round(time.Millisecond))
 	}
 
-	w.app.sendReplyWithFiles(ctx, convID, reply, item.ReplyTo)
+	var footer replyFooter
+	if w.toolsCfg.Summary {
+		footer = w.activity.footer(w.piCfg.WorkingDir, w.be.MarkdownFlavor())
+	}
+
+	sentID := w.app.sendReplyWithFiles(ctx, convID, reply, item.ReplyTo, item.Source, footer)
+	w.app.outbox.RecordReminderDelivery(ctx, item.ReminderID, convID, sentID)
 
 	return false
 }
 
+// startProgress starts posting progress updates for a long turn and
+// returns a function that stops them, redacting the last update if
+// discard is set and finishing it otherwise. Streaming backends show the turn
+// as it goes, and shown tool calls already say what it is doing, so
+// those get none.
+func (w *Worker) startProgress(ctx context.Context, convID string) func(discard bool) {
+	w.progress = nil
+
+	if w.toolsCfg.ProgressInterval <= 0 || w.verbosity(ctx, convID).ToolCalls {
+		return func(bool) {}
+	}
+
+	if _, ok := w.be.(backend.Streamer); ok {
+		return func(bool) {}
+	}
+
+	p := newProgressReporter(w.be, w.toolsCfg, convID)
+	w.progress = p
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		p.run(ctx, w.toolsCfg.ProgressInterval)
+	}()
+
+	var once sync.Once
+
+	return func(discard bool) {
+		once.Do(func() {
+			cancel()
+			<-done
+
+			if discard {
+				p.discard(context.WithoutCancel(ctx))
+			} else {
+				p.finish(context.WithoutCancel(ctx))
+			}
+		})
+	}
+}
+
+// relayThinking sends the turn's thinking before the reply: collapsed
+// where the backend supports it, else cut to a short summary. Streaming
+// backends already got it as it came.
+func (w *Worker) relayThinking(ctx context.Context, convID string) {
+	thinking := strings.TrimSpace(w.thinking.String())
+	if thinking == "" || w.streamThinking != nil {
+		return
+	}
+
+	if ds, ok := w.be.(backend.DetailsSender); ok {
+		ds.SendMessageWithDetails(ctx, convID, "", "💭 Thinking", truncateRunes(thinking, maxThinkingDetails), "")
+
+		return
+	}
+
+	w.be.SendMessage(ctx, convID, "💭 "+truncateRunes(thinking, maxThinkingSummary), "")
+}
+
 func (w *Worker) buildPrompt(item Inbox) (string, bool) {
 	switch item.Source {
 	case sourceUser:
-		return item.Content, true
+		return w.wrapPrompt(item, item.Content), true
 	case sourceTrigger:
-		return buildTriggerPrompt(w.triggerPrompt, item.Content), true
+		return w.wrapPrompt(item, w.buildTriggerItemPrompt(item)), true
 	case sourceHeartbeat:
 		items := parseHeartbeatItems(w.readHeartbeatFile())
 		if len(items) == 0 {
 			return "", false
 		}
 
-		return buildHeartbeatPrompt(w.hbPrompt, items), true
+		return w.wrapPrompt(item, buildHeartbeatPrompt(w.hbPrompt, items)), true
 	default:
 		return "", false
 	}
 }
 
 // handleNoRoomID requeues triggers (room may appear later) and drops
-// everything else. Returns true if the item was requeued, signalling
+// everything else, failing synchronous callers. Returns true if the item was requeued, signalling
 // drainOnce to stop looping and wait for the next Notify.
 func (w *Worker) handleNoRoomID(item Inbox) bool {
+	if waiter := w.takeWaiter(item); waiter != nil {
+		// Don't keep a synchronous caller waiting for a room that may
+		// never come; the item is dropped with its answer.
+		slog.Info("worker: no room ID for synchronous trigger, failing it")
+
+		waiter.done <- triggerResult{Error: errNoConversation.Error()}
+
+		return false
+	}
+
 	if item.Source == sourceTrigger {
 		if err := w.inbox.Requeue(context.Background(), item); err != nil {
 			slog.Error("worker: failed to requeue trigger (item lost)", "error", err)
@@ -445,6 +659,8 @@ func (w *Worker) handlePiError(ctx context.Context, item Inbox, convID, label st
 		w.stopPi()
 	}
 
+	w.failWaiter(item, err)
+
 	if item.Source == sourceUser {
 		w.be.SendMessage(ctx, convID, fmt.Sprintf("Error: %v", err), "")
 	}
@@ -553,10 +769,46 @@ func (w *Worker) ensurePi(ctx context.Context) (*PiProcess, error) {
 		return nil, err
 	}
 
-	if w.piCfg.ShowToolCalls {
-		flavor := w.be.MarkdownFlavor()
-		pi.onToolCall = func(evt ToolCallEvent) { //nolint:contextcheck // fire-and-forget notification, no parent ctx
-			w.be.SendMessage(context.Background(), w.resolveRoomID(), formatToolCall(evt, flavor), "")
+	// Wired unconditionally: the callbacks check the conversation's
+	// settings per event, so !verbose and !quiet apply mid-turn.
+	pi.onToolCall = func(evt ToolCallEvent) { //nolint:contextcheck // fire-and-forget notification, no parent ctx
+		if w.activity != nil {
+			w.activity.started(evt)
+		}
+
+		if w.progress != nil {
+			w.progress.toolStarted(evt)
+		}
+
+		if t := w.tools; t != nil && w.verbosity(context.Background(), t.conversationID).ToolCalls {
+			t.started(context.Background(), evt)
+		}
+	}
+	pi.onToolResult = func(evt ToolResultEvent) { //nolint:contextcheck // fire-and-forget notification, no parent ctx
+		if w.activity != nil {
+			w.activity.finished(evt)
+		}
+
+		if w.progress != nil {
+			w.progress.toolFinished(evt)
+		}
+
+		if w.tools != nil {
+			w.tools.finished(context.Background(), evt)
+		}
+	}
+	pi.onThinking = func(delta string) { //nolint:contextcheck // settings lookup only, no parent ctx
+		// The turn's conversation, like onToolCall: a trigger may target
+		// another room than .room_id.
+		t := w.tools
+		if t == nil || !w.verbosity(context.Background(), t.conversationID).Thinking {
+			return
+		}
+
+		w.thinking.WriteString(delta)
+
+		if w.streamThinking != nil {
+			w.streamThinking(delta)
 		}
 	}
 
@@ -567,6 +819,16 @@ func (w *Worker) ensurePi(ctx context.Context) (*PiProcess, error) {
 	return pi, nil
 }
 
+// verbosity returns the settings of conversationID. Without an App
+// (tests), the configured defaults apply.
+func (w *Worker) verbosity(ctx context.Context, conversationID string) Verbosity {
+	if w.app == nil {
+		return Verbosity{ToolCalls: w.piCfg.ShowToolCalls, DebugTiming: w.piCfg.DebugTiming}
+	}
+
+	return w.app.settings.Get(ctx, conversationID)
+}
+
 func (w *Worker) stopPi() {
 	w.mu.Lock()
 	pi := w.pi